@@ -0,0 +1,224 @@
+// Command export writes the dataset as a JSONL manifest, one segment per line, or as
+// a Kaldi data directory in a tar.gz, the same as the /api/v1/export endpoints. With
+// -release it writes a frozen release instead of the current transcripts. It loads
+// config/config.yml and the environment the same as the server, so run it from the
+// repository root. It only uses the database; bundled audio is fetched from its
+// public URL:
+//
+//	go run ./cmd/export -language uz -from 2025-08-01 -o train.jsonl
+//	go run ./cmd/export -format kaldi -audio bundle -o data.tar.gz
+//	go run ./cmd/export -release 3 -o release.jsonl
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/internal/usecase/repo"
+	"github.com/mirjalilova/voice_transcribe/pkg/kaldi"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+func main() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf("Config error: %s", err)
+	}
+
+	var (
+		pgURL   = flag.String("pg", cfg.PG.URL, "Postgres URL, the configured one by default")
+		output  = flag.String("o", "", "Output file, stdout by default")
+		format  = flag.String("format", "jsonl", "jsonl or kaldi")
+		audio   = flag.String("audio", "url", "Kaldi audio: url or bundle")
+		release = flag.Int("release", 0, "Release ID to export instead of the current transcripts")
+		req     entity.ManifestReq
+	)
+	flag.StringVar(&req.Status, "status", "done", "Transcript status: done, invalid or ready")
+	flag.StringVar(&req.FromDate, "from", "", "Submitted from (YYYY-MM-DD)")
+	flag.StringVar(&req.ToDate, "to", "", "Submitted to (YYYY-MM-DD)")
+	flag.StringVar(&req.UserId, "user", "", "Transcriber ID")
+	flag.StringVar(&req.Language, "language", "", "Primary language of the segments, such as uz or ru")
+	flag.StringVar(&req.SpanLanguage, "span-language", "", "Keep segments with a passage in this language, such as ru")
+	flag.BoolVar(&req.IncludeFlagged, "include-flagged", false, "Include transcripts flagged by the quality check")
+	flag.StringVar(&req.Split, "split", "", "Dataset split, such as train, dev or test")
+	flag.StringVar(&req.Script, "script", "", "Write Uzbek transcripts in latin or cyrillic")
+	flag.StringVar(&req.Tags, "tags", "", "Event tags: keep, map or drop")
+	flag.BoolVar(&req.Normalize, "normalize", false, "Add the text normalized for training; Kaldi text uses it. A release keeps its own")
+	flag.Parse()
+
+	if *pgURL == "" {
+		log.Fatal("Postgres URL is required, set -pg or PG_URL")
+	}
+
+	codes := strings.Split(cfg.Language.Codes, ",")
+	for _, language := range []string{req.Language, req.SpanLanguage} {
+		if language != "" && !slices.Contains(codes, language) {
+			log.Fatalf("Language must be one of %s", strings.Join(codes, ", "))
+		}
+	}
+	if req.Script != "" && req.Script != "latin" && req.Script != "cyrillic" {
+		log.Fatal("Script must be one of latin, cyrillic")
+	}
+	if req.Tags != "" && req.Tags != "keep" && req.Tags != "map" && req.Tags != "drop" {
+		log.Fatal("Tags must be one of keep, map, drop")
+	}
+	if *format != "jsonl" && *format != "kaldi" {
+		log.Fatal("Format must be one of jsonl, kaldi")
+	}
+	if *audio != "url" && *audio != "bundle" {
+		log.Fatal("Audio must be one of url, bundle")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pg, err := postgres.New(*pgURL, postgres.MaxPoolSize(1))
+	if err != nil {
+		log.Fatalf("Postgres error: %s", err)
+	}
+	defer pg.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Output error: %s", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	exportRepo := repo.NewExportRepo(pg, cfg, logger.New("info"))
+	var stream streamFunc = func(ctx context.Context, fn func(*entity.ManifestEntry) error) error {
+		return exportRepo.StreamManifest(ctx, &req, fn)
+	}
+	releaseRepo := repo.NewReleaseRepo(pg, cfg, logger.New("info"))
+	normalized := req.Normalize
+	if *release > 0 {
+		r, err := releaseRepo.GetRelease(ctx, *release)
+		if err != nil {
+			log.Fatalf("Release error: %s", err)
+		}
+		normalized = r.Filter.Normalize
+		stream = func(ctx context.Context, fn func(*entity.ManifestEntry) error) error {
+			return releaseRepo.StreamRelease(ctx, *release, fn)
+		}
+	}
+
+	var count int
+	switch {
+	case *format == "kaldi":
+		count, err = exportKaldi(ctx, stream, out, *audio == "bundle", normalized)
+	case *release > 0:
+		// The frozen lines, so that the file matches the checksum of the release.
+		count, err = exportReleaseManifest(ctx, releaseRepo, *release, out)
+	default:
+		count, err = exportManifest(ctx, stream, out)
+	}
+	if err != nil {
+		log.Fatalf("Export error after %d segments: %s", count, err)
+	}
+
+	log.Printf("Exported %d segments", count)
+}
+
+// streamFunc calls fn with every entry to export.
+type streamFunc func(ctx context.Context, fn func(*entity.ManifestEntry) error) error
+
+func exportManifest(ctx context.Context, stream streamFunc, out io.Writer) (int, error) {
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	lines := 0
+	err := stream(ctx, func(entry *entity.ManifestEntry) error {
+		lines++
+		return enc.Encode(entry)
+	})
+	if err != nil {
+		return lines, err
+	}
+
+	return lines, w.Flush()
+}
+
+func exportReleaseManifest(ctx context.Context, releaseRepo *repo.ReleaseRepo, id int, out io.Writer) (int, error) {
+	w := bufio.NewWriter(out)
+
+	lines := 0
+	err := releaseRepo.StreamReleaseManifest(ctx, id, func(line string) error {
+		lines++
+		_, err := w.WriteString(line)
+		return err
+	})
+	if err != nil {
+		return lines, err
+	}
+
+	return lines, w.Flush()
+}
+
+func exportKaldi(ctx context.Context, stream streamFunc, out io.Writer, bundle, normalized bool) (int, error) {
+	var open kaldi.Opener
+	if bundle {
+		open = openURL
+	}
+
+	w := bufio.NewWriter(out)
+	exporter, err := kaldi.NewExporter(w, open)
+	if err != nil {
+		return 0, err
+	}
+	defer exporter.Close()
+
+	err = stream(ctx, func(entry *entity.ManifestEntry) error {
+		return exporter.Add(ctx, kaldi.Utterance{
+			AudioId:   entry.AudioId,
+			SegmentId: entry.SegmentId,
+			AudioURL:  entry.AudioFilepath,
+			Text:      entry.TrainingText(normalized),
+			Duration:  entry.Duration,
+		})
+	})
+	if err == nil {
+		err = exporter.Finish()
+	}
+	if err != nil {
+		return exporter.Count(), err
+	}
+
+	return exporter.Count(), w.Flush()
+}
+
+// openURL downloads audio from its public URL.
+func openURL(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -9,13 +10,22 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App    `yaml:"app"`
-		HTTP   `yaml:"http"`
-		Log    `yaml:"logger"`
-		PG     `yaml:"postgres"`
-		Minio  `yaml:"minio"`
-		ApiKey `yaml:"api_key"`
-		JWT    `yaml:"jwt"`
+		App       `yaml:"app"`
+		HTTP      `yaml:"http"`
+		Log       `yaml:"logger"`
+		PG        `yaml:"postgres"`
+		Minio     `yaml:"minio"`
+		ApiKey    `yaml:"api_key"`
+		JWT       `yaml:"jwt"`
+		Work      `yaml:"work"`
+		Stats     `yaml:"stats"`
+		Quality   `yaml:"quality"`
+		Fraud     `yaml:"fraud"`
+		Export    `yaml:"export"`
+		Split     `yaml:"split"`
+		Normalize `yaml:"normalize"`
+		Language  `yaml:"language"`
+		Lint      `yaml:"lint"`
 	}
 
 	// App -.
@@ -58,6 +68,81 @@ type (
 		Secret string `env-required:"true" yaml:"secret" env:"JWT_SECRET"`
 	}
 
+	// Work -.
+	Work struct {
+		Timezone    string        `yaml:"timezone"     env:"WORK_TIMEZONE"     env-default:"Asia/Tashkent"`
+		IdleTimeout time.Duration `yaml:"idle_timeout" env:"WORK_IDLE_TIMEOUT" env-default:"90s"`
+		BlockLength time.Duration `yaml:"block_length" env:"WORK_BLOCK_LENGTH" env-default:"30m"`
+		ShiftLength time.Duration `yaml:"shift_length" env:"WORK_SHIFT_LENGTH" env-default:"9h"`
+	}
+
+	// Stats -.
+	Stats struct {
+		RefreshInterval     time.Duration `yaml:"refresh_interval"      env:"STATS_REFRESH_INTERVAL"      env-default:"10m"`
+		CacheTTL            time.Duration `yaml:"cache_ttl"             env:"STATS_CACHE_TTL"             env-default:"1m"`
+		TextRefreshInterval time.Duration `yaml:"text_refresh_interval" env:"STATS_TEXT_REFRESH_INTERVAL" env-default:"1m"`
+		// Ascending upper bounds of the duration (seconds) and text length (characters)
+		// buckets of the statistics. Values above the last bound share one bucket.
+		DurationEdges []float64 `yaml:"duration_edges" env:"STATS_DURATION_EDGES" env-default:"1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22"`
+		TextEdges     []int     `yaml:"text_edges"     env:"STATS_TEXT_EDGES"     env-default:"15,30,45,60,75,90,105,120"`
+	}
+
+	// Quality -.
+	Quality struct {
+		CheckInterval    time.Duration `yaml:"check_interval"     env:"QUALITY_CHECK_INTERVAL"     env-default:"1h"`
+		FenceFactor      float64       `yaml:"fence_factor"       env:"QUALITY_FENCE_FACTOR"       env-default:"3"`
+		MinSamples       int           `yaml:"min_samples"        env:"QUALITY_MIN_SAMPLES"        env-default:"50"`
+		EmptyMinDuration time.Duration `yaml:"empty_min_duration" env:"QUALITY_EMPTY_MIN_DURATION" env-default:"2s"`
+	}
+
+	// Fraud -.
+	Fraud struct {
+		CheckInterval        time.Duration `yaml:"check_interval"          env:"FRAUD_CHECK_INTERVAL"          env-default:"30m"`
+		MinChars             int           `yaml:"min_chars"               env:"FRAUD_MIN_CHARS"               env-default:"10"`
+		MaxAiEditRate        float64       `yaml:"max_ai_edit_rate"        env:"FRAUD_MAX_AI_EDIT_RATE"        env-default:"0.02"`
+		MaxNeighbourEditRate float64       `yaml:"max_neighbour_edit_rate" env:"FRAUD_MAX_NEIGHBOUR_EDIT_RATE" env-default:"0.1"`
+		MinSpentRatio        float64       `yaml:"min_spent_ratio"         env:"FRAUD_MIN_SPENT_RATIO"         env-default:"1"`
+	}
+
+	// Export -.
+	Export struct {
+		BundleInterval time.Duration `yaml:"bundle_interval" env:"EXPORT_BUNDLE_INTERVAL" env-default:"1m"`
+		BundleLease    time.Duration `yaml:"bundle_lease"    env:"EXPORT_BUNDLE_LEASE"    env-default:"10m"`
+		PartSize       int64         `yaml:"part_size"       env:"EXPORT_PART_SIZE"       env-default:"67108864"`
+		LinkExpiry     time.Duration `yaml:"link_expiry"     env:"EXPORT_LINK_EXPIRY"     env-default:"24h"`
+		// BundleAttempts is the number of claims a bundle gets before a failed build is final.
+		BundleAttempts int `yaml:"bundle_attempts" env:"EXPORT_BUNDLE_ATTEMPTS" env-default:"3"`
+	}
+
+	// Split -.
+	Split struct {
+		Seed           string        `yaml:"seed"            env:"SPLIT_SEED"            env-default:"voice_transcribe"`
+		Ratios         string        `yaml:"ratios"          env:"SPLIT_RATIOS"          env-default:"train:0.8,dev:0.1,test:0.1"`
+		Stratify       string        `yaml:"stratify"        env:"SPLIT_STRATIFY"        env-default:"duration"`
+		AssignInterval time.Duration `yaml:"assign_interval" env:"SPLIT_ASSIGN_INTERVAL" env-default:"1h"`
+	}
+
+	// Normalize -.
+	Normalize struct {
+		Lowercase        bool   `yaml:"lowercase"         env:"NORMALIZE_LOWERCASE"         env-default:"true"`
+		StripPunctuation bool   `yaml:"strip_punctuation" env:"NORMALIZE_STRIP_PUNCTUATION" env-default:"true"`
+		LanguageTags     string `yaml:"language_tags"     env:"NORMALIZE_LANGUAGE_TAGS"     env-default:"keep"`
+		EventTags        string `yaml:"event_tags"        env:"NORMALIZE_EVENT_TAGS"        env-default:"drop"`
+		Apostrophe       string `yaml:"apostrophe"        env:"NORMALIZE_APOSTROPHE"        env-default:"'"`
+		Numbers          bool   `yaml:"numbers"           env:"NORMALIZE_NUMBERS"           env-default:"true"`
+	}
+
+	// Language -.
+	Language struct {
+		// Codes are the languages of transcripts, the first one the default.
+		Codes string `yaml:"codes" env:"LANGUAGE_CODES" env-default:"uz,ru,en"`
+	}
+
+	// Lint -.
+	Lint struct {
+		// Rules are the severities of the guideline rules, off, warning or error.
+		Rules string `yaml:"rules" env:"LINT_RULES" env-default:"tag:error,bracket:error,digits:error,mixed_script:error,spacing:warning"`
+	}
 )
 
 // NewConfig returns app config.
@@ -17,6 +17,12 @@ var (
 	ErrorConflict       = "CONFLICT"
 	ErrorBadRequest     = "BAD_REQUEST"
 	ErrorDuplicateKey   = "DUPLICATE_KEY"
+
+	ErrorPreconditionRequired = "PRECONDITION_REQUIRED"
+	ErrorDailyQuotaExceeded   = "DAILY_QUOTA_EXCEEDED"
+	ErrorWeeklyQuotaExceeded  = "WEEKLY_QUOTA_EXCEEDED"
+	ErrorOutsideWorkingHours  = "OUTSIDE_WORKING_HOURS"
+	ErrorGuidelineViolation   = "GUIDELINE_VIOLATION"
 )
 
 var (
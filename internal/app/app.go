@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/mirjalilova/voice_transcribe/config"
 	v1 "github.com/mirjalilova/voice_transcribe/internal/controller/http"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
 	"github.com/mirjalilova/voice_transcribe/internal/usecase"
 
 	"github.com/mirjalilova/voice_transcribe/pkg/httpserver"
@@ -55,13 +57,46 @@ func Run(cfg *config.Config) {
 	// Use case
 	useCase := usecase.New(pg, cfg, l)
 
+	// Background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go runEvery(jobCtx, cfg.Stats.RefreshInterval, "refresh statistics", func(ctx context.Context) error {
+		_, err := useCase.AudioSegmentRepo.RefreshStatistics(ctx)
+		return err
+	})
+	go runEvery(jobCtx, cfg.Quality.CheckInterval, "check transcript quality", func(ctx context.Context) error {
+		_, err := useCase.QualityRepo.Check(ctx)
+		return err
+	})
+	go runEvery(jobCtx, cfg.Fraud.CheckInterval, "check transcripts for fraud", func(ctx context.Context) error {
+		_, err := useCase.FraudRepo.CheckPending(ctx)
+		return err
+	})
+	go runEvery(jobCtx, cfg.Split.AssignInterval, "assign dataset splits", func(ctx context.Context) error {
+		_, err := useCase.SplitRepo.AssignSplits(ctx, &entity.SplitAssignReq{})
+		return err
+	})
+	go func() {
+		migrated, err := useCase.TranscriptRepo.MigrateLanguageSpans(jobCtx)
+		if err != nil {
+			slog.Error("Failed to migrate inline language passages", "err", err, "migrated", migrated)
+			return
+		}
+		if migrated > 0 {
+			slog.Info("Migrated inline language passages", "transcripts", migrated)
+		}
+	}()
+
 	//MinIO
 	minioClient, err := minio.MinIOConnect(cfg)
 	if err != nil {
-		slog.Error("Failed to connect to MinIO", err)
+		slog.Error("Failed to connect to MinIO", "err", err)
 		return
 	}
 
+	bundles := newBundler(useCase.ExportRepo, minioClient, cfg.Export)
+	go runEvery(jobCtx, cfg.Export.BundleInterval, "build export bundles", bundles.RunPending)
+
 	// // Redis
 	// var rdb = redis.NewClient(&redis.Options{
 	// 	Addr: "redis:6379",
@@ -93,3 +128,25 @@ func Run(cfg *config.Config) {
 	}
 
 }
+
+// runEvery runs job now and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			slog.Error("Failed to "+name, "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package app
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/internal/usecase"
+	"github.com/mirjalilova/voice_transcribe/pkg/minio"
+)
+
+// bundlePageSize is the number of segments read from the database at a time. A page is
+// read in full before its audio is fetched, so no connection is held meanwhile.
+const bundlePageSize = 500
+
+// bundleProgressEvery is the number of segments between progress updates.
+const bundleProgressEvery = 100
+
+// bundler builds the dataset bundles requested through the API: the audio of every
+// selected segment is fetched from MinIO and packed with a manifest.jsonl into a
+// tar.gz, which is uploaded back to MinIO.
+//
+// The bundle is uploaded in parts. Each part is a gzip member of whole tar entries and
+// gzip members can be concatenated, so a part never changes once uploaded. Every part
+// is saved with the last segment in it, and a bundle whose worker went away is picked
+// up after its lease and resumed from the segment after its last part. A worker renews
+// the lease while it builds, whatever the pace of the audio downloads. A failed build
+// is resumed the same way until the bundle runs out of attempts.
+type bundler struct {
+	repo     usecase.ExportRepoI
+	minio    *minio.MinIO
+	lease    time.Duration
+	attempts int
+	partSize int64
+}
+
+func newBundler(repo usecase.ExportRepoI, mn *minio.MinIO, cfg config.Export) *bundler {
+	partSize := max(cfg.PartSize, minio.MinPartSize)
+	return &bundler{repo: repo, minio: mn, lease: cfg.BundleLease, attempts: max(cfg.BundleAttempts, 1), partSize: partSize}
+}
+
+// RunPending builds the queued bundles one after another until none is left.
+func (b *bundler) RunPending(ctx context.Context) error {
+	for {
+		bundle, err := b.repo.ClaimBundle(ctx, b.lease)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Building export bundle", "id", bundle.Id, "attempt", bundle.Attempts)
+		err = b.buildLeased(ctx, bundle)
+		if err == nil {
+			slog.Info("Export bundle built", "id", bundle.Id)
+			continue
+		}
+		if ctx.Err() != nil {
+			// Shutting down; the bundle is resumed once its lease runs out.
+			return err
+		}
+		if errors.Is(err, entity.ErrBundleLeaseLost) {
+			slog.Warn("Export bundle was claimed by another worker", "id", bundle.Id)
+			continue
+		}
+
+		if bundle.Attempts < b.attempts {
+			slog.Warn("Failed to build export bundle, retrying after the lease", "id", bundle.Id, "attempt", bundle.Attempts, "err", err)
+			if err := b.repo.RetryBundle(ctx, bundle.Id, bundle.Attempts, err.Error()); err != nil && !errors.Is(err, entity.ErrBundleLeaseLost) {
+				return err
+			}
+			continue
+		}
+
+		slog.Error("Failed to build export bundle", "id", bundle.Id, "err", err)
+		if bundle.UploadId != "" {
+			if err := b.minio.AbortUpload(ctx, *bundle.ObjectName, bundle.UploadId); err != nil {
+				slog.Warn("Failed to abort export bundle upload", "id", bundle.Id, "err", err)
+			}
+		}
+		if err := b.repo.FailBundle(ctx, bundle.Id, bundle.Attempts, err.Error()); err != nil && !errors.Is(err, entity.ErrBundleLeaseLost) {
+			return err
+		}
+	}
+}
+
+// buildLeased builds the bundle while renewing its lease every third of the lease.
+// The build is stopped as soon as the lease turns out to be lost.
+func (b *bundler) buildLeased(ctx context.Context, bundle *entity.ExportBundle) error {
+	buildCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(max(b.lease/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := b.repo.RenewBundleLease(buildCtx, bundle.Id, bundle.Attempts)
+				if errors.Is(err, entity.ErrBundleLeaseLost) {
+					cancel(err)
+					return
+				}
+				if err != nil {
+					slog.Warn("Failed to renew export bundle lease", "id", bundle.Id, "err", err)
+				}
+			}
+		}
+	}()
+
+	err := b.build(buildCtx, bundle)
+	if cause := context.Cause(buildCtx); errors.Is(cause, entity.ErrBundleLeaseLost) {
+		return cause
+	}
+	return err
+}
+
+func (b *bundler) build(ctx context.Context, bundle *entity.ExportBundle) error {
+	if bundle.UploadId == "" {
+		objectName := fmt.Sprintf("bundles/dataset_%d.tar.gz", bundle.Id)
+		uploadId, err := b.minio.StartUpload(ctx, objectName, "application/gzip")
+		if err != nil {
+			return fmt.Errorf("failed to start upload: %w", err)
+		}
+		if err := b.repo.StartBundleUpload(ctx, bundle.Id, bundle.Attempts, objectName, uploadId); err != nil {
+			return err
+		}
+		bundle.ObjectName, bundle.UploadId = &objectName, uploadId
+	}
+
+	parts, err := b.repo.GetBundleParts(ctx, bundle.Id)
+	if err != nil {
+		return err
+	}
+
+	req := bundle.Filter
+	req.Limit = bundlePageSize
+
+	var (
+		etags              []string
+		processed, skipped int
+		size               int64
+	)
+	for _, part := range parts {
+		etags = append(etags, part.ETag)
+		processed += part.Segments + part.Skipped
+		skipped += part.Skipped
+		size += part.Size
+		req.AfterAudioId, req.AfterSegmentId = part.LastAudioId, part.LastSegmentId
+	}
+	if len(parts) > 0 {
+		slog.Info("Resuming export bundle", "id", bundle.Id, "parts", len(parts), "processed", processed)
+	}
+	if err := b.repo.UpdateBundleProgress(ctx, bundle.Id, bundle.Attempts, processed, skipped); err != nil {
+		return err
+	}
+
+	part, err := newBundlePart(len(parts) + 1)
+	if err != nil {
+		return err
+	}
+	defer func() { part.Close() }()
+
+	upload := func() error {
+		etag, err := part.upload(ctx, b.minio, *bundle.ObjectName, bundle.UploadId)
+		if err != nil {
+			return err
+		}
+		etags = append(etags, etag)
+		size += part.size.n
+		return nil
+	}
+
+	for {
+		page := make([]entity.ManifestEntry, 0, bundlePageSize)
+		err := b.repo.StreamManifest(ctx, &req, func(entry *entity.ManifestEntry) error {
+			page = append(page, *entry)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range page {
+			added, err := part.add(ctx, b.minio, &page[i])
+			if err != nil {
+				return err
+			}
+			processed++
+			if !added {
+				skipped++
+			}
+
+			if part.size.n >= b.partSize {
+				if err := part.seal(); err != nil {
+					return err
+				}
+				if err := upload(); err != nil {
+					return err
+				}
+				if err := b.repo.SaveBundlePart(ctx, bundle.Id, bundle.Attempts, part.record(etags[len(etags)-1])); err != nil {
+					return err
+				}
+
+				next, err := newBundlePart(part.number + 1)
+				if err != nil {
+					return err
+				}
+				part.Close()
+				part = next
+			} else if processed%bundleProgressEvery == 0 {
+				if err := b.repo.UpdateBundleProgress(ctx, bundle.Id, bundle.Attempts, processed, skipped); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(page) < bundlePageSize {
+			break
+		}
+		last := page[len(page)-1]
+		req.AfterAudioId, req.AfterSegmentId = last.AudioId, last.SegmentId
+	}
+
+	// The last part carries the manifest of the whole bundle and the end of the archive.
+	manifest, err := os.CreateTemp("", "bundle-manifest-")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer os.Remove(manifest.Name())
+	defer manifest.Close()
+
+	w := bufio.NewWriter(manifest)
+	err = b.repo.StreamBundleManifest(ctx, bundle.Id, func(lines string) error {
+		_, err := w.WriteString(lines)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := part.manifest.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := part.finish(manifest); err != nil {
+		return err
+	}
+	if err := upload(); err != nil {
+		return err
+	}
+	if err := b.minio.CompleteUpload(ctx, *bundle.ObjectName, bundle.UploadId, etags); err != nil {
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+
+	return b.repo.FinishBundle(ctx, bundle.Id, bundle.Attempts, processed, skipped, size)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// bundlePart is one part of a bundle upload: a gzip member of whole tar entries, kept
+// in a temporary file until it is uploaded, and the manifest lines of its segments.
+type bundlePart struct {
+	number int
+	file   *os.File
+	size   *countingWriter
+	gz     *gzip.Writer
+	tw     *tar.Writer
+
+	manifest bytes.Buffer
+	enc      *json.Encoder
+
+	segments, skipped          int
+	lastAudioId, lastSegmentId int
+}
+
+func newBundlePart(number int) (*bundlePart, error) {
+	f, err := os.CreateTemp("", "bundle-part-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create part file: %w", err)
+	}
+
+	p := &bundlePart{number: number, file: f, size: &countingWriter{w: f}}
+	p.gz = gzip.NewWriter(p.size)
+	p.tw = tar.NewWriter(p.gz)
+	p.enc = json.NewEncoder(&p.manifest)
+	p.enc.SetEscapeHTML(false)
+	return p, nil
+}
+
+// add writes the audio of the segment and its manifest line, with audio_filepath set
+// to the audio in the archive. It returns false when the audio object is missing and
+// the segment was left out.
+func (p *bundlePart) add(ctx context.Context, mn *minio.MinIO, entry *entity.ManifestEntry) (bool, error) {
+	p.lastAudioId, p.lastSegmentId = entry.AudioId, entry.SegmentId
+
+	var (
+		r    io.ReadCloser
+		size int64
+		err  error
+	)
+	if entry.AudioFilepath != "" {
+		r, size, err = mn.Open(ctx, entry.AudioFilepath)
+	}
+	if entry.AudioFilepath == "" || minio.IsNotFound(err) {
+		slog.Warn("Audio of segment is missing, leaving it out of the bundle", "segment_id", entry.SegmentId, "url", entry.AudioFilepath)
+		p.skipped++
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", entry.AudioFilepath, err)
+	}
+	defer r.Close()
+
+	ext := path.Ext(entry.AudioFilepath)
+	if ext == "" {
+		ext = ".wav"
+	}
+	name := fmt.Sprintf("audio/%d/%d%s", entry.AudioId, entry.SegmentId, ext)
+
+	err = p.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(p.tw, r); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	entry.AudioFilepath = name
+	if err := p.enc.Encode(entry); err != nil {
+		return false, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	p.segments++
+	return true, nil
+}
+
+// seal ends the gzip member without ending the archive, so that more parts can follow.
+func (p *bundlePart) seal() error {
+	if err := p.tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write part: %w", err)
+	}
+	if err := p.gz.Close(); err != nil {
+		return fmt.Errorf("failed to write part: %w", err)
+	}
+	return nil
+}
+
+// finish adds manifest.jsonl and ends the archive.
+func (p *bundlePart) finish(manifest *os.File) error {
+	info, err := manifest.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := manifest.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	err = p.tw.WriteHeader(&tar.Header{
+		Name:    "manifest.jsonl",
+		Mode:    0o644,
+		Size:    info.Size(),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := io.Copy(p.tw, manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := p.tw.Close(); err != nil {
+		return fmt.Errorf("failed to write part: %w", err)
+	}
+	if err := p.gz.Close(); err != nil {
+		return fmt.Errorf("failed to write part: %w", err)
+	}
+	return nil
+}
+
+// upload sends the sealed or finished part and returns its ETag.
+func (p *bundlePart) upload(ctx context.Context, mn *minio.MinIO, objectName, uploadId string) (string, error) {
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read part: %w", err)
+	}
+
+	etag, err := mn.UploadPart(ctx, objectName, uploadId, p.number, p.file, p.size.n)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d: %w", p.number, err)
+	}
+	return etag, nil
+}
+
+func (p *bundlePart) record(etag string) *entity.ExportBundlePart {
+	return &entity.ExportBundlePart{
+		Number:        p.number,
+		ETag:          etag,
+		Size:          p.size.n,
+		Segments:      p.segments,
+		Skipped:       p.skipped,
+		LastAudioId:   p.lastAudioId,
+		LastSegmentId: p.lastSegmentId,
+		Manifest:      p.manifest.String(),
+	}
+}
+
+// Close removes the temporary file.
+func (p *bundlePart) Close() error {
+	p.file.Close()
+	return os.Remove(p.file.Name())
+}
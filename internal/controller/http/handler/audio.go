@@ -5,6 +5,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -15,8 +16,10 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 	"github.com/mirjalilova/voice_transcribe/config"
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
 )
@@ -29,6 +32,9 @@ import (
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "Zip file"
+// @Param skills formData string false "Comma separated skill codes required to transcribe the files"
+// @Param priority formData int false "Assignment priority, higher first"
+// @Param deadline formData string false "Deadline as YYYY-MM-DD HH:MM:SS or YYYY-MM-DD, in the work timezone"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -41,6 +47,37 @@ func (h *Handler) UploadZipAndExtractAudio(c *gin.Context) {
 		return
 	}
 
+	var priority int
+	if value := c.PostForm("priority"); value != "" {
+		priority, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority"})
+			return
+		}
+	}
+
+	var deadline *time.Time
+	if value := c.PostForm("deadline"); value != "" {
+		parsed, err := h.parseDeadline(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deadline format, expected YYYY-MM-DD HH:MM:SS or YYYY-MM-DD"})
+			return
+		}
+		deadline = &parsed
+	}
+
+	skills := parseSkillCodes(c.PostForm("skills"))
+	code, err := h.unknownSkillCode(c, skills)
+	if err != nil {
+		slog.Error("Error getting skills", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting skills"})
+		return
+	}
+	if code != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown skill: " + code})
+		return
+	}
+
 	tempPath := filepath.Join(os.TempDir(), file.Filename)
 	if err := c.SaveUploadedFile(file, tempPath); err != nil {
 		slog.Error("Error saving zip file", "err", err)
@@ -103,6 +140,8 @@ func (h *Handler) UploadZipAndExtractAudio(c *gin.Context) {
 		audio_id, err := h.UseCase.AudioFileRepo.Create(c, &entity.CreateAudioFile{
 			Filename: f.Name,
 			FilePath: minioURL,
+			Priority: priority,
+			Deadline: deadline,
 		})
 		if err != nil {
 			c.JSON(500, gin.H{"error": err})
@@ -110,6 +149,15 @@ func (h *Handler) UploadZipAndExtractAudio(c *gin.Context) {
 			return
 		}
 
+		if len(skills) > 0 {
+			err = h.UseCase.SkillRepo.SetAudioSkills(c, *audio_id, skills)
+			if err != nil {
+				slog.Error("Error setting audio file skills", "err", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to set audio file skills"})
+				return
+			}
+		}
+
 		err = h.Chunking(c, *audio_id, dstPath, f.Name)
 		if err != nil {
 			slog.Error("Error chunking audio file", "err", err)
@@ -145,11 +193,28 @@ type Response struct {
 }
 
 func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afileName string) error {
+	chunks, err := h.chunkAudio(c, audio_id, audioPath)
+	if err != nil {
+		return err
+	}
+
+	for i := range chunks {
+		err = h.UseCase.AudioSegmentRepo.Create(c, &chunks[i])
+		if err != nil {
+			return fmt.Errorf("failed to create audio segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// chunkAudio runs the audio through the VAD chunker and uploads the chunks to MinIO.
+// It returns the segments to create without touching the database.
+func (h *Handler) chunkAudio(c *gin.Context, audio_id int, audioPath string) ([]entity.CreateAudioSegment, error) {
 	url := "http://192.168.31.27:9512/vad-chunk"
 
 	file, err := os.Open(audioPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
@@ -158,11 +223,11 @@ func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afile
 
 	part, err := writer.CreateFormFile("audio_file", filepath.Base(audioPath))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	_, err = io.Copy(part, file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	writer.WriteField("min_duration", "1")
@@ -170,12 +235,12 @@ func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afile
 
 	err = writer.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req, err := http.NewRequest("POST", url, &requestBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	req.Header.Set("Accept", "application/json")
@@ -183,50 +248,51 @@ func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afile
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to vad-chunk: %s", resp.Status)
+		return nil, fmt.Errorf("failed to vad-chunk: %s", resp.Status)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var result Response
 	err = json.Unmarshal(body, &result)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	outputDir := "./internal/media/segments"
 	os.MkdirAll(outputDir, os.ModePerm)
 
+	var segments []entity.CreateAudioSegment
 	for _, chunk := range result.Chunks {
 		downloadURL := fmt.Sprintf("http://192.168.31.27:9512/download/%s/%s", result.JobID, chunk.ChunkID)
 
 		resp, err := http.Get(downloadURL)
 		if err != nil {
-			return fmt.Errorf("failed to download chunk: %w", err)
+			return nil, fmt.Errorf("failed to download chunk: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to download chunk: %s", resp.Status)
+			return nil, fmt.Errorf("failed to download chunk: %s", resp.Status)
 		}
 
 		filename := filepath.Join(outputDir, chunk.ChunkID)
 		outFile, err := os.Create(filename)
 		if err != nil {
-			return fmt.Errorf("error creating file: %w", err)
+			return nil, fmt.Errorf("error creating file: %w", err)
 		}
 
 		_, err = io.Copy(outFile, resp.Body)
 		if err != nil {
-			return fmt.Errorf("error saving file: %w", err)
+			return nil, fmt.Errorf("error saving file: %w", err)
 		}
 		outFile.Close()
 
@@ -234,7 +300,7 @@ func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afile
 		if err != nil {
 			slog.Error("Failed to upload file to MinIO", "err", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to storage"})
-			return err
+			return nil, err
 		}
 
 		// a, err := strconv.Atoi(afileName[14:15])
@@ -256,22 +322,19 @@ func (h *Handler) Chunking(c *gin.Context, audio_id int, audioPath string, afile
 		// 	fmt.Println(chunk.Start, chunk.End)
 		// }
 
-		err = h.UseCase.AudioSegmentRepo.Create(c, &entity.CreateAudioSegment{
+		segments = append(segments, entity.CreateAudioSegment{
 			AudioId:  audio_id,
 			FileName: minioURL,
 			Duration: float32(chunk.End - chunk.Start),
 			// TranscribeOption: text,
 		})
-		if err != nil {
-			return fmt.Errorf("failed to create audio segment: %w", err)
-		}
 
 		err = os.Remove(filename)
 		if err != nil {
 			slog.Error("Failed to remove local file after upload", "file", chunk.ChunkID, "err", err)
 		}
 	}
-	return nil
+	return segments, nil
 }
 
 // GetAudioFile godoc
@@ -391,3 +454,265 @@ func extractTranscript(vttFile string, startSecond, endSecond float64) (string,
 
 	return strings.Join(result, " "), nil
 }
+
+// ReopenAudioFile godoc
+// @Router /api/v1/audio_file/{id}/reopen [put]
+// @Summary Re-open an audio file for rework
+// @Description Reset the transcripts of the file, or of the given segments, to ready. Their revision history is kept; the file goes to the given transcriber or back to the pool.
+// @Security BearerAuth
+// @Tags audio
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Audio ID"
+// @Param body body entity.ReopenAudioFileBody true "Re-open request"
+// @Success 200 {object} entity.ReopenAudioFileResult
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ReopenAudioFile(ctx *gin.Context) {
+	var body entity.ReopenAudioFileBody
+
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("ReopenAudioFile error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Reason) == "" {
+		slog.Error("ReopenAudioFile error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[int]bool, len(body.SegmentIds))
+	for _, id := range body.SegmentIds {
+		if seen[id] {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Duplicate segment ID", http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+	}
+
+	var actorId *string
+	if claims, exists := ctx.Get("claims"); exists {
+		id := claims.(jwt.MapClaims)["id"].(string)
+		actorId = &id
+	}
+
+	res, err := h.UseCase.AudioFileRepo.Reopen(ctx, &entity.ReopenAudioFile{
+		AudioId:    intId,
+		Reason:     body.Reason,
+		SegmentIds: body.SegmentIds,
+		UserId:     body.UserId,
+		ActorId:    actorId,
+	})
+	if errors.Is(err, entity.ErrSegmentsNotInFile) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Segments do not belong to the audio file", http.StatusBadRequest)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error re-opening audio file") {
+		slog.Error("ReopenAudioFile error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Audio file re-opened successfully", slog.Int("audio_id", intId), slog.Int("segments", res.Segments))
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ReassignAudioFiles godoc
+// @Router /api/v1/audio_file/reassign [put]
+// @Summary Reassign audio files
+// @Description Move unfinished audio files to another transcriber, or back to the pool when to_user_id is empty. Files are picked by id or by the current transcriber with optional status and date filters.
+// @Security BearerAuth
+// @Tags audio
+// @Accept  json
+// @Produce  json
+// @Param body body entity.ReassignAudioFilesBody true "Reassign request"
+// @Success 200 {object} entity.ReassignAudioFilesResult
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ReassignAudioFiles(ctx *gin.Context) {
+	var body entity.ReassignAudioFilesBody
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("ReassignAudioFiles error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.AudioIds) == 0 && body.FromUserId == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "audio_ids or from_user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if body.Status != "" && body.Status != "pending" && body.Status != "processing" && body.Status != "unassigned" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Status must be one of pending, processing, unassigned", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.AudioIds) == 0 && body.Status == "" {
+		body.Status = "processing"
+	}
+
+	if body.ToUserId != "" && body.ToUserId == body.FromUserId {
+		h.ReturnError(ctx, config.ErrorBadRequest, "to_user_id must differ from from_user_id", http.StatusBadRequest)
+		return
+	}
+
+	var actorId *string
+	if claims, exists := ctx.Get("claims"); exists {
+		id := claims.(jwt.MapClaims)["id"].(string)
+		actorId = &id
+	}
+
+	res, err := h.UseCase.AudioFileRepo.Reassign(ctx, &entity.ReassignAudioFiles{
+		ReassignAudioFilesBody: body,
+		ActorId:                actorId,
+	})
+	if h.HandleDbError(ctx, err, "Error reassigning audio files") {
+		slog.Error("ReassignAudioFiles error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Audio files reassigned successfully", slog.Int("moved", res.Moved), slog.String("to_user_id", body.ToUserId))
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetAudioFileEvents godoc
+// @Router /api/v1/audio_file/{id}/events [get]
+// @Summary Get audio file events
+// @Description Audit trail of re-opens and reassignments of the audio file
+// @Security BearerAuth
+// @Tags audio
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Audio ID"
+// @Success 200 {object} entity.AudioFileEventList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetAudioFileEvents(ctx *gin.Context) {
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("GetAudioFileEvents error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.UseCase.AudioFileRepo.GetEvents(ctx, intId)
+	if h.HandleDbError(ctx, err, "Error getting audio file events") {
+		slog.Error("GetAudioFileEvents error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// UpdateAudioFilePriority godoc
+// @Router /api/v1/audio_file/{id}/priority [put]
+// @Summary Update the priority and deadline of an audio file
+// @Description Files are assigned by priority, then by deadline, then by age. An empty deadline clears it.
+// @Security BearerAuth
+// @Tags audio
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Audio ID"
+// @Param body body entity.UpdateAudioFilePriorityBody true "Priority and deadline"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateAudioFilePriority(ctx *gin.Context) {
+	var body entity.UpdateAudioFilePriorityBody
+
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("UpdateAudioFilePriority error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("UpdateAudioFilePriority error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Priority == nil && body.Deadline == nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	req := entity.UpdateAudioFilePriority{
+		AudioId:  intId,
+		Priority: body.Priority,
+	}
+	if body.Deadline != nil {
+		if *body.Deadline == "" {
+			req.ClearDeadline = true
+		} else {
+			deadline, err := h.parseDeadline(*body.Deadline)
+			if err != nil {
+				h.ReturnError(ctx, config.ErrorBadRequest, "Invalid deadline format, expected YYYY-MM-DD HH:MM:SS or YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			req.Deadline = &deadline
+		}
+	}
+
+	err = h.UseCase.AudioFileRepo.UpdatePriority(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error updating audio file priority") {
+		slog.Error("UpdateAudioFilePriority error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Audio file priority updated successfully",
+	})
+}
+
+// GetDeadlineRisks godoc
+// @Router /api/v1/dashboard/deadlines [get]
+// @Summary Get audio files at risk of missing their deadline
+// @Description Estimate when each unfinished file with a deadline will be done from the throughput of the last days and the backlog ahead of it in the assignment order.
+// @Security BearerAuth
+// @Tags dashboard
+// @Accept  json
+// @Produce  json
+// @Param days query int false "Days to measure throughput over, 7 by default"
+// @Param at_risk query bool false "Only files at risk"
+// @Success 200 {object} entity.DeadlineRiskList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetDeadlineRisks(ctx *gin.Context) {
+	days := 7
+	if value := ctx.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+	atRisk, _ := strconv.ParseBool(ctx.Query("at_risk"))
+
+	res, err := h.UseCase.AudioFileRepo.GetDeadlineRisks(ctx, days, atRisk)
+	if h.HandleDbError(ctx, err, "Error getting deadline risks") {
+		slog.Error("GetDeadlineRisks error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// parseDeadline accepts a timestamp or a date, which stands for the end of that day,
+// both in the work timezone.
+func (h *Handler) parseDeadline(value string) (time.Time, error) {
+	loc, err := time.LoadLocation(h.Config.Work.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if deadline, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc); err == nil {
+		return deadline, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.Add(24*time.Hour - time.Second), nil
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -98,6 +101,8 @@ func (h *Handler) GetAudioSegment(ctx *gin.Context) {
 // @Param status query string false "Filter by status"
 // @Success 200 {object} entity.AudioSegmentList
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 403 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
 func (h *Handler) GetAudioSegments(ctx *gin.Context) {
 	var req entity.GetAudioSegmentReq
 
@@ -139,6 +144,10 @@ func (h *Handler) GetAudioSegments(ctx *gin.Context) {
 		return
 	}
 
+	if h.quotaExceeded(ctx, req.UserID) {
+		return
+	}
+
 	// Fetch audio_segment
 	audio_segment, err := h.UseCase.AudioSegmentRepo.GetList(ctx, &req)
 	if h.HandleDbError(ctx, err, "Error getting audio_segment") {
@@ -249,6 +258,12 @@ func (h *Handler) GetUserTranscriptStatictics(ctx *gin.Context) {
 		return
 	}
 
+	res.Quota, err = h.UseCase.QuotaRepo.GetUserQuota(ctx, userId)
+	if h.HandleDbError(ctx, err, "Error getting quota of user") {
+		slog.Error("GetUserTranscriptStatictics error", slog.String("error", err.Error()))
+		return
+	}
+
 	// Return response
 	ctx.JSON(http.StatusOK, res)
 }
@@ -263,7 +278,9 @@ func (h *Handler) GetUserTranscriptStatictics(ctx *gin.Context) {
 // @Produce  json
 // @Param user_id query string false "User ID"
 // @Param report query bool false "Report"
-// @Param ru query bool false "Russian"
+// @Param language query string false "Segments in this language, as their primary language or in a passage"
+// @Param ru query bool false "Russian, the same as language=ru"
+// @Param include_flagged query bool false "Include transcripts flagged by the quality check"
 // @Param offset query number false "Offset for pagination"
 // @Param limit query number false "Limit for pagination"
 // @Success 200 {object} entity.DatasetViewerListResponse
@@ -310,12 +327,26 @@ func (h *Handler) DatasetViewer(ctx *gin.Context) {
 		return
 	}
 
-	ru := ctx.Query("ru")
-	ruBool, err := strconv.ParseBool(ru)
-	if err != nil {
-		slog.Error("Error parsing ru parameter: ", "err", err)
-		ctx.JSON(400, gin.H{"Error": "Invalid ru parameter"})
-		return
+	language := ctx.Query("language")
+	if ru := ctx.Query("ru"); ru != "" {
+		ruBool, err := strconv.ParseBool(ru)
+		if err != nil {
+			slog.Error("Error parsing ru parameter: ", "err", err)
+			ctx.JSON(400, gin.H{"Error": "Invalid ru parameter"})
+			return
+		}
+		if ruBool {
+			language = "ru"
+		}
+	}
+
+	includeFlagged := false
+	if value := ctx.Query("include_flagged"); value != "" {
+		includeFlagged, err = strconv.ParseBool(value)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid include_flagged parameter", http.StatusBadRequest)
+			return
+		}
 	}
 
 	// If page & limit are provided, validate them
@@ -329,7 +360,7 @@ func (h *Handler) DatasetViewer(ctx *gin.Context) {
 	req.Offset = offsetValue
 
 	// Fetch audio_segment
-	dataset_viewer, err := h.UseCase.AudioSegmentRepo.DatasetViewer(ctx, &req, user_id, reportBool, ruBool)
+	dataset_viewer, err := h.UseCase.AudioSegmentRepo.DatasetViewer(ctx, &req, user_id, language, reportBool, includeFlagged)
 	if h.HandleDbError(ctx, err, "Error getting audio_segment") {
 		slog.Error("DatasetViewer error", slog.String("error", err.Error()))
 		return
@@ -373,6 +404,119 @@ func (h *Handler) GetStatistic(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, statistic)
 }
 
+// defaultHistogramWidths are the bucket widths used when neither width nor edges is given.
+var defaultHistogramWidths = map[string]float64{
+	entity.HistogramDuration:     1,
+	entity.HistogramChars:        15,
+	entity.HistogramWords:        5,
+	entity.HistogramCharsPerSec:  5,
+	entity.HistogramMinutesSpent: 1,
+}
+
+// minHistogramWidths are the smallest bucket widths accepted, so a tiny width cannot
+// overflow the bucket index or ask for millions of buckets.
+var minHistogramWidths = map[string]float64{
+	entity.HistogramDuration:     0.01,
+	entity.HistogramChars:        1,
+	entity.HistogramWords:        1,
+	entity.HistogramCharsPerSec:  0.01,
+	entity.HistogramMinutesSpent: 0.01,
+}
+
+// maxHistogramEdges bounds the number of explicit bucket edges.
+const maxHistogramEdges = 1000
+
+// GetHistogram godoc
+// @Router /api/v1/statistic/histogram [get]
+// @Summary Get a histogram of segments
+// @Description Count segments per bucket of duration, chars, words, chars_per_sec or minutes_spent. Buckets are either of equal width or bounded by explicit edges and come back in order.
+// @Security BearerAuth
+// @Tags dashboard
+// @Accept  json
+// @Produce  json
+// @Param dimension query string true "duration, chars, words, chars_per_sec or minutes_spent"
+// @Param width query number false "Bucket width, at least 1 for chars and words and 0.01 otherwise"
+// @Param edges query string false "Comma separated ascending bucket edges, e.g. 0,1,2,5,10"
+// @Param from_date query string false "From date (YYYY-MM-DD)"
+// @Param to_date query string false "To date (YYYY-MM-DD)"
+// @Param user_id query string false "Transcriber ID"
+// @Param audio_id query int false "Audio ID"
+// @Param status query string false "Transcript status: done (default), invalid or ready"
+// @Success 200 {object} entity.Histogram
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetHistogram(ctx *gin.Context) {
+	req := entity.HistogramReq{
+		Dimension: ctx.Query("dimension"),
+		FromDate:  ctx.Query("from_date"),
+		ToDate:    ctx.Query("to_date"),
+		UserId:    ctx.Query("user_id"),
+		Status:    ctx.DefaultQuery("status", "done"),
+	}
+
+	width, ok := defaultHistogramWidths[req.Dimension]
+	if !ok {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Dimension must be one of duration, chars, words, chars_per_sec, minutes_spent", http.StatusBadRequest)
+		return
+	}
+
+	if req.Status != "done" && req.Status != "invalid" && req.Status != "ready" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Status must be one of done, invalid, ready", http.StatusBadRequest)
+		return
+	}
+
+	if value := ctx.Query("edges"); value != "" {
+		parts := strings.Split(value, ",")
+		if len(parts) > maxHistogramEdges {
+			h.ReturnError(ctx, config.ErrorBadRequest, fmt.Sprintf("At most %d edges are allowed", maxHistogramEdges), http.StatusBadRequest)
+			return
+		}
+		for _, edge := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(edge), 64)
+			if err != nil || (len(req.Edges) > 0 && v <= req.Edges[len(req.Edges)-1]) {
+				h.ReturnError(ctx, config.ErrorBadRequest, "Edges must be ascending numbers", http.StatusBadRequest)
+				return
+			}
+			req.Edges = append(req.Edges, v)
+		}
+	} else if value := ctx.Query("width"); value != "" {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < minHistogramWidths[req.Dimension] {
+			h.ReturnError(ctx, config.ErrorBadRequest, fmt.Sprintf("Width must be a number of at least %g", minHistogramWidths[req.Dimension]), http.StatusBadRequest)
+			return
+		}
+		width = v
+	}
+	req.Width = width
+
+	for _, date := range []string{req.FromDate, req.ToDate} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := ctx.Query("audio_id"); value != "" {
+		audioId, err := strconv.Atoi(value)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+			return
+		}
+		req.AudioId = audioId
+	}
+
+	res, err := h.UseCase.AudioSegmentRepo.GetHistogram(ctx, &req)
+	if errors.Is(err, entity.ErrTooManyBuckets) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Width gives too many buckets, use a larger width", http.StatusBadRequest)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error getting histogram") {
+		slog.Error("GetHistogram error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
 // GetAudioTranscriptStats godoc
 // @Router /api/v1/dashboard/stats [get]
 // @Summary Get  AudioT ranscript Stats
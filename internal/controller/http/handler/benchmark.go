@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+// CreateAsrModel godoc
+// @Router /api/v1/benchmark/model [post]
+// @Summary Register an ASR model
+// @Description Register an ASR model to be benchmarked against approved transcripts
+// @Security BearerAuth
+// @Tags benchmark
+// @Accept  json
+// @Produce  json
+// @Param model body entity.CreateAsrModel true "Model object"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateAsrModel(ctx *gin.Context) {
+	var body entity.CreateAsrModel
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Name) == "" {
+		slog.Error("CreateAsrModel error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if claims, exists := ctx.Get("claims"); exists {
+		userId := claims.(jwt.MapClaims)["id"].(string)
+		body.CreatedBy = &userId
+	}
+
+	id, err := h.UseCase.BenchmarkRepo.CreateModel(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error creating asr model") {
+		slog.Error("CreateAsrModel error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Asr model created successfully", slog.String("name", body.Name))
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Asr model created successfully",
+		"id":      id,
+	})
+}
+
+// GetAsrModels godoc
+// @Router /api/v1/benchmark/model/list [get]
+// @Summary Get a list of ASR models
+// @Description Get a list of ASR models
+// @Security BearerAuth
+// @Tags benchmark
+// @Accept  json
+// @Produce  json
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.AsrModelList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetAsrModels(ctx *gin.Context) {
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+
+	models, err := h.UseCase.BenchmarkRepo.GetModels(ctx, &entity.Filter{Limit: limitValue, Offset: offsetValue})
+	if h.HandleDbError(ctx, err, "Error getting asr models") {
+		slog.Error("GetAsrModels error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models)
+}
+
+// UploadAsrHypotheses godoc
+// @Router /api/v1/benchmark/model/{id}/hypotheses [post]
+// @Summary Upload ASR hypotheses
+// @Description Upload a JSONL file of {"segment_id": 1, "hypothesis": "..."} lines. Each hypothesis is scored against the approved transcript of its segment. A file with an invalid line is rejected as a whole.
+// @Security BearerAuth
+// @Tags benchmark
+// @Accept multipart/form-data
+// @Produce  json
+// @Param id path int true "Model ID"
+// @Param file formData file true "JSONL file"
+// @Success 200 {object} entity.AsrUploadResult
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UploadAsrHypotheses(ctx *gin.Context) {
+	modelId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("UploadAsrHypotheses error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid model ID", http.StatusBadRequest)
+		return
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		slog.Error("Error getting file from form", "err", err)
+		h.ReturnError(ctx, config.ErrorBadRequest, "File is required", http.StatusBadRequest)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		slog.Error("Error opening uploaded file", "err", err)
+		h.ReturnError(ctx, config.ErrorInternalServer, "Unable to open file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	// The whole file is read and checked before anything is saved, so a bad line
+	// leaves the earlier scores of the model as they were.
+	var hyps []entity.AsrHypothesis
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var hyp entity.AsrHypothesis
+		if err := json.Unmarshal([]byte(text), &hyp); err != nil || hyp.SegmentId == 0 {
+			slog.Error("UploadAsrHypotheses error", slog.Int("line", line), slog.Any("error", err))
+			h.ReturnError(ctx, config.ErrorBadRequest, fmt.Sprintf("Invalid hypothesis on line %d", line), http.StatusBadRequest)
+			return
+		}
+		hyps = append(hyps, hyp)
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Error("Error reading uploaded file", "err", err)
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unable to read file", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.UseCase.BenchmarkRepo.SaveHypotheses(ctx, modelId, hyps)
+	if h.HandleDbError(ctx, err, "Error saving asr hypotheses") {
+		slog.Error("UploadAsrHypotheses error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Asr hypotheses uploaded successfully", slog.Int("model_id", modelId), slog.Int("scored", res.Scored))
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetAsrLeaderboard godoc
+// @Router /api/v1/benchmark/leaderboard [get]
+// @Summary Get the ASR model leaderboard
+// @Description Rank the models by WER, broken down by duration bucket, emotion and language
+// @Security BearerAuth
+// @Tags benchmark
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.AsrLeaderboard
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetAsrLeaderboard(ctx *gin.Context) {
+	res, err := h.UseCase.BenchmarkRepo.GetLeaderboard(ctx)
+	if h.HandleDbError(ctx, err, "Error getting asr leaderboard") {
+		slog.Error("GetAsrLeaderboard error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/langspan"
+)
+
+// GetEventTags godoc
+// @Router /api/v1/event_tag/list [get]
+// @Summary Get the event tag catalog
+// @Description Get the non-speech event tags transcribers write in brackets, such as [Musiqa], with their aliases. Inactive tags are only listed with all=true.
+// @Security BearerAuth
+// @Tags event_tag
+// @Accept  json
+// @Produce  json
+// @Param all query bool false "Include inactive tags"
+// @Success 200 {object} entity.EventTagList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetEventTags(ctx *gin.Context) {
+	all, _ := strconv.ParseBool(ctx.Query("all"))
+
+	res, err := h.UseCase.EventTagRepo.GetTags(ctx, !all)
+	if h.HandleDbError(ctx, err, "Error getting event tags") {
+		slog.Error("GetEventTags error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// CreateEventTag godoc
+// @Router /api/v1/event_tag [post]
+// @Summary Add an event tag
+// @Description Add an event tag to the catalog. Transcripts saved from now on have its aliases rewritten to the tag.
+// @Security BearerAuth
+// @Tags event_tag
+// @Accept  json
+// @Produce  json
+// @Param tag body entity.CreateEventTag true "Event tag"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateEventTag(ctx *gin.Context) {
+	var body entity.CreateEventTag
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Code) == "" || strings.TrimSpace(body.Tag) == "" || body.Name.Uz == "" {
+		slog.Error("CreateEventTag error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := eventTagError(body.Tag, body.Aliases); msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.UseCase.EventTagRepo.CreateTag(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error creating event tag") {
+		slog.Error("CreateEventTag error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Event tag created successfully", slog.String("code", body.Code))
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Event tag created successfully",
+		"id":      id,
+	})
+}
+
+// UpdateEventTag godoc
+// @Router /api/v1/event_tag/{id} [put]
+// @Summary Update an event tag
+// @Description Update the spelling, aliases, export token, names, order or activity of an event tag
+// @Security BearerAuth
+// @Tags event_tag
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Event tag ID"
+// @Param tag body entity.UpdateEventTag true "Event tag"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateEventTag(ctx *gin.Context) {
+	var body entity.UpdateEventTag
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid event tag ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("UpdateEventTag error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Tag) == "" && body.Aliases == nil && body.ExportAs == nil && body.Name.Uz == "" &&
+		body.Name.Ru == "" && body.Name.Cy == "" && body.IsActive == nil && body.SortOrder == nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+	var aliases []string
+	if body.Aliases != nil {
+		aliases = *body.Aliases
+	}
+	if msg := eventTagError(body.Tag, aliases); msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return
+	}
+	body.Id = id
+
+	err = h.UseCase.EventTagRepo.UpdateTag(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error updating event tag") {
+		slog.Error("UpdateEventTag error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Event tag updated successfully",
+	})
+}
+
+// DeleteEventTag godoc
+// @Router /api/v1/event_tag/{id} [delete]
+// @Summary Delete an event tag
+// @Description Delete an event tag from the catalog
+// @Security BearerAuth
+// @Tags event_tag
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Event tag ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteEventTag(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid event tag ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.EventTagRepo.DeleteTag(ctx, id)
+	if h.HandleDbError(ctx, err, "Error deleting event tag") {
+		slog.Error("DeleteEventTag error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Event tag deleted successfully",
+	})
+}
+
+// GetEventTagStats godoc
+// @Router /api/v1/event_tag/stats [get]
+// @Summary Get event tag statistics
+// @Description Count the segments and hours with each event tag of the catalog, written in its catalog spelling or an alias, among the segments the export filters select.
+// @Security BearerAuth
+// @Tags event_tag
+// @Produce  json
+// @Param status query string false "Transcript status, done by default"
+// @Param from_date query string false "Submitted from (YYYY-MM-DD)"
+// @Param to_date query string false "Submitted to (YYYY-MM-DD)"
+// @Param user_id query string false "Transcriber ID"
+// @Param language query string false "Primary language of the segments, such as uz or ru"
+// @Param span_language query string false "Segments with a passage in this language, such as ru"
+// @Param include_flagged query bool false "Include transcripts flagged by the quality check"
+// @Param split query string false "Dataset split, such as train, dev or test"
+// @Success 200 {object} entity.EventTagStats
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetEventTagStats(ctx *gin.Context) {
+	req, ok := h.parseManifestReq(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := h.UseCase.EventTagRepo.GetStats(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting event tag stats") {
+		slog.Error("GetEventTagStats error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// eventTagError returns what is wrong with the spellings of an event tag, or "" when
+// they are valid.
+func eventTagError(tag string, aliases []string) string {
+	for _, name := range append([]string{tag}, aliases...) {
+		if strings.ContainsAny(name, "[]") {
+			return "Write tags and aliases without brackets"
+		}
+	}
+	return ""
+}
+
+// normalizeTags writes the event tags of a transcript in their catalog spelling and
+// moves its language spans to match.
+func normalizeTags(catalog *eventtag.Catalog, text, language string, spans []entity.LanguageSpan) (string, []entity.LanguageSpan) {
+	var in []langspan.Span
+	for _, s := range spans {
+		in = append(in, langspan.Span(s))
+	}
+
+	text, out := langspan.Map(text, language, in, func(part, _ string) string {
+		return catalog.Normalize(part)
+	})
+
+	res := make([]entity.LanguageSpan, len(out))
+	for i, s := range out {
+		res[i] = entity.LanguageSpan(s)
+	}
+	return text, res
+}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/kaldi"
+	"github.com/mirjalilova/voice_transcribe/pkg/translit"
+)
+
+// exportFlushEvery is the number of manifest lines written between flushes.
+const exportFlushEvery = 500
+
+// parseManifestReq reads the export filters from the query. It writes an error and
+// returns false when a filter is invalid.
+func (h *Handler) parseManifestReq(ctx *gin.Context) (*entity.ManifestReq, bool) {
+	req := entity.ManifestReq{
+		Status:       ctx.DefaultQuery("status", "done"),
+		FromDate:     ctx.Query("from_date"),
+		ToDate:       ctx.Query("to_date"),
+		UserId:       ctx.Query("user_id"),
+		Language:     ctx.Query("language"),
+		SpanLanguage: ctx.Query("span_language"),
+		Split:        ctx.Query("split"),
+		Script:       ctx.Query("script"),
+		Tags:         ctx.Query("tags"),
+	}
+
+	if msg := h.manifestReqError(&req); msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return nil, false
+	}
+	if value := ctx.Query("include_flagged"); value != "" {
+		includeFlagged, err := strconv.ParseBool(value)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid include_flagged parameter", http.StatusBadRequest)
+			return nil, false
+		}
+		req.IncludeFlagged = includeFlagged
+	}
+	if value := ctx.Query("normalize"); value != "" {
+		normalize, err := strconv.ParseBool(value)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid normalize parameter", http.StatusBadRequest)
+			return nil, false
+		}
+		req.Normalize = normalize
+	}
+
+	return &req, true
+}
+
+// manifestReqError returns what is wrong with the export filters, or "" when they are valid.
+func (h *Handler) manifestReqError(req *entity.ManifestReq) string {
+	if req.Status != "done" && req.Status != "invalid" && req.Status != "ready" {
+		return "Status must be one of done, invalid, ready"
+	}
+	codes := h.languageCodes()
+	for _, language := range []string{req.Language, req.SpanLanguage} {
+		if language != "" && !slices.Contains(codes, language) {
+			return "Language must be one of " + strings.Join(codes, ", ")
+		}
+	}
+	if req.Script != "" && req.Script != string(translit.Latin) && req.Script != string(translit.Cyrillic) {
+		return "Script must be one of latin, cyrillic"
+	}
+	switch eventtag.Mode(req.Tags) {
+	case "", eventtag.Keep, eventtag.Map, eventtag.Drop:
+	default:
+		return "Tags must be one of keep, map, drop"
+	}
+	for _, date := range []string{req.FromDate, req.ToDate} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return "Invalid date format, expected YYYY-MM-DD"
+		}
+	}
+	return ""
+}
+
+// streamContext lifts the request and write timeouts for a long download. Requests
+// are otherwise cut after a few seconds; a client going away still ends the download
+// through the failed writes.
+func streamContext(ctx *gin.Context) context.Context {
+	rc := http.NewResponseController(ctx.Writer)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("Unable to lift the write deadline", "err", err)
+	}
+	return context.WithoutCancel(ctx.Request.Context())
+}
+
+// headerWriter sets the response headers right before the first write, so that an
+// error hit before any output can still be answered with a JSON error.
+type headerWriter struct {
+	ctx    *gin.Context
+	header func()
+}
+
+func (w *headerWriter) Write(p []byte) (int, error) {
+	if !w.ctx.Writer.Written() {
+		w.header()
+	}
+	return w.ctx.Writer.Write(p)
+}
+
+// ExportManifest godoc
+// @Router /api/v1/export/manifest [get]
+// @Summary Export the dataset as a JSONL manifest
+// @Description Stream one JSON object per segment with audio_filepath, duration, text, emotion, speaker and audio_id, as NeMo and HuggingFace datasets expect. Each audio file counts as one speaker. Transcripts flagged by the quality check are left out unless include_flagged is set.
+// @Security BearerAuth
+// @Tags export
+// @Produce  json
+// @Param status query string false "Transcript status, done by default"
+// @Param from_date query string false "Submitted from (YYYY-MM-DD)"
+// @Param to_date query string false "Submitted to (YYYY-MM-DD)"
+// @Param user_id query string false "Transcriber ID"
+// @Param language query string false "Primary language of the segments, such as uz or ru"
+// @Param span_language query string false "Segments with a passage in this language, such as ru"
+// @Param include_flagged query bool false "Include transcripts flagged by the quality check"
+// @Param split query string false "Dataset split, such as train, dev or test"
+// @Param script query string false "Write Uzbek transcripts in latin or cyrillic"
+// @Param tags query string false "Event tags: keep in the catalog spelling, map to their export token or drop"
+// @Param normalize query bool false "Add text_normalized, the text normalized for training"
+// @Success 200 {object} entity.ManifestEntry
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ExportManifest(ctx *gin.Context) {
+	req, ok := h.parseManifestReq(ctx)
+	if !ok {
+		return
+	}
+
+	streamCtx := streamContext(ctx)
+
+	w := bufio.NewWriter(ctx.Writer)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	writeHeader := func() {
+		ctx.Header("Content-Type", "application/x-ndjson")
+		ctx.Header("Content-Disposition", `attachment; filename="manifest.jsonl"`)
+		ctx.Status(http.StatusOK)
+	}
+
+	lines := 0
+	err := h.UseCase.ExportRepo.StreamManifest(streamCtx, req, func(entry *entity.ManifestEntry) error {
+		if lines == 0 {
+			writeHeader()
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		lines++
+		if lines%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && lines == 0 {
+		if h.HandleDbError(ctx, err, "Error exporting manifest") {
+			slog.Error("ExportManifest error", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if err != nil {
+		// The status is already sent; a cut manifest is all the client can tell.
+		slog.Error("ExportManifest stopped", slog.Int("lines", lines), slog.String("error", err.Error()))
+		return
+	}
+
+	if lines == 0 {
+		writeHeader()
+	}
+	if err := w.Flush(); err != nil {
+		slog.Error("ExportManifest flush error", slog.String("error", err.Error()))
+		return
+	}
+	ctx.Writer.Flush()
+
+	slog.Info("Manifest exported", slog.Int("lines", lines))
+}
+
+// ExportKaldi godoc
+// @Router /api/v1/export/kaldi [get]
+// @Summary Export the dataset as a Kaldi data directory
+// @Description Stream a tar.gz with data/wav.scp, text, segments, utt2spk, spk2utt and utt2dur for Kaldi and ESPnet recipes. Utterance ids are built from the audio and segment ids, and each audio file counts as one speaker. wav.scp references the MinIO URLs, or with audio=bundle the audio files packed under data/wav. Filters are those of the JSONL export.
+// @Security BearerAuth
+// @Tags export
+// @Produce  application/gzip
+// @Param audio query string false "url (default) or bundle"
+// @Param status query string false "Transcript status, done by default"
+// @Param from_date query string false "Submitted from (YYYY-MM-DD)"
+// @Param to_date query string false "Submitted to (YYYY-MM-DD)"
+// @Param user_id query string false "Transcriber ID"
+// @Param language query string false "Primary language of the segments, such as uz or ru"
+// @Param span_language query string false "Segments with a passage in this language, such as ru"
+// @Param include_flagged query bool false "Include transcripts flagged by the quality check"
+// @Param split query string false "Dataset split, such as train, dev or test"
+// @Param script query string false "Write Uzbek transcripts in latin or cyrillic"
+// @Param tags query string false "Event tags: keep in the catalog spelling, map to their export token or drop"
+// @Param normalize query bool false "Use the text normalized for training"
+// @Success 200 {file} file
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ExportKaldi(ctx *gin.Context) {
+	req, ok := h.parseManifestReq(ctx)
+	if !ok {
+		return
+	}
+
+	var open kaldi.Opener
+	switch ctx.DefaultQuery("audio", "url") {
+	case "url":
+	case "bundle":
+		open = h.MinIO.Open
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Audio must be one of url, bundle", http.StatusBadRequest)
+		return
+	}
+
+	streamCtx := streamContext(ctx)
+
+	// Nothing reaches the client before the first bundled audio file or, without
+	// bundling, before the lists are appended, so earlier errors still get a status.
+	w := &headerWriter{ctx: ctx, header: func() {
+		ctx.Header("Content-Type", "application/gzip")
+		ctx.Header("Content-Disposition", `attachment; filename="kaldi_data.tar.gz"`)
+		ctx.Status(http.StatusOK)
+	}}
+	exporter, err := kaldi.NewExporter(w, open)
+	if err != nil {
+		slog.Error("ExportKaldi error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorInternalServer, "Unable to start export", http.StatusInternalServerError)
+		return
+	}
+	// Close only removes the temporary lists; it writes nothing to the response.
+	defer exporter.Close()
+
+	err = h.UseCase.ExportRepo.StreamManifest(streamCtx, req, func(entry *entity.ManifestEntry) error {
+		return exporter.Add(streamCtx, kaldi.Utterance{
+			AudioId:   entry.AudioId,
+			SegmentId: entry.SegmentId,
+			AudioURL:  entry.AudioFilepath,
+			Text:      entry.TrainingText(req.Normalize),
+			Duration:  entry.Duration,
+		})
+	})
+	if err == nil {
+		err = exporter.Finish()
+	}
+	if err != nil && !ctx.Writer.Written() {
+		if h.HandleDbError(ctx, err, "Error exporting Kaldi data directory") {
+			slog.Error("ExportKaldi error", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if err != nil {
+		// The archive is left unfinished so that the client sees it is broken.
+		slog.Error("ExportKaldi stopped", slog.Int("utterances", exporter.Count()), slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Kaldi data directory exported", slog.Int("utterances", exporter.Count()))
+}
+
+// CreateExportBundle godoc
+// @Router /api/v1/export/bundles [post]
+// @Summary Request a self-contained dataset bundle
+// @Description Queue a background job that fetches the audio of every selected segment from MinIO and packs it with a manifest.jsonl into a tar.gz stored back in MinIO, for machines that cannot reach the MinIO URLs. In the manifest audio_filepath points into the archive; segments whose audio object is missing are left out and counted as skipped. Filters are those of the JSONL export.
+// @Security BearerAuth
+// @Tags export
+// @Accept  json
+// @Produce  json
+// @Param filter body entity.ManifestReq true "Segment filters"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateExportBundle(ctx *gin.Context) {
+	var body entity.ManifestReq
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		slog.Error("CreateExportBundle error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Status == "" {
+		body.Status = "done"
+	}
+	if msg := h.manifestReqError(&body); msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return
+	}
+
+	var createdBy *string
+	if claims, exists := ctx.Get("claims"); exists {
+		userId := claims.(jwt.MapClaims)["id"].(string)
+		createdBy = &userId
+	}
+
+	id, err := h.UseCase.ExportRepo.CreateBundle(ctx, &body, createdBy)
+	if h.HandleDbError(ctx, err, "Error creating export bundle") {
+		slog.Error("CreateExportBundle error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Export bundle requested", slog.Int("id", *id))
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Export bundle requested successfully",
+		"id":      id,
+	})
+}
+
+// GetExportBundles godoc
+// @Router /api/v1/export/bundles/list [get]
+// @Summary Get a list of dataset bundles
+// @Description Get the requested dataset bundles, newest first, with their progress
+// @Security BearerAuth
+// @Tags export
+// @Accept  json
+// @Produce  json
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.ExportBundleList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetExportBundles(ctx *gin.Context) {
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+
+	bundles, err := h.UseCase.ExportRepo.GetBundles(ctx, &entity.Filter{Limit: limitValue, Offset: offsetValue})
+	if h.HandleDbError(ctx, err, "Error getting export bundles") {
+		slog.Error("GetExportBundles error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, bundles)
+}
+
+// GetExportBundle godoc
+// @Router /api/v1/export/bundles/{id} [get]
+// @Summary Get a dataset bundle
+// @Description Get the status and progress of a dataset bundle. Once it is done, download_url is a presigned link to the tar.gz on the MinIO endpoint that expires after a while; get the bundle again for a fresh link.
+// @Security BearerAuth
+// @Tags export
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Bundle ID"
+// @Success 200 {object} entity.ExportBundle
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetExportBundle(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid bundle ID", http.StatusBadRequest)
+		return
+	}
+
+	bundle, err := h.UseCase.ExportRepo.GetBundle(ctx, id)
+	if h.HandleDbError(ctx, err, "Error getting export bundle") {
+		slog.Error("GetExportBundle error", slog.String("error", err.Error()))
+		return
+	}
+
+	if bundle.Status == entity.BundleDone && bundle.ObjectName != nil {
+		link, err := h.MinIO.PresignedURL(ctx, *bundle.ObjectName, h.Config.Export.LinkExpiry)
+		if err != nil {
+			slog.Error("GetExportBundle error", slog.String("error", err.Error()))
+			h.ReturnError(ctx, config.ErrorInternalServer, "Unable to create download link", http.StatusInternalServerError)
+			return
+		}
+		bundle.DownloadURL = link
+	}
+
+	ctx.JSON(http.StatusOK, bundle)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/fraud"
+)
+
+// parseFraudDays reads the days query parameter, 30 by default.
+func (h *Handler) parseFraudDays(ctx *gin.Context) (int, bool) {
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Days must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+	return days, true
+}
+
+// GetUserRisks godoc
+// @Router /api/v1/fraud/users [get]
+// @Summary Get the risk scores of transcribers
+// @Description Rank transcribers by how much of their recent work looks low-effort: unchanged AI text, copies of the neighbouring segments, submissions faster than the audio and repetitive filler.
+// @Security BearerAuth
+// @Tags fraud
+// @Accept  json
+// @Produce  json
+// @Param days query int false "Submissions of the last days, 30 by default"
+// @Success 200 {object} entity.UserRiskList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetUserRisks(ctx *gin.Context) {
+	days, ok := h.parseFraudDays(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := h.UseCase.FraudRepo.GetUserRisks(ctx, days)
+	if h.HandleDbError(ctx, err, "Error getting user risks") {
+		slog.Error("GetUserRisks error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetFraudFlags godoc
+// @Router /api/v1/fraud/flags [get]
+// @Summary Get flagged submissions
+// @Description Get the submissions with low-effort findings, riskiest first.
+// @Security BearerAuth
+// @Tags fraud
+// @Accept  json
+// @Produce  json
+// @Param user_id query string false "Transcriber ID"
+// @Param reason query string false "unchanged_ai, duplicate_neighbour, too_fast or repetitive_filler"
+// @Param days query int false "Submissions of the last days, 30 by default"
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.FraudFlagList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetFraudFlags(ctx *gin.Context) {
+	req := entity.FraudFlagReq{
+		UserId: ctx.Query("user_id"),
+		Reason: ctx.Query("reason"),
+	}
+
+	if _, ok := fraud.Weights[req.Reason]; req.Reason != "" && !ok {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Reason must be one of unchanged_ai, duplicate_neighbour, too_fast, repetitive_filler", http.StatusBadRequest)
+		return
+	}
+
+	var ok bool
+	req.Days, ok = h.parseFraudDays(ctx)
+	if !ok {
+		return
+	}
+
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+	req.Filter.Limit = limitValue
+	req.Filter.Offset = offsetValue
+
+	res, err := h.UseCase.FraudRepo.GetFlags(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error getting fraud flags") {
+		slog.Error("GetFraudFlags error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
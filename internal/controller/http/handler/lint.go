@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/lint"
+)
+
+// ValidateTranscript godoc
+// @Router /api/v1/transcript/validate [post]
+// @Summary Check a transcript against the guidelines
+// @Description Check a transcript text against the transcription guidelines as it is typed: event tags from the event tag catalog in any spelling it knows, balanced brackets, numbers written as words, one alphabet per word and spacing. Issues carry the character offsets [start, end) in transcribe_text. Errors block saving with PUT /transcript/update, warnings do not.
+// @Security BearerAuth
+// @Tags transcript
+// @Accept  json
+// @Produce  json
+// @Param text body entity.LintReq true "Transcript text"
+// @Success 200 {object} entity.LintResult
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 500 {object} entity.ErrorResponse
+func (h *Handler) ValidateTranscript(ctx *gin.Context) {
+	var body entity.LintReq
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		slog.Error("ValidateTranscript error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	catalog, err := h.UseCase.EventTagRepo.GetCatalog(ctx)
+	if h.HandleDbError(ctx, err, "Error getting event tags") {
+		slog.Error("ValidateTranscript error", slog.String("error", err.Error()))
+		return
+	}
+
+	issues, blocking, err := h.lintTranscript(body.TranscriptText, catalog)
+	if err != nil {
+		slog.Error("ValidateTranscript error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorInternalServer, "Invalid guideline rules", http.StatusInternalServerError)
+		return
+	}
+
+	if issues == nil {
+		issues = []entity.LintIssue{}
+	}
+	ctx.JSON(http.StatusOK, entity.LintResult{
+		Valid:  !blocking,
+		Issues: issues,
+	})
+}
+
+// lintTranscript checks text against the guideline rules of the config and the tags
+// of catalog. It also reports whether any issue blocks saving.
+func (h *Handler) lintTranscript(text string, catalog *eventtag.Catalog) ([]entity.LintIssue, bool, error) {
+	rules, err := lint.ParseRules(h.Config.Lint.Rules)
+	if err != nil {
+		return nil, false, err
+	}
+	linter, err := lint.New(lint.Config{Rules: rules, Tags: catalog.Names()})
+	if err != nil {
+		return nil, false, err
+	}
+
+	found := linter.Lint(text)
+	var issues []entity.LintIssue
+	for _, issue := range found {
+		issues = append(issues, entity.LintIssue(issue))
+	}
+	return issues, lint.Blocking(found), nil
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+// GetQualityOutliers godoc
+// @Router /api/v1/quality/outliers [get]
+// @Summary Get the quality outlier queue
+// @Description Get done transcripts whose chars or words per second are outliers for their language, or whose text is empty on a long segment. Open reviews are returned by default.
+// @Security BearerAuth
+// @Tags quality
+// @Accept  json
+// @Produce  json
+// @Param language query string false "uz or ru"
+// @Param reason query string false "empty_text, chars_per_sec_high, chars_per_sec_low, words_per_sec_high or words_per_sec_low"
+// @Param review query string false "open, confirmed, dismissed or all"
+// @Param user_id query string false "Transcriber ID"
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.QualityOutlierList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetQualityOutliers(ctx *gin.Context) {
+	req := entity.QualityOutlierReq{
+		Language: ctx.Query("language"),
+		Reason:   ctx.Query("reason"),
+		Review:   ctx.DefaultQuery("review", entity.QualityReviewOpen),
+		UserId:   ctx.Query("user_id"),
+	}
+
+	switch req.Review {
+	case entity.QualityReviewOpen, entity.QualityReviewConfirmed, entity.QualityReviewDismissed:
+	case "all":
+		req.Review = ""
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Review must be one of open, confirmed, dismissed, all", http.StatusBadRequest)
+		return
+	}
+
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+	req.Filter.Limit = limitValue
+	req.Filter.Offset = offsetValue
+
+	res, err := h.UseCase.QualityRepo.GetOutliers(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error getting quality outliers") {
+		slog.Error("GetQualityOutliers error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetQualityCheck godoc
+// @Router /api/v1/quality/check [get]
+// @Summary Get the last quality check
+// @Description Get the speaking-rate fences of every language and the number of flagged transcripts as of the last quality check.
+// @Security BearerAuth
+// @Tags quality
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.QualityCheckResult
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetQualityCheck(ctx *gin.Context) {
+	res, err := h.UseCase.QualityRepo.GetLastCheck(ctx)
+	if h.HandleDbError(ctx, err, "Error getting quality check") {
+		slog.Error("GetQualityCheck error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ReviewQualityOutlier godoc
+// @Router /api/v1/quality/{id}/review [put]
+// @Summary Review a quality outlier
+// @Description Confirm a flagged transcript or dismiss the flag. Dismissed transcripts are no longer held back from exports; an edit to the transcript reopens the review.
+// @Security BearerAuth
+// @Tags quality
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Transcript ID"
+// @Param body body entity.ReviewQualityOutlierBody true "Review"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ReviewQualityOutlier(ctx *gin.Context) {
+	var body entity.ReviewQualityOutlierBody
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid transcript ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("ReviewQualityOutlier error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if body.Review != entity.QualityReviewConfirmed && body.Review != entity.QualityReviewDismissed {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Review must be one of confirmed, dismissed", http.StatusBadRequest)
+		return
+	}
+
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		h.ReturnError(ctx, config.ErrorUnauthorized, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = h.UseCase.QualityRepo.Review(ctx, &entity.ReviewQualityOutlier{
+		TranscriptId: id,
+		Review:       body.Review,
+		ReviewerId:   claims.(jwt.MapClaims)["id"].(string),
+	})
+	if h.HandleDbError(ctx, err, "Error reviewing quality outlier") {
+		slog.Error("ReviewQualityOutlier error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Quality outlier reviewed successfully",
+	})
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+var workTimeRe = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// SetTranscriberLimit godoc
+// @Router /api/v1/transcriber_limit [put]
+// @Summary Set the limits of a user or a role
+// @Description Create or replace the daily segment quota, weekly audio minutes quota and working hours of a user or of every user with a role. Limits left empty are unlimited.
+// @Security BearerAuth
+// @Tags quota
+// @Accept  json
+// @Produce  json
+// @Param limit body entity.SetTranscriberLimit true "Limits"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) SetTranscriberLimit(ctx *gin.Context) {
+	var body entity.SetTranscriberLimit
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("SetTranscriberLimit error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (body.UserId == "") == (body.Role == "") {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Exactly one of user_id and role is required", http.StatusBadRequest)
+		return
+	}
+	if body.Role != "" && body.Role != "admin" && body.Role != "transcriber" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Role must be one of admin, transcriber", http.StatusBadRequest)
+		return
+	}
+	if (body.MaxSegmentsPerDay != nil && *body.MaxSegmentsPerDay < 0) || (body.MaxMinutesPerWeek != nil && *body.MaxMinutesPerWeek < 0) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Limits must not be negative", http.StatusBadRequest)
+		return
+	}
+	if (body.WorkStart == nil) != (body.WorkEnd == nil) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "work_start and work_end go together", http.StatusBadRequest)
+		return
+	}
+	if body.WorkStart != nil && (!workTimeRe.MatchString(*body.WorkStart) || !workTimeRe.MatchString(*body.WorkEnd)) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid working hours, expected HH:MM", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.UseCase.QuotaRepo.SetLimit(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error setting transcriber limit") {
+		slog.Error("SetTranscriberLimit error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Transcriber limit saved successfully",
+		"id":      id,
+	})
+}
+
+// GetTranscriberLimits godoc
+// @Router /api/v1/transcriber_limit/list [get]
+// @Summary Get the limits of users and roles
+// @Description Get the limits of users and roles
+// @Security BearerAuth
+// @Tags quota
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.TranscriberLimitList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetTranscriberLimits(ctx *gin.Context) {
+	res, err := h.UseCase.QuotaRepo.GetLimits(ctx)
+	if h.HandleDbError(ctx, err, "Error getting transcriber limits") {
+		slog.Error("GetTranscriberLimits error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// DeleteTranscriberLimit godoc
+// @Router /api/v1/transcriber_limit/{id} [delete]
+// @Summary Delete limits
+// @Description Delete the limits of a user or a role
+// @Security BearerAuth
+// @Tags quota
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Limit ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteTranscriberLimit(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid limit ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.QuotaRepo.DeleteLimit(ctx, id)
+	if h.HandleDbError(ctx, err, "Error deleting transcriber limit") {
+		slog.Error("DeleteTranscriberLimit error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Transcriber limit deleted successfully",
+	})
+}
+
+// quotaExceeded writes an error and returns true when the user may not take more
+// work right now.
+func (h *Handler) quotaExceeded(ctx *gin.Context, userId string) bool {
+	quota, err := h.UseCase.QuotaRepo.GetUserQuota(ctx, userId)
+	if h.HandleDbError(ctx, err, "Error checking quota") {
+		slog.Error("quotaExceeded error", slog.String("error", err.Error()))
+		return true
+	}
+
+	switch {
+	case !quota.WithinWorkingHours:
+		h.ReturnError(ctx, config.ErrorOutsideWorkingHours,
+			fmt.Sprintf("Working hours are %s-%s (%s)", *quota.WorkStart, *quota.WorkEnd, quota.Timezone), http.StatusForbidden)
+	case quota.RemainingSegmentsToday != nil && *quota.RemainingSegmentsToday == 0:
+		h.ReturnError(ctx, config.ErrorDailyQuotaExceeded,
+			fmt.Sprintf("Daily quota of %d segments reached", *quota.MaxSegmentsPerDay), http.StatusTooManyRequests)
+	case quota.RemainingMinutesWeek != nil && *quota.RemainingMinutesWeek == 0:
+		h.ReturnError(ctx, config.ErrorWeeklyQuotaExceeded,
+			fmt.Sprintf("Weekly quota of %g audio minutes reached", *quota.MaxMinutesPerWeek), http.StatusTooManyRequests)
+	default:
+		return false
+	}
+
+	slog.Warn("Quota exceeded", slog.String("user_id", userId))
+	return true
+}
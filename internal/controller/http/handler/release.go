@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+// CreateRelease godoc
+// @Router /api/v1/release [post]
+// @Summary Freeze a dataset release
+// @Description Freeze the segments matching the filter, done transcripts by default, into a named release. The release keeps its own copy of every segment's text, text hash and manifest fields and records statistics and the SHA-256 of its manifest, so it exports the same bytes however the transcripts change later.
+// @Security BearerAuth
+// @Tags release
+// @Accept  json
+// @Produce  json
+// @Param release body entity.CreateRelease true "Release object"
+// @Success 201 {object} entity.Release
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateRelease(ctx *gin.Context) {
+	var body entity.CreateRelease
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Name) == "" {
+		slog.Error("CreateRelease error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Filter.Status == "" {
+		body.Filter.Status = "done"
+	}
+	if msg := h.manifestReqError(&body.Filter); msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return
+	}
+
+	if claims, exists := ctx.Get("claims"); exists {
+		userId := claims.(jwt.MapClaims)["id"].(string)
+		body.CreatedBy = &userId
+	}
+
+	// Freezing a large release takes longer than a request is otherwise allowed.
+	id, err := h.UseCase.ReleaseRepo.CreateRelease(streamContext(ctx), &body)
+	if h.HandleDbError(ctx, err, "Error creating release") {
+		slog.Error("CreateRelease error", slog.String("error", err.Error()))
+		return
+	}
+
+	release, err := h.UseCase.ReleaseRepo.GetRelease(ctx, *id)
+	if h.HandleDbError(ctx, err, "Error getting release") {
+		slog.Error("CreateRelease error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Release created", slog.String("name", release.Name), slog.Int("segments", release.Segments))
+	ctx.JSON(http.StatusCreated, release)
+}
+
+// GetReleases godoc
+// @Router /api/v1/release/list [get]
+// @Summary Get a list of dataset releases
+// @Description Get the dataset releases, newest first, with their statistics
+// @Security BearerAuth
+// @Tags release
+// @Accept  json
+// @Produce  json
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.ReleaseList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetReleases(ctx *gin.Context) {
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+
+	releases, err := h.UseCase.ReleaseRepo.GetReleases(ctx, &entity.Filter{Limit: limitValue, Offset: offsetValue})
+	if h.HandleDbError(ctx, err, "Error getting releases") {
+		slog.Error("GetReleases error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, releases)
+}
+
+// GetRelease godoc
+// @Router /api/v1/release/{id} [get]
+// @Summary Get a dataset release
+// @Description Get a dataset release with its filter and statistics
+// @Security BearerAuth
+// @Tags release
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Release ID"
+// @Success 200 {object} entity.Release
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetRelease(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid release ID", http.StatusBadRequest)
+		return
+	}
+
+	release, err := h.UseCase.ReleaseRepo.GetRelease(ctx, id)
+	if h.HandleDbError(ctx, err, "Error getting release") {
+		slog.Error("GetRelease error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, release)
+}
+
+// ExportRelease godoc
+// @Router /api/v1/release/{id}/manifest [get]
+// @Summary Export a dataset release as a JSONL manifest
+// @Description Stream the frozen manifest of a release, in the format of the JSONL export. It is the same byte for byte every time; its SHA-256 is sent in the X-Manifest-Sha256 header.
+// @Security BearerAuth
+// @Tags release
+// @Produce  json
+// @Param id path int true "Release ID"
+// @Success 200 {object} entity.ManifestEntry
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) ExportRelease(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid release ID", http.StatusBadRequest)
+		return
+	}
+
+	release, err := h.UseCase.ReleaseRepo.GetRelease(ctx, id)
+	if h.HandleDbError(ctx, err, "Error getting release") {
+		slog.Error("ExportRelease error", slog.String("error", err.Error()))
+		return
+	}
+
+	streamCtx := streamContext(ctx)
+
+	w := bufio.NewWriter(ctx.Writer)
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="release_%d.jsonl"`, release.Id))
+	ctx.Header("X-Manifest-Sha256", release.ManifestSha256)
+	ctx.Status(http.StatusOK)
+
+	lines := 0
+	err = h.UseCase.ReleaseRepo.StreamReleaseManifest(streamCtx, release.Id, func(line string) error {
+		if _, err := w.WriteString(line); err != nil {
+			return err
+		}
+		lines++
+		if lines%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// The status is already sent; a cut manifest fails the checksum.
+		slog.Error("ExportRelease stopped", slog.Int("lines", lines), slog.String("error", err.Error()))
+		return
+	}
+	ctx.Writer.Flush()
+
+	slog.Info("Release exported", slog.String("name", release.Name), slog.Int("lines", lines))
+}
+
+// DiffReleases godoc
+// @Router /api/v1/release/diff [get]
+// @Summary Compare two dataset releases
+// @Description Count the segments added to, removed from and with changed text in release to compared with release from, and list them by audio and segment id
+// @Security BearerAuth
+// @Tags release
+// @Accept  json
+// @Produce  json
+// @Param from query int true "Older release ID"
+// @Param to query int true "Newer release ID"
+// @Param kind query string false "added, removed or changed"
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.ReleaseDiff
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) DiffReleases(ctx *gin.Context) {
+	from, errFrom := strconv.Atoi(ctx.Query("from"))
+	to, errTo := strconv.Atoi(ctx.Query("to"))
+	if errFrom != nil || errTo != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "from and to must be release IDs", http.StatusBadRequest)
+		return
+	}
+
+	req := entity.ReleaseDiffReq{From: from, To: to, Kind: ctx.Query("kind")}
+	switch req.Kind {
+	case "", entity.ReleaseAdded, entity.ReleaseRemoved, entity.ReleaseChanged:
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Kind must be one of added, removed, changed", http.StatusBadRequest)
+		return
+	}
+
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+	req.Filter.Limit = limitValue
+	req.Filter.Offset = offsetValue
+
+	diff, err := h.UseCase.ReleaseRepo.DiffReleases(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error comparing releases") {
+		slog.Error("DiffReleases error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, diff)
+}
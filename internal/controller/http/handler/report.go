@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/minio"
+)
+
+// GetReportReasons godoc
+// @Router /api/v1/report_reason/list [get]
+// @Summary Get the report reason catalog
+// @Description Get the report reason catalog. Inactive reasons are only listed with all=true.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param all query bool false "Include inactive reasons"
+// @Success 200 {object} entity.ReportReasonList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetReportReasons(ctx *gin.Context) {
+	all, _ := strconv.ParseBool(ctx.Query("all"))
+
+	res, err := h.UseCase.ReportRepo.GetReasons(ctx, !all)
+	if h.HandleDbError(ctx, err, "Error getting report reasons") {
+		slog.Error("GetReportReasons error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// CreateReportReason godoc
+// @Router /api/v1/report_reason [post]
+// @Summary Add a report reason
+// @Description Add a report reason to the catalog
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param reason body entity.CreateReportReason true "Report reason"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateReportReason(ctx *gin.Context) {
+	var body entity.CreateReportReason
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Code) == "" || body.Name.Uz == "" {
+		slog.Error("CreateReportReason error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.UseCase.ReportRepo.CreateReason(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error creating report reason") {
+		slog.Error("CreateReportReason error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Report reason created successfully", slog.String("code", body.Code))
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Report reason created successfully",
+		"id":      id,
+	})
+}
+
+// UpdateReportReason godoc
+// @Router /api/v1/report_reason/{id} [put]
+// @Summary Update a report reason
+// @Description Update the names, order or activity of a report reason
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Report reason ID"
+// @Param reason body entity.UpdateReportReason true "Report reason"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateReportReason(ctx *gin.Context) {
+	var body entity.UpdateReportReason
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid report reason ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("UpdateReportReason error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Name.Uz == "" && body.Name.Ru == "" && body.Name.Cy == "" && body.IsActive == nil && body.SortOrder == nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+	body.Id = id
+
+	err = h.UseCase.ReportRepo.UpdateReason(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error updating report reason") {
+		slog.Error("UpdateReportReason error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Report reason updated successfully",
+	})
+}
+
+// DeleteReportReason godoc
+// @Router /api/v1/report_reason/{id} [delete]
+// @Summary Delete a report reason
+// @Description Delete a report reason from the catalog
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Report reason ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteReportReason(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid report reason ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.ReportRepo.DeleteReason(ctx, id)
+	if h.HandleDbError(ctx, err, "Error deleting report reason") {
+		slog.Error("DeleteReportReason error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Report reason deleted successfully",
+	})
+}
+
+// GetReportQueue godoc
+// @Router /api/v1/report/queue [get]
+// @Summary Get the report resolution queue
+// @Description Get the open reports grouped by reason, oldest first. Offset and limit apply within each group.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param reason query string false "Only this reason"
+// @Param offset query number false "Offset for pagination"
+// @Param limit query number false "Limit for pagination"
+// @Success 200 {object} entity.ReportQueue
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetReportQueue(ctx *gin.Context) {
+	var req entity.ReportQueueReq
+
+	limitValue, offsetValue, err := parsePaginationParams(ctx, ctx.Query("limit"), ctx.Query("offset"))
+	if err != nil {
+		slog.Error("Error parsing pagination parameters: ", "err", err)
+		return
+	}
+
+	req.Reason = ctx.Query("reason")
+	req.Filter.Limit = limitValue
+	req.Filter.Offset = offsetValue
+
+	res, err := h.UseCase.ReportRepo.GetQueue(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error getting report queue") {
+		slog.Error("GetReportQueue error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ResolveReport godoc
+// @Router /api/v1/report/{id}/resolve [put]
+// @Summary Resolve a report
+// @Description Accept the report, reopen the segment for transcription or re-chunk it. Reopening can cover the whole audio file.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Chunk ID"
+// @Param body body entity.ResolveReportBody true "Resolution"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ResolveReport(ctx *gin.Context) {
+	var body entity.ResolveReportBody
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid chunk ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("ResolveReport error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch body.Action {
+	case entity.ReportActionAccept, entity.ReportActionReopen:
+	case entity.ReportActionRechunk:
+		if body.EntireAudio {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Re-chunking applies to a single segment", http.StatusBadRequest)
+			return
+		}
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Action must be one of accept, reopen, rechunk", http.StatusBadRequest)
+		return
+	}
+
+	var user_id string
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		h.ReturnError(ctx, config.ErrorUnauthorized, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user_id = claims.(jwt.MapClaims)["id"].(string)
+
+	req := entity.ResolveReport{
+		SegmentId:   id,
+		Action:      body.Action,
+		EntireAudio: body.EntireAudio,
+		Note:        body.Note,
+		UserID:      &user_id,
+	}
+	if body.Action == entity.ReportActionRechunk {
+		err = h.resolveRechunk(ctx, &req)
+	} else {
+		err = h.UseCase.ReportRepo.Resolve(ctx, &req)
+	}
+	if h.HandleDbError(ctx, err, "Error resolving report") {
+		slog.Error("ResolveReport error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Report resolved successfully", slog.Int("segment_id", id), slog.String("action", body.Action))
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Report resolved successfully",
+	})
+}
+
+// resolveRechunk claims the report, chunks the segment outside any transaction and
+// then adds the chunks and closes the report together. A failure hands the claim
+// back to the queue, so a retry starts from scratch.
+func (h *Handler) resolveRechunk(ctx *gin.Context, req *entity.ResolveReport) error {
+	err := h.UseCase.ReportRepo.ClaimRechunk(ctx, req.SegmentId, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := h.rechunkSegment(ctx, req.SegmentId)
+	if err == nil {
+		err = h.UseCase.ReportRepo.FinishRechunk(ctx, req, chunks)
+	}
+	if err != nil {
+		if rerr := h.UseCase.ReportRepo.ReleaseRechunk(context.Background(), req.SegmentId, req.UserID); rerr != nil {
+			slog.Error("Error releasing re-chunk claim", slog.Int("segment_id", req.SegmentId), slog.String("error", rerr.Error()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// rechunkSegment downloads the segment audio and runs it through the VAD chunker
+// again, returning the new chunks of the same audio file.
+func (h *Handler) rechunkSegment(ctx *gin.Context, id int) ([]entity.CreateAudioSegment, error) {
+	segment, err := h.UseCase.AudioSegmentRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := minio.ObjectName(segment.FilePath)
+	tempPath := filepath.Join(os.TempDir(), name)
+	if err := h.MinIO.Download(*h.Config, segment.FilePath, tempPath); err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempPath)
+
+	return h.chunkAudio(ctx, segment.AudioId, tempPath)
+}
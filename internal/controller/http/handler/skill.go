@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+// GetSkills godoc
+// @Router /api/v1/skill/list [get]
+// @Summary Get the skill catalog
+// @Description Get the languages, dialects and domains transcribers can be qualified for
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param kind query string false "language, dialect or domain"
+// @Success 200 {object} entity.SkillList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetSkills(ctx *gin.Context) {
+	res, err := h.UseCase.SkillRepo.GetList(ctx, ctx.Query("kind"))
+	if h.HandleDbError(ctx, err, "Error getting skills") {
+		slog.Error("GetSkills error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// CreateSkill godoc
+// @Router /api/v1/skill [post]
+// @Summary Add a skill
+// @Description Add a skill to the catalog
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param skill body entity.CreateSkill true "Skill"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CreateSkill(ctx *gin.Context) {
+	var body entity.CreateSkill
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || strings.TrimSpace(body.Code) == "" || body.Name == "" {
+		slog.Error("CreateSkill error", slog.Any("error", err))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !isSkillKind(body.Kind) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Kind must be one of language, dialect, domain", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.UseCase.SkillRepo.Create(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error creating skill") {
+		slog.Error("CreateSkill error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Skill created successfully", slog.String("code", body.Code))
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Skill created successfully",
+		"id":      id,
+	})
+}
+
+// UpdateSkill godoc
+// @Router /api/v1/skill/{id} [put]
+// @Summary Update a skill
+// @Description Update the name or kind of a skill
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Skill ID"
+// @Param skill body entity.UpdateSkill true "Skill"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) UpdateSkill(ctx *gin.Context) {
+	var body entity.UpdateSkill
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid skill ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("UpdateSkill error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body.Id = id
+
+	if body.Name == "" && body.Kind == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	if body.Kind != "" && !isSkillKind(body.Kind) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Kind must be one of language, dialect, domain", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.SkillRepo.Update(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error updating skill") {
+		slog.Error("UpdateSkill error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Skill updated successfully",
+	})
+}
+
+// DeleteSkill godoc
+// @Router /api/v1/skill/{id} [delete]
+// @Summary Delete a skill
+// @Description Delete a skill from the catalog. Audio files requiring it become available to everyone.
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Skill ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteSkill(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid skill ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.SkillRepo.Delete(ctx, id)
+	if h.HandleDbError(ctx, err, "Error deleting skill") {
+		slog.Error("DeleteSkill error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Skill deleted successfully",
+	})
+}
+
+// GetUserSkills godoc
+// @Router /api/v1/user/{id}/skills [get]
+// @Summary Get the skills of a user
+// @Description Get the skills of a user
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.SkillList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetUserSkills(ctx *gin.Context) {
+	res, err := h.UseCase.SkillRepo.GetUserSkills(ctx, ctx.Param("id"))
+	if h.HandleDbError(ctx, err, "Error getting user skills") {
+		slog.Error("GetUserSkills error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// SetUserSkills godoc
+// @Router /api/v1/user/{id}/skills [put]
+// @Summary Set the skills of a user
+// @Description Replace the skills of a user. The user only gets audio files whose required skills they all have.
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param skills body entity.SetSkills true "Skill codes"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) SetUserSkills(ctx *gin.Context) {
+	var body entity.SetSkills
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("SetUserSkills error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	code, err := h.unknownSkillCode(ctx, body.Codes)
+	if h.HandleDbError(ctx, err, "Error getting skills") {
+		slog.Error("SetUserSkills error", slog.String("error", err.Error()))
+		return
+	}
+	if code != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unknown skill: "+code, http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.SkillRepo.SetUserSkills(ctx, ctx.Param("id"), body.Codes)
+	if h.HandleDbError(ctx, err, "Error setting user skills") {
+		slog.Error("SetUserSkills error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "User skills updated successfully",
+	})
+}
+
+// GetAudioFileSkills godoc
+// @Router /api/v1/audio_file/{id}/skills [get]
+// @Summary Get the required skills of an audio file
+// @Description Get the required skills of an audio file
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Audio ID"
+// @Success 200 {object} entity.SkillList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetAudioFileSkills(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.UseCase.SkillRepo.GetAudioSkills(ctx, id)
+	if h.HandleDbError(ctx, err, "Error getting audio file skills") {
+		slog.Error("GetAudioFileSkills error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// SetAudioFileSkills godoc
+// @Router /api/v1/audio_file/{id}/skills [put]
+// @Summary Set the required skills of an audio file
+// @Description Replace the skills a transcriber needs to be given the audio file
+// @Security BearerAuth
+// @Tags skill
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Audio ID"
+// @Param skills body entity.SetSkills true "Skill codes"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) SetAudioFileSkills(ctx *gin.Context) {
+	var body entity.SetSkills
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid audio ID", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		slog.Error("SetAudioFileSkills error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	code, err := h.unknownSkillCode(ctx, body.Codes)
+	if h.HandleDbError(ctx, err, "Error getting skills") {
+		slog.Error("SetAudioFileSkills error", slog.String("error", err.Error()))
+		return
+	}
+	if code != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unknown skill: "+code, http.StatusBadRequest)
+		return
+	}
+
+	err = h.UseCase.SkillRepo.SetAudioSkills(ctx, id, body.Codes)
+	if h.HandleDbError(ctx, err, "Error setting audio file skills") {
+		slog.Error("SetAudioFileSkills error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Audio file skills updated successfully",
+	})
+}
+
+func isSkillKind(kind string) bool {
+	return kind == entity.SkillKindLanguage || kind == entity.SkillKindDialect || kind == entity.SkillKindDomain
+}
+
+// parseSkillCodes splits a comma separated list of skill codes.
+func parseSkillCodes(value string) []string {
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// unknownSkillCode returns the first of codes missing from the skill catalog, or ""
+// when all of them are known.
+func (h *Handler) unknownSkillCode(ctx *gin.Context, codes []string) (string, error) {
+	if len(codes) == 0 {
+		return "", nil
+	}
+
+	catalog, err := h.UseCase.SkillRepo.GetList(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	known := make(map[string]bool, len(catalog.Skills))
+	for _, skill := range catalog.Skills {
+		known[skill.Code] = true
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return code, nil
+		}
+	}
+
+	return "", nil
+}
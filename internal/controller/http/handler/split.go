@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/split"
+)
+
+// AssignSplits godoc
+// @Router /api/v1/split/assign [post]
+// @Summary Assign audio files to dataset splits
+// @Description Put audio files into splits such as train, dev and test by a hash of their id and a seed, or, when stratified by call duration or most common emotion, so that every stratum follows the ratios on its own. Splits are per audio file, so segments of one call or speaker never end up in two splits. Files without done transcripts are left until they have some. Files keep their split unless reassign is set; new files are also assigned in the background with the configured settings. Empty fields fall back to the config.
+// @Security BearerAuth
+// @Tags split
+// @Accept  json
+// @Produce  json
+// @Param split body entity.SplitAssignReq true "Split settings"
+// @Success 200 {object} entity.SplitAssignResult
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) AssignSplits(ctx *gin.Context) {
+	var body entity.SplitAssignReq
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		slog.Error("AssignSplits error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch body.Stratify {
+	case "", entity.StratifyNone, entity.StratifyDuration, entity.StratifyEmotion:
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "Stratify must be one of none, duration, emotion", http.StatusBadRequest)
+		return
+	}
+	if len(body.Ratios) > 0 {
+		ratios := make([]split.Ratio, len(body.Ratios))
+		for i, ratio := range body.Ratios {
+			ratios[i] = split.Ratio{Name: ratio.Name, Weight: ratio.Ratio}
+		}
+		if err := split.Validate(ratios); err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid ratios: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	res, err := h.UseCase.SplitRepo.AssignSplits(ctx, &body)
+	if h.HandleDbError(ctx, err, "Error assigning splits") {
+		slog.Error("AssignSplits error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Splits assigned", slog.Int("assigned", res.Assigned), slog.Int("kept", res.Kept))
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetSplitStats godoc
+// @Router /api/v1/split/stats [get]
+// @Summary Get dataset split statistics
+// @Description Get the audio files, done segments and hours of every split, and the number of audio files without a split yet
+// @Security BearerAuth
+// @Tags split
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.SplitStatsList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetSplitStats(ctx *gin.Context) {
+	stats, err := h.UseCase.SplitRepo.GetSplitStats(ctx)
+	if h.HandleDbError(ctx, err, "Error getting split stats") {
+		slog.Error("GetSplitStats error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
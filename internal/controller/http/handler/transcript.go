@@ -1,14 +1,19 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
+	"github.com/jackc/pgx/v4"
 	"github.com/mirjalilova/voice_transcribe/config"
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/langspan"
 )
 
 // GetTranscript godoc
@@ -41,6 +46,7 @@ func (h *Handler) GetTranscript(ctx *gin.Context) {
 	}
 
 	slog.Info("Transcript retrieved successfully")
+	ctx.Header("ETag", transcriptETag(transcript.Version))
 	ctx.JSON(200, transcript)
 }
 
@@ -95,15 +101,21 @@ func (h *Handler) GetTranscripts(ctx *gin.Context) {
 // UpdateTranscript godoc
 // @Router /api/v1/transcript/update [put]
 // @Summary Update a transcript
-// @Description Update a transcript
+// @Description Update a transcript. Passages in other languages than the segment go into language_spans; when they are left out, passages typed as (ru: ...) into transcribe_text are taken out into spans. The text is checked against the guidelines unless the segment is reported: errors reject the save with 422 GUIDELINE_VIOLATION, warnings come back with the saved transcript. Event tags are saved in their catalog spelling.
 // @Security BearerAuth
 // @Tags transcript
 // @Accept  json
 // @Produce  json
 // @Param id query int true "Chunk ID"
+// @Param If-Match header string false "ETag returned by GET /transcript/{id}, required unless version is sent in the body"
 // @Param transcript body entity.UpdateTranscriptBody true "Transcript object"
-// @Success 200 {object} entity.SuccessResponse
+// @Success 200 {object} entity.UpdateTranscriptResponse
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 403 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.TranscriptConflictResponse
+// @Failure 422 {object} entity.TranscriptLintResponse
+// @Failure 428 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
 func (h *Handler) UpdateTranscript(ctx *gin.Context) {
 	var (
 		body entity.UpdateTranscriptBody
@@ -129,6 +141,64 @@ func (h *Handler) UpdateTranscript(ctx *gin.Context) {
 		return
 	}
 
+	if body.ReportReason != "" {
+		active, err := h.UseCase.ReportRepo.IsActiveReason(ctx, body.ReportReason)
+		if h.HandleDbError(ctx, err, "Error checking report reason") {
+			return
+		}
+		if !active {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Unknown report reason", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var catalog *eventtag.Catalog
+	hasText := body.TranscriptText != "" && body.TranscriptText != "string"
+	if hasText {
+		catalog, err = h.UseCase.EventTagRepo.GetCatalog(ctx)
+		if h.HandleDbError(ctx, err, "Error getting event tags") {
+			slog.Error("UpdateTranscript error", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	// Guidelines apply to the text as typed, so offsets point into the editor. A
+	// reported segment is saved as it is.
+	var warnings []entity.LintIssue
+	reported := body.ReportReason != "" || (body.ReportText != "" && body.ReportText != "string")
+	if hasText && !reported {
+		issues, blocking, err := h.lintTranscript(body.TranscriptText, catalog)
+		if err != nil {
+			slog.Error("UpdateTranscript error", slog.String("error", err.Error()))
+			h.ReturnError(ctx, config.ErrorInternalServer, "Invalid guideline rules", http.StatusInternalServerError)
+			return
+		}
+		if blocking {
+			ctx.JSON(http.StatusUnprocessableEntity, entity.TranscriptLintResponse{
+				Message: "Transcript breaks the transcription guidelines",
+				Code:    config.ErrorGuidelineViolation,
+				Issues:  issues,
+			})
+			return
+		}
+		warnings = issues
+	}
+
+	language, spans, msg := h.transcriptLanguages(&body)
+	if msg != "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, msg, http.StatusBadRequest)
+		return
+	}
+	if hasText {
+		body.TranscriptText, spans = normalizeTags(catalog, body.TranscriptText, language, spans)
+	}
+
+	version, ok := requestVersion(ctx, body.Version)
+	if !ok {
+		h.ReturnError(ctx, config.ErrorPreconditionRequired, "Transcript version is required, send the ETag as If-Match or the version in the body", http.StatusPreconditionRequired)
+		return
+	}
+
 	var user_id string
 	claims, exists := ctx.Get("claims")
 	if !exists {
@@ -142,6 +212,10 @@ func (h *Handler) UpdateTranscript(ctx *gin.Context) {
 		user_id = claims.(jwt.MapClaims)["id"].(string)
 	}
 
+	if h.quotaExceeded(ctx, user_id) {
+		return
+	}
+
 	err = h.UseCase.TranscriptRepo.Update(ctx, &entity.UpdateTranscript{
 		Id:                 intId,
 		TranscriptText:     body.TranscriptText,
@@ -149,7 +223,19 @@ func (h *Handler) UpdateTranscript(ctx *gin.Context) {
 		UserID:             &user_id,
 		EntireAudioInvalid: body.EntireAudioInvalid,
 		Emotion:            body.Emotion,
+		Version:            version,
+		ReportReason:       body.ReportReason,
+		Language:           language,
+		LanguageSpans:      spans,
 	})
+	if errors.Is(err, entity.ErrTranscriptVersionConflict) {
+		h.transcriptConflict(ctx, intId)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.HandleDbError(ctx, err, "Error updating transcript")
+		return
+	}
 	if err != nil {
 		slog.Error("UpdateTranscript error", slog.String("error", err.Error()))
 		ctx.JSON(400, entity.ErrorResponse{
@@ -159,9 +245,15 @@ func (h *Handler) UpdateTranscript(ctx *gin.Context) {
 		return
 	}
 
+	// A failed check is caught up by the batch check.
+	if err := h.UseCase.FraudRepo.Check(ctx, []int{intId}); err != nil {
+		slog.Error("Error checking transcript for fraud", slog.String("error", err.Error()))
+	}
+
 	slog.Info("Transcript updated successfully")
-	ctx.JSON(200, gin.H{
-		"message": "Transcript updated successfully",
+	ctx.JSON(200, entity.UpdateTranscriptResponse{
+		Message: "Transcript updated successfully",
+		Issues:  warnings,
 	})
 }
 
@@ -274,3 +366,286 @@ func (h *Handler) StartTranscripts(ctx *gin.Context) {
 		"message": "Transcript started successfully",
 	})
 }
+
+// TranscriptHeartbeat godoc
+// @Router /api/v1/transcript/heartbeat [put]
+// @Summary Report editor activity on a segment
+// @Description The editor calls this every few seconds while audio plays or the user types. Time between heartbeats counts as active unless the session was idle for longer than the idle timeout.
+// @Security BearerAuth
+// @Tags transcript
+// @Accept  json
+// @Produce  json
+// @Param id query int true "Chunk ID"
+// @Param body body entity.HeartbeatBody false "Heartbeat"
+// @Success 200 {object} entity.HeartbeatResult
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 403 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) TranscriptHeartbeat(ctx *gin.Context) {
+	var body entity.HeartbeatBody
+
+	intId, err := strconv.Atoi(ctx.Query("id"))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid chunk ID", http.StatusBadRequest)
+		return
+	}
+
+	if ctx.Request.ContentLength > 0 {
+		err = ctx.ShouldBindJSON(&body)
+		if err != nil {
+			slog.Error("TranscriptHeartbeat error", slog.String("error", err.Error()))
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		h.ReturnError(ctx, config.ErrorUnauthorized, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := h.UseCase.TranscriptRepo.Heartbeat(ctx, &entity.Heartbeat{
+		SegmentId: intId,
+		UserId:    claims.(jwt.MapClaims)["id"].(string),
+		End:       body.End,
+	})
+	if errors.Is(err, entity.ErrSegmentNotAssigned) {
+		h.ReturnError(ctx, config.ErrorForbidden, "Segment is not assigned to you", http.StatusForbidden)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error recording heartbeat") {
+		slog.Error("TranscriptHeartbeat error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetTranscriptHistory godoc
+// @Router /api/v1/transcript/{id}/history [get]
+// @Summary Get the revision history of a transcript
+// @Description Get every saved revision of a transcript together with its original ai_text
+// @Security BearerAuth
+// @Tags transcript
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Chunk ID"
+// @Success 200 {object} entity.TranscriptHistory
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetTranscriptHistory(ctx *gin.Context) {
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("GetTranscriptHistory error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid transcript ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.UseCase.TranscriptRepo.GetHistory(ctx, intId)
+	if h.HandleDbError(ctx, err, "Error getting transcript history") {
+		slog.Error("GetTranscriptHistory error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// GetTranscriptDiff godoc
+// @Router /api/v1/transcript/{id}/diff [get]
+// @Summary Get a word-level diff between two revisions
+// @Description Revision 0 is the original ai_text. When "to" is omitted the latest revision is used.
+// @Security BearerAuth
+// @Tags transcript
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Chunk ID"
+// @Param from query int false "From revision ID, 0 for ai_text"
+// @Param to query int false "To revision ID, latest by default"
+// @Success 200 {object} entity.TranscriptDiff
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetTranscriptDiff(ctx *gin.Context) {
+	req := entity.TranscriptDiffReq{}
+
+	var err error
+	req.SegmentId, err = strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("GetTranscriptDiff error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid transcript ID", http.StatusBadRequest)
+		return
+	}
+
+	if from := ctx.Query("from"); from != "" {
+		req.FromRevision, err = strconv.Atoi(from)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid from revision", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if to := ctx.Query("to"); to != "" {
+		toRevision, err := strconv.Atoi(to)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid to revision", http.StatusBadRequest)
+			return
+		}
+		req.ToRevision = &toRevision
+	}
+
+	res, err := h.UseCase.TranscriptRepo.Diff(ctx, &req)
+	if h.HandleDbError(ctx, err, "Error getting transcript diff") {
+		slog.Error("GetTranscriptDiff error", slog.String("error", err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// RevertTranscript godoc
+// @Router /api/v1/transcript/{id}/revert [put]
+// @Summary Revert a transcript to an earlier revision
+// @Description Revision 0 restores the original ai_text. "(ru: ...)" passages of revisions from before language spans become spans. The revert itself is kept as a new revision.
+// @Security BearerAuth
+// @Tags transcript
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Chunk ID"
+// @Param revision_id query int true "Revision ID, 0 for ai_text"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) RevertTranscript(ctx *gin.Context) {
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		slog.Error("RevertTranscript error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid transcript ID", http.StatusBadRequest)
+		return
+	}
+
+	revisionId, err := strconv.Atoi(ctx.Query("revision_id"))
+	if err != nil {
+		slog.Error("RevertTranscript error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	var user_id string
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		h.ReturnError(ctx, config.ErrorUnauthorized, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user_id = claims.(jwt.MapClaims)["id"].(string)
+
+	err = h.UseCase.TranscriptRepo.Revert(ctx, &entity.RevertTranscript{
+		SegmentId:  intId,
+		RevisionId: revisionId,
+		UserID:     &user_id,
+		Languages:  h.languageCodes(),
+	})
+	if errors.Is(err, entity.ErrRevertLanguages) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Cannot revert: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error reverting transcript") {
+		slog.Error("RevertTranscript error", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("Transcript reverted successfully", slog.Int("segment_id", intId), slog.Int("revision_id", revisionId))
+	ctx.JSON(http.StatusOK, entity.SuccessResponse{
+		Message: "Transcript reverted successfully",
+	})
+}
+
+// transcriptETag formats a transcript version as a strong ETag.
+func transcriptETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// requestVersion returns the transcript version an update is based on, taken from
+// the body or, failing that, from the If-Match header.
+func requestVersion(ctx *gin.Context, bodyVersion *int) (int, bool) {
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+
+	ifMatch := strings.TrimPrefix(strings.TrimSpace(ctx.GetHeader("If-Match")), "W/")
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// languageCodes returns the configured transcript languages, the default one first.
+func (h *Handler) languageCodes() []string {
+	var codes []string
+	for _, code := range strings.Split(h.Config.Language.Codes, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		codes = []string{"uz"}
+	}
+	return codes
+}
+
+// transcriptLanguages returns the primary language and language spans of an edit.
+// Without spans in the body, "(ru: ...)" passages typed into the text are taken out
+// into spans and body.TranscriptText is left plain. msg says what is wrong, if
+// anything.
+func (h *Handler) transcriptLanguages(body *entity.UpdateTranscriptBody) (string, []entity.LanguageSpan, string) {
+	text := body.TranscriptText
+	if text == "" || text == "string" {
+		if body.Language != "" || body.LanguageSpans != nil {
+			return "", nil, "language and language_spans are saved with transcribe_text, which is missing"
+		}
+		return "", nil, ""
+	}
+
+	codes := h.languageCodes()
+	language := body.Language
+	if language == "" {
+		language = codes[0]
+	}
+
+	var spans []langspan.Span
+	if body.LanguageSpans == nil {
+		text, language, spans = langspan.Parse(text, language)
+		body.TranscriptText = text
+	} else {
+		if langspan.HasInline(text) {
+			return "", nil, "transcribe_text has (ru: ...) passages, send them as language_spans instead"
+		}
+		for _, s := range *body.LanguageSpans {
+			spans = append(spans, langspan.Span(s))
+		}
+	}
+
+	if err := langspan.Validate(text, language, spans, codes); err != nil {
+		return "", nil, "Invalid languages: " + err.Error()
+	}
+
+	res := make([]entity.LanguageSpan, len(spans))
+	for i, s := range spans {
+		res[i] = entity.LanguageSpan(s)
+	}
+	return language, res, ""
+}
+
+// transcriptConflict answers a stale update with 409 and the current server state.
+func (h *Handler) transcriptConflict(ctx *gin.Context, id int) {
+	current, err := h.UseCase.TranscriptRepo.GetById(ctx, id)
+	if h.HandleDbError(ctx, err, "Error getting transcript") {
+		return
+	}
+
+	slog.Warn("Transcript update conflict", slog.Int("segment_id", id), slog.Int("version", current.Version))
+	ctx.Header("ETag", transcriptETag(current.Version))
+	ctx.JSON(http.StatusConflict, entity.TranscriptConflictResponse{
+		Message: "Transcript was changed by someone else",
+		Code:    config.ErrorConflict,
+		Current: *current,
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/translit"
+)
+
+// Transliterate godoc
+// @Router /api/v1/translit [post]
+// @Summary Transliterate Uzbek text
+// @Description Write Uzbek text in the Latin or the Cyrillic alphabet by the official rules, and tell which alphabet it was written in. Letters already in the target alphabet are kept, so mixed text comes out in one alphabet. Event tags such as [noise] and language tags such as (ru: ...) are left as they are.
+// @Security BearerAuth
+// @Tags translit
+// @Accept  json
+// @Produce  json
+// @Param translit body entity.TranslitReq true "Text and target script"
+// @Success 200 {object} entity.TranslitRes
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) Transliterate(ctx *gin.Context) {
+	var body entity.TranslitReq
+
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		slog.Error("Transliterate error", slog.String("error", err.Error()))
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	script := translit.Script(body.Script)
+	if script != translit.Latin && script != translit.Cyrillic {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Script must be one of latin, cyrillic", http.StatusBadRequest)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entity.TranslitRes{
+		Text:         translit.Convert(body.Text, script),
+		SourceScript: string(translit.Detect(body.Text)),
+	})
+}
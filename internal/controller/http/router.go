@@ -53,8 +53,8 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 	engine.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // Frontend domenini yozish
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Authentication"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Authentication", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 	}))
 
@@ -102,9 +102,14 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 		router.GET("/transcript/list", middleware.NewAuth(enforcer), handlerV1.GetTranscripts)
 		router.GET("/transcript/:id", middleware.NewAuth(enforcer), handlerV1.GetTranscript)
 		router.PUT("/transcript/update", middleware.NewAuth(enforcer), handlerV1.UpdateTranscript)
+		router.POST("/transcript/validate", middleware.NewAuth(enforcer), handlerV1.ValidateTranscript)
 		// router.PUT("/transcript/update/status", handlerV1.UpdateStatus)
 		router.DELETE("/transcript/delete", middleware.NewAuth(enforcer), handlerV1.DeleteTranscript)
 		router.PUT("/transcript/start", middleware.NewAuth(enforcer), handlerV1.StartTranscripts)
+		router.PUT("/transcript/heartbeat", middleware.NewAuth(enforcer), handlerV1.TranscriptHeartbeat)
+		router.GET("/transcript/:id/history", middleware.NewAuth(enforcer), handlerV1.GetTranscriptHistory)
+		router.GET("/transcript/:id/diff", middleware.NewAuth(enforcer), handlerV1.GetTranscriptDiff)
+		router.PUT("/transcript/:id/revert", middleware.NewAuth(enforcer), handlerV1.RevertTranscript)
 
 		// audio_segment
 		router.GET("/audio_segment", middleware.NewAuth(enforcer), handlerV1.GetAudioSegments)
@@ -116,11 +121,83 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 		router.GET("/dashboard/user/:user_id", middleware.NewAuth(enforcer), handlerV1.GetUserTranscriptStatictics)
 		router.GET("/dataset_viewer", middleware.NewAuth(enforcer), handlerV1.DatasetViewer)
 		router.GET("/statistic", middleware.NewAuth(enforcer), handlerV1.GetStatistic)
+		router.GET("/statistic/histogram", middleware.NewAuth(enforcer), handlerV1.GetHistogram)
 		router.GET("/dashboard/stats", middleware.NewAuth(enforcer), handlerV1.GetAudioTranscriptStats)
 		router.GET("/dashboard/hours", middleware.NewAuth(enforcer), handlerV1.GetHourlyTranscripts)
+		router.GET("/dashboard/deadlines", middleware.NewAuth(enforcer), handlerV1.GetDeadlineRisks)
 
 		// audio
 		router.POST("/upload-zip-audio", middleware.NewAuth(enforcer), handlerV1.UploadZipAndExtractAudio)
 		router.GET("/audio_file/:id", middleware.NewAuth(enforcer), handlerV1.GetAudioFile)
+		router.PUT("/audio_file/:id/reopen", middleware.NewAuth(enforcer), handlerV1.ReopenAudioFile)
+		router.PUT("/audio_file/reassign", middleware.NewAuth(enforcer), handlerV1.ReassignAudioFiles)
+		router.GET("/audio_file/:id/events", middleware.NewAuth(enforcer), handlerV1.GetAudioFileEvents)
+		router.GET("/audio_file/:id/skills", middleware.NewAuth(enforcer), handlerV1.GetAudioFileSkills)
+		router.PUT("/audio_file/:id/skills", middleware.NewAuth(enforcer), handlerV1.SetAudioFileSkills)
+		router.PUT("/audio_file/:id/priority", middleware.NewAuth(enforcer), handlerV1.UpdateAudioFilePriority)
+
+		// skill
+		router.GET("/skill/list", middleware.NewAuth(enforcer), handlerV1.GetSkills)
+		router.POST("/skill", middleware.NewAuth(enforcer), handlerV1.CreateSkill)
+		router.PUT("/skill/:id", middleware.NewAuth(enforcer), handlerV1.UpdateSkill)
+		router.DELETE("/skill/:id", middleware.NewAuth(enforcer), handlerV1.DeleteSkill)
+		router.GET("/user/:id/skills", middleware.NewAuth(enforcer), handlerV1.GetUserSkills)
+		router.PUT("/user/:id/skills", middleware.NewAuth(enforcer), handlerV1.SetUserSkills)
+
+		// quota
+		router.PUT("/transcriber_limit", middleware.NewAuth(enforcer), handlerV1.SetTranscriberLimit)
+		router.GET("/transcriber_limit/list", middleware.NewAuth(enforcer), handlerV1.GetTranscriberLimits)
+		router.DELETE("/transcriber_limit/:id", middleware.NewAuth(enforcer), handlerV1.DeleteTranscriberLimit)
+
+		// report
+		router.GET("/report_reason/list", middleware.NewAuth(enforcer), handlerV1.GetReportReasons)
+		router.POST("/report_reason", middleware.NewAuth(enforcer), handlerV1.CreateReportReason)
+		router.PUT("/report_reason/:id", middleware.NewAuth(enforcer), handlerV1.UpdateReportReason)
+		router.DELETE("/report_reason/:id", middleware.NewAuth(enforcer), handlerV1.DeleteReportReason)
+		router.GET("/report/queue", middleware.NewAuth(enforcer), handlerV1.GetReportQueue)
+		router.PUT("/report/:id/resolve", middleware.NewAuth(enforcer), handlerV1.ResolveReport)
+
+		// quality
+		router.GET("/quality/outliers", middleware.NewAuth(enforcer), handlerV1.GetQualityOutliers)
+		router.GET("/quality/check", middleware.NewAuth(enforcer), handlerV1.GetQualityCheck)
+		router.PUT("/quality/:id/review", middleware.NewAuth(enforcer), handlerV1.ReviewQualityOutlier)
+
+		// fraud
+		router.GET("/fraud/users", middleware.NewAuth(enforcer), handlerV1.GetUserRisks)
+		router.GET("/fraud/flags", middleware.NewAuth(enforcer), handlerV1.GetFraudFlags)
+
+		// export
+		router.GET("/export/manifest", middleware.NewAuth(enforcer), handlerV1.ExportManifest)
+		router.GET("/export/kaldi", middleware.NewAuth(enforcer), handlerV1.ExportKaldi)
+		router.POST("/export/bundles", middleware.NewAuth(enforcer), handlerV1.CreateExportBundle)
+		router.GET("/export/bundles/list", middleware.NewAuth(enforcer), handlerV1.GetExportBundles)
+		router.GET("/export/bundles/:id", middleware.NewAuth(enforcer), handlerV1.GetExportBundle)
+
+		// split
+		router.POST("/split/assign", middleware.NewAuth(enforcer), handlerV1.AssignSplits)
+		router.GET("/split/stats", middleware.NewAuth(enforcer), handlerV1.GetSplitStats)
+
+		// release
+		router.POST("/release", middleware.NewAuth(enforcer), handlerV1.CreateRelease)
+		router.GET("/release/list", middleware.NewAuth(enforcer), handlerV1.GetReleases)
+		router.GET("/release/diff", middleware.NewAuth(enforcer), handlerV1.DiffReleases)
+		router.GET("/release/:id", middleware.NewAuth(enforcer), handlerV1.GetRelease)
+		router.GET("/release/:id/manifest", middleware.NewAuth(enforcer), handlerV1.ExportRelease)
+
+		// event tag
+		router.GET("/event_tag/list", middleware.NewAuth(enforcer), handlerV1.GetEventTags)
+		router.GET("/event_tag/stats", middleware.NewAuth(enforcer), handlerV1.GetEventTagStats)
+		router.POST("/event_tag", middleware.NewAuth(enforcer), handlerV1.CreateEventTag)
+		router.PUT("/event_tag/:id", middleware.NewAuth(enforcer), handlerV1.UpdateEventTag)
+		router.DELETE("/event_tag/:id", middleware.NewAuth(enforcer), handlerV1.DeleteEventTag)
+
+		// translit
+		router.POST("/translit", middleware.NewAuth(enforcer), handlerV1.Transliterate)
+
+		// benchmark
+		router.POST("/benchmark/model", middleware.NewAuth(enforcer), handlerV1.CreateAsrModel)
+		router.GET("/benchmark/model/list", middleware.NewAuth(enforcer), handlerV1.GetAsrModels)
+		router.POST("/benchmark/model/:id/hypotheses", middleware.NewAuth(enforcer), handlerV1.UploadAsrHypotheses)
+		router.GET("/benchmark/leaderboard", middleware.NewAuth(enforcer), handlerV1.GetAsrLeaderboard)
 	}
 }
@@ -1,13 +1,127 @@
 package entity
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrSegmentsNotInFile is returned when segments picked for re-opening are not
+// segments of the audio file.
+var ErrSegmentsNotInFile = errors.New("segments do not belong to the audio file")
+
 type CreateAudioFile struct {
-	Filename string `json:"filename"`
-	FilePath string `json:"file_path"`
+	Filename string     `json:"filename"`
+	FilePath string     `json:"file_path"`
+	Priority int        `json:"priority"`
+	Deadline *time.Time `json:"deadline"`
 }
 
 type AudioFile struct {
-	ID       int    `json:"id"`
-	Filename string `json:"filename"`
+	ID       int     `json:"id"`
+	Filename string  `json:"filename"`
+	Status   string  `json:"status"`
+	UserID   string  `json:"user_id"`
+	Priority int     `json:"priority"`
+	Deadline *string `json:"deadline"`
+}
+
+type UpdateAudioFilePriorityBody struct {
+	Priority *int `json:"priority"`
+	// Deadline as "2006-01-02 15:04:05", or a date meaning the end of that day, in the
+	// work timezone. An empty string clears it.
+	Deadline *string `json:"deadline" example:"2025-08-20 18:00:00"`
+}
+
+type UpdateAudioFilePriority struct {
+	AudioId       int        `json:"audio_id"`
+	Priority      *int       `json:"priority"`
+	Deadline      *time.Time `json:"deadline"`
+	ClearDeadline bool       `json:"clear_deadline"`
+}
+
+type DeadlineRisk struct {
+	AudioId           int    `json:"audio_id"`
+	Filename          string `json:"filename"`
+	Status            string `json:"status"`
+	Priority          int    `json:"priority"`
+	Deadline          string `json:"deadline"`
+	RemainingSegments int    `json:"remaining_segments"`
+	// BacklogAhead counts the segments left in this file and in every file assigned before it.
+	BacklogAhead    int     `json:"backlog_ahead"`
+	EstimatedFinish *string `json:"estimated_finish"`
+	AtRisk          bool    `json:"at_risk"`
+}
+
+type DeadlineRiskList struct {
+	// ThroughputPerDay is the average number of segments finished per day over the last Days days.
+	ThroughputPerDay float64        `json:"throughput_per_day"`
+	Days             int            `json:"days"`
+	AtRisk           int            `json:"at_risk"`
+	Files            []DeadlineRisk `json:"files"`
+}
+
+type ReopenAudioFileBody struct {
+	Reason string `json:"reason"`
+	// SegmentIds limits the re-opening to these segments of the file; all of them by default.
+	SegmentIds []int `json:"segment_ids"`
+	// UserId assigns the re-opened file to this transcriber; without it the file goes
+	// back to the pool.
+	UserId string `json:"user_id"`
+}
+
+type ReopenAudioFile struct {
+	AudioId    int     `json:"audio_id"`
+	Reason     string  `json:"reason"`
+	SegmentIds []int   `json:"segment_ids"`
+	UserId     string  `json:"user_id"`
+	ActorId    *string `json:"actor_id"`
+}
+
+type ReopenAudioFileResult struct {
+	AudioId  int    `json:"audio_id"`
+	Segments int    `json:"segments"`
 	Status   string `json:"status"`
-	UserID   string `json:"user_id"`
+}
+
+type ReassignAudioFilesBody struct {
+	// AudioIds moves these files. Otherwise the files are selected by the filters below.
+	AudioIds   []int  `json:"audio_ids"`
+	FromUserId string `json:"from_user_id"`
+	// Status of the files to move. Files selected by from_user_id are only moved while
+	// processing unless another status is given.
+	Status      string `json:"status" example:"processing"`
+	CreatedFrom string `json:"created_from" example:"2025-07-01"`
+	CreatedTo   string `json:"created_to" example:"2025-07-31"`
+	Limit       int    `json:"limit"`
+	// ToUserId receives the files; when empty they go back to the pool.
+	ToUserId string `json:"to_user_id"`
+	Reason   string `json:"reason"`
+}
+
+type ReassignAudioFiles struct {
+	ReassignAudioFilesBody
+	ActorId *string `json:"actor_id"`
+}
+
+type ReassignAudioFilesResult struct {
+	Moved    int   `json:"moved"`
+	AudioIds []int `json:"audio_ids"`
+}
+
+type AudioFileEvent struct {
+	Id           int     `json:"id"`
+	AudioId      int     `json:"audio_id"`
+	Action       string  `json:"action"`
+	FromUserId   *string `json:"from_user_id"`
+	FromUsername *string `json:"from_username"`
+	ToUserId     *string `json:"to_user_id"`
+	ToUsername   *string `json:"to_username"`
+	ActorId      *string `json:"actor_id"`
+	Reason       *string `json:"reason"`
+	SegmentIds   []int   `json:"segment_ids"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+type AudioFileEventList struct {
+	Events []AudioFileEvent `json:"events"`
 }
@@ -1,5 +1,7 @@
 package entity
 
+import "errors"
+
 type CreateAudioSegment struct {
 	AudioId          int     `json:"audio_id"`
 	FileName         string  `json:"filename_name"`
@@ -34,9 +36,13 @@ type TranscriptPersent struct {
 	ProcessingAudio   int `json:"processing_audio"`
 	PendingAudioFiles int `json:"pending_audio_files"`
 	ErrorAudioFiles   int `json:"error_audio_files"`
-	TotalSegments     int `json:"total_segments"`
-	CompletedSegments int `json:"completed_segments"`
-	ReportSegments    int `json:"report_segments"`
+	// UnassignedAudio counts files returned to the pool, ReassignedToday the reassignments made today.
+	UnassignedAudio   int    `json:"unassigned_audio_files"`
+	ReassignedToday   int    `json:"reassigned_today"`
+	TotalSegments     int    `json:"total_segments"`
+	CompletedSegments int    `json:"completed_segments"`
+	ReportSegments    int    `json:"report_segments"`
+	RefreshedAt       string `json:"refreshed_at"`
 }
 
 // type UserTranscriptCount struct {
@@ -53,6 +59,10 @@ type UserTranscriptStatictics struct {
 	WeeklyAudioFiles int     `json:"weekly_audio_files"`
 	WeeklyChunks     int     `json:"weekly_chunks"`
 	DailyChunks      string  `json:"daily_chunks"`
+	// Active minutes measured by editor heartbeats.
+	ActiveMinutesToday float64    `json:"active_minutes_today"`
+	ActiveMinutesWeek  float64    `json:"active_minutes_week"`
+	Quota              *UserQuota `json:"quota"`
 }
 
 type TranscriptStatictics struct {
@@ -87,11 +97,16 @@ type DatasetViewerList struct {
 	TranscriberID *string  `json:"transcriber_id"`
 	MinutesSpent  *float32 `json:"minutes_spent"`
 	Emotion       *string  `json:"emotion"`
+	// Language is the primary language of the segment and LanguageSpans the passages
+	// of text in other languages.
+	Language      string         `json:"language"`
+	LanguageSpans []LanguageSpan `json:"language_spans"`
 }
 
 type DatasetViewerListResponse struct {
-	Total int                 `json:"total"`
-	Data  []DatasetViewerList `json:"data"`
+	Total       int                 `json:"total"`
+	Data        []DatasetViewerList `json:"data"`
+	RefreshedAt string              `json:"refreshed_at"`
 }
 
 type Statistics struct {
@@ -100,6 +115,7 @@ type Statistics struct {
 	PreviouText map[string]int `json:"previous_text"`
 	NextText    map[string]int `json:"next_text"`
 	Transcriber map[string]int `json:"transcriber"`
+	RefreshedAt string         `json:"refreshed_at"`
 }
 
 type DailyTranscript struct {
@@ -117,3 +133,41 @@ type DailyTranscriptResponse struct {
 type ListDailyTranscriptResponse struct {
 	Data []DailyTranscriptResponse `json:"data"`
 }
+
+// ErrTooManyBuckets is returned when the bucket width spreads the segments over more
+// buckets than a histogram holds.
+var ErrTooManyBuckets = errors.New("width gives too many buckets")
+
+const (
+	HistogramDuration     = "duration"
+	HistogramChars        = "chars"
+	HistogramWords        = "words"
+	HistogramCharsPerSec  = "chars_per_sec"
+	HistogramMinutesSpent = "minutes_spent"
+)
+
+type HistogramReq struct {
+	Dimension string `json:"dimension"`
+	// Either Width, for equal buckets starting at zero, or Edges, for explicit bucket bounds.
+	Width    float64   `json:"width"`
+	Edges    []float64 `json:"edges"`
+	FromDate string    `json:"from_date"`
+	ToDate   string    `json:"to_date"`
+	UserId   string    `json:"user_id"`
+	AudioId  int       `json:"audio_id"`
+	Status   string    `json:"status"`
+}
+
+// HistogramBucket counts the values in [From, To). A nil bound is unbounded.
+type HistogramBucket struct {
+	From  *float64 `json:"from"`
+	To    *float64 `json:"to"`
+	Label string   `json:"label"`
+	Count int      `json:"count"`
+}
+
+type Histogram struct {
+	Dimension string            `json:"dimension"`
+	Total     int               `json:"total"`
+	Buckets   []HistogramBucket `json:"buckets"`
+}
@@ -0,0 +1,61 @@
+package entity
+
+type CreateAsrModel struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	CreatedBy   *string `json:"-"`
+}
+
+type AsrModel struct {
+	Id          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Segments    int     `json:"segments"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+type AsrModelList struct {
+	Models []AsrModel `json:"models"`
+	Count  int        `json:"count"`
+}
+
+type AsrHypothesis struct {
+	SegmentId  int    `json:"segment_id"`
+	Hypothesis string `json:"hypothesis"`
+}
+
+type AsrUploadResult struct {
+	Received int `json:"received"`
+	Scored   int `json:"scored"`
+	// Skipped counts hypotheses whose segment has no approved transcript.
+	Skipped int `json:"skipped"`
+}
+
+type AsrScore struct {
+	Segments   int     `json:"segments"`
+	WER        float64 `json:"wer"`
+	CER        float64 `json:"cer"`
+	WordErrors int     `json:"word_errors"`
+	RefWords   int     `json:"ref_words"`
+	CharErrors int     `json:"char_errors"`
+	RefChars   int     `json:"ref_chars"`
+}
+
+type AsrLeaderboardBucket struct {
+	Bucket string   `json:"bucket"`
+	Score  AsrScore `json:"score"`
+}
+
+type AsrLeaderboardEntry struct {
+	Rank      int                    `json:"rank"`
+	ModelId   int                    `json:"model_id"`
+	ModelName string                 `json:"model_name"`
+	Overall   AsrScore               `json:"overall"`
+	Duration  []AsrLeaderboardBucket `json:"duration"`
+	Emotion   []AsrLeaderboardBucket `json:"emotion"`
+	Language  []AsrLeaderboardBucket `json:"language"`
+}
+
+type AsrLeaderboard struct {
+	Models []AsrLeaderboardEntry `json:"models"`
+}
@@ -0,0 +1,55 @@
+package entity
+
+type EventTag struct {
+	Id int `json:"id"`
+	// Code names the tag in statistics and exports.
+	Code string `json:"code" example:"music"`
+	// Tag is the canonical spelling written in brackets.
+	Tag string `json:"tag" example:"Musiqa"`
+	// Aliases are other spellings, rewritten to Tag when a transcript is saved.
+	Aliases []string `json:"aliases" example:"music,мусиқа"`
+	// ExportAs replaces the tag in exports with tags=map, "<code>" when empty.
+	ExportAs  string            `json:"export_as" example:"<music>"`
+	Name      MultilingualField `json:"name"`
+	IsActive  bool              `json:"is_active"`
+	SortOrder int               `json:"sort_order"`
+}
+
+type CreateEventTag struct {
+	Code      string            `json:"code" example:"music"`
+	Tag       string            `json:"tag" example:"Musiqa"`
+	Aliases   []string          `json:"aliases" example:"music,мусиқа"`
+	ExportAs  string            `json:"export_as" example:"<music>"`
+	Name      MultilingualField `json:"name"`
+	SortOrder int               `json:"sort_order"`
+}
+
+type UpdateEventTag struct {
+	Id        int               `json:"-"`
+	Tag       string            `json:"tag"`
+	Aliases   *[]string         `json:"aliases"`
+	ExportAs  *string           `json:"export_as"`
+	Name      MultilingualField `json:"name"`
+	IsActive  *bool             `json:"is_active"`
+	SortOrder *int              `json:"sort_order"`
+}
+
+type EventTagList struct {
+	Tags []EventTag `json:"tags"`
+}
+
+// EventTagStat counts the segments with a tag, spelled canonically or by an alias.
+type EventTagStat struct {
+	Code     string  `json:"code"`
+	Tag      string  `json:"tag"`
+	Segments int     `json:"segments"`
+	Hours    float64 `json:"hours"`
+	// Share is the part of all matching segments that have the tag.
+	Share float64 `json:"share"`
+}
+
+type EventTagStats struct {
+	Segments int            `json:"segments"`
+	Hours    float64        `json:"hours"`
+	Tags     []EventTagStat `json:"tags"`
+}
@@ -0,0 +1,117 @@
+package entity
+
+import "errors"
+
+// ErrBundleLeaseLost is returned when a bundle was claimed again by another worker
+// after the lease of the caller ran out.
+var ErrBundleLeaseLost = errors.New("export bundle lease lost")
+
+// ManifestReq filters the segments of a dataset export.
+type ManifestReq struct {
+	// Status of the transcripts, done by default.
+	Status string `json:"status"`
+	// FromDate and ToDate bound the day the transcripts were submitted, as YYYY-MM-DD.
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+	UserId   string `json:"user_id"`
+	// Language is the primary language of the segments, such as uz or ru.
+	Language string `json:"language"`
+	// SpanLanguage keeps the segments with a passage in this language, such as ru.
+	SpanLanguage string `json:"span_language"`
+	// IncludeFlagged keeps transcripts held back by the quality check.
+	IncludeFlagged bool `json:"include_flagged"`
+	// Split keeps the audio files assigned to this dataset split, such as train.
+	Split string `json:"split"`
+	// Script writes Uzbek transcripts in latin or cyrillic, whichever they were typed
+	// in. Empty keeps them as typed.
+	Script string `json:"script"`
+	// Normalize adds the text normalized for training next to the raw text.
+	Normalize bool `json:"normalize"`
+	// Tags keeps the event tags in their catalog spelling, maps them to their export
+	// token or drops them: keep, map or drop. Empty leaves them as typed.
+	Tags string `json:"tags"`
+	// AfterAudioId and AfterSegmentId skip the segments up to and including this one,
+	// in export order. They are set when a bundle build resumes.
+	AfterAudioId   int `json:"-"`
+	AfterSegmentId int `json:"-"`
+	// Limit caps the number of segments read, when set.
+	Limit int `json:"-"`
+}
+
+// ManifestEntry is one line of a NeMo or HuggingFace style JSONL manifest.
+// Each audio file is taken to be a single speaker.
+type ManifestEntry struct {
+	AudioFilepath string  `json:"audio_filepath"`
+	Duration      float64 `json:"duration"`
+	Text          string  `json:"text"`
+	// TextNormalized is set when the export asked for normalized text.
+	TextNormalized string `json:"text_normalized,omitempty"`
+	Emotion        string `json:"emotion,omitempty"`
+	Speaker        string `json:"speaker"`
+	AudioId        int    `json:"audio_id"`
+	SegmentId      int    `json:"segment_id"`
+	Language       string `json:"language"`
+	TranscriberId  string `json:"transcriber_id,omitempty"`
+	Split          string `json:"split,omitempty"`
+	// LanguageSpans are the passages of text in other languages than Language.
+	LanguageSpans []LanguageSpan `json:"language_spans,omitempty"`
+}
+
+// TrainingText is the text to train on, as in Kaldi text files: the normalized text
+// when the export normalized, even if nothing was left of it, and the text otherwise.
+func (e *ManifestEntry) TrainingText(normalized bool) string {
+	if normalized {
+		return e.TextNormalized
+	}
+	return e.Text
+}
+
+const (
+	BundlePending = "pending"
+	BundleRunning = "running"
+	BundleDone    = "done"
+	BundleFailed  = "failed"
+)
+
+// ExportBundle is a tar.gz of the audio of the selected segments and a manifest.jsonl
+// whose audio_filepath points into the archive, built in the background.
+type ExportBundle struct {
+	Id     int         `json:"id"`
+	Status string      `json:"status"`
+	Filter ManifestReq `json:"filter"`
+	// Total is the number of segments selected when the bundle was requested.
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	// Skipped counts segments whose audio object is missing in MinIO.
+	Skipped int `json:"skipped"`
+	// Progress is processed / total, between 0 and 1.
+	Progress   float64 `json:"progress"`
+	Size       int64   `json:"size"`
+	ObjectName *string `json:"object_name"`
+	UploadId   string  `json:"-"`
+	Error      *string `json:"error"`
+	Attempts   int     `json:"attempts"`
+	CreatedBy  *string `json:"created_by"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
+	FinishedAt *string `json:"finished_at"`
+	// DownloadURL is a presigned link to the finished bundle.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type ExportBundleList struct {
+	Bundles []ExportBundle `json:"bundles"`
+	Count   int            `json:"count"`
+}
+
+// ExportBundlePart is an uploaded part of a bundle.
+type ExportBundlePart struct {
+	Number        int
+	ETag          string
+	Size          int64
+	Segments      int
+	Skipped       int
+	LastAudioId   int
+	LastSegmentId int
+	Manifest      string
+}
@@ -0,0 +1,48 @@
+package entity
+
+// UserRisk sums up the low-effort findings of a transcriber's submissions.
+// RiskScore is the average risk of their checked transcripts, from 0 to 100.
+type UserRisk struct {
+	UserId             string  `json:"user_id"`
+	Username           *string `json:"username"`
+	Transcripts        int     `json:"transcripts"`
+	Flagged            int     `json:"flagged"`
+	UnchangedAi        int     `json:"unchanged_ai"`
+	DuplicateNeighbour int     `json:"duplicate_neighbour"`
+	TooFast            int     `json:"too_fast"`
+	RepetitiveFiller   int     `json:"repetitive_filler"`
+	RiskScore          float64 `json:"risk_score"`
+}
+
+type UserRiskList struct {
+	Days  int        `json:"days"`
+	Users []UserRisk `json:"users"`
+}
+
+type FraudFlagReq struct {
+	UserId string `json:"user_id"`
+	Reason string `json:"reason"`
+	Days   int    `json:"days"`
+	Filter Filter `json:"filter"`
+}
+
+type FraudFlag struct {
+	TranscriptId int      `json:"transcript_id"`
+	SegmentId    int      `json:"segment_id"`
+	AudioId      int      `json:"audio_id"`
+	ChunkUrl     string   `json:"chunk_url"`
+	UserId       string   `json:"user_id"`
+	Username     *string  `json:"username"`
+	Text         *string  `json:"text"`
+	AiText       *string  `json:"ai_text"`
+	Duration     float64  `json:"duration"`
+	SpentSeconds *float64 `json:"spent_seconds"`
+	Reasons      []string `json:"reasons"`
+	Risk         float64  `json:"risk"`
+	SubmittedAt  string   `json:"submitted_at"`
+}
+
+type FraudFlagList struct {
+	Flags []FraudFlag `json:"flags"`
+	Count int         `json:"count"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+// LintReq checks a transcript text against the transcription guidelines.
+type LintReq struct {
+	TranscriptText string `json:"transcribe_text" example:"[Musiqa] Salom, 5 ta olma"`
+}
+
+// LintIssue is a guideline the text breaks at its characters [start, end).
+type LintIssue struct {
+	Rule string `json:"rule" example:"digits"`
+	// Severity is warning or error. Errors block saving the transcript.
+	Severity string `json:"severity" example:"error"`
+	Start    int    `json:"start" example:"16"`
+	End      int    `json:"end" example:"17"`
+	Message  string `json:"message" example:"Write the number 5 in words"`
+}
+
+type LintResult struct {
+	// Valid is false when an issue is an error.
+	Valid  bool        `json:"valid"`
+	Issues []LintIssue `json:"issues"`
+}
+
+// TranscriptLintResponse answers an update rejected by the guidelines.
+type TranscriptLintResponse struct {
+	Message string      `json:"message"`
+	Code    string      `json:"code"`
+	Issues  []LintIssue `json:"issues"`
+}
+
+// UpdateTranscriptResponse answers a saved update with the warnings of its text.
+type UpdateTranscriptResponse struct {
+	Message string      `json:"message"`
+	Issues  []LintIssue `json:"issues,omitempty"`
+}
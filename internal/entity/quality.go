@@ -0,0 +1,78 @@
+package entity
+
+// Reasons a transcript is flagged by the quality check.
+const (
+	QualityEmptyText       = "empty_text"
+	QualityCharsPerSecHigh = "chars_per_sec_high"
+	QualityCharsPerSecLow  = "chars_per_sec_low"
+	QualityWordsPerSecHigh = "words_per_sec_high"
+	QualityWordsPerSecLow  = "words_per_sec_low"
+)
+
+// Reviews of a flagged transcript. Confirmed and open ones are left out of exports.
+const (
+	QualityReviewOpen      = "open"
+	QualityReviewConfirmed = "confirmed"
+	QualityReviewDismissed = "dismissed"
+)
+
+// QualityLanguageStats are the fences a language's transcripts were checked against.
+// Fences are nil when the language has too few transcripts to judge.
+type QualityLanguageStats struct {
+	Language         string   `json:"language"`
+	Transcripts      int      `json:"transcripts"`
+	Flagged          int      `json:"flagged"`
+	CharsPerSecLower *float64 `json:"chars_per_sec_lower"`
+	CharsPerSecUpper *float64 `json:"chars_per_sec_upper"`
+	WordsPerSecLower *float64 `json:"words_per_sec_lower"`
+	WordsPerSecUpper *float64 `json:"words_per_sec_upper"`
+}
+
+type QualityCheckResult struct {
+	Languages []QualityLanguageStats `json:"languages"`
+	CheckedAt string                 `json:"checked_at"`
+}
+
+type QualityOutlier struct {
+	TranscriptId int      `json:"transcript_id"`
+	SegmentId    int      `json:"segment_id"`
+	AudioId      int      `json:"audio_id"`
+	ChunkUrl     string   `json:"chunk_url"`
+	Text         *string  `json:"text"`
+	Language     string   `json:"language"`
+	Duration     float64  `json:"duration"`
+	Chars        int      `json:"chars"`
+	Words        int      `json:"words"`
+	CharsPerSec  float64  `json:"chars_per_sec"`
+	WordsPerSec  float64  `json:"words_per_sec"`
+	Reasons      []string `json:"reasons"`
+	Review       string   `json:"review"`
+	UserId       *string  `json:"user_id"`
+	Username     *string  `json:"username"`
+	ReviewedBy   *string  `json:"reviewed_by"`
+	ReviewedAt   *string  `json:"reviewed_at"`
+	ComputedAt   string   `json:"computed_at"`
+}
+
+type QualityOutlierReq struct {
+	Language string `json:"language"`
+	Reason   string `json:"reason"`
+	Review   string `json:"review"`
+	UserId   string `json:"user_id"`
+	Filter   Filter `json:"filter"`
+}
+
+type QualityOutlierList struct {
+	Outliers []QualityOutlier `json:"outliers"`
+	Count    int              `json:"count"`
+}
+
+type ReviewQualityOutlierBody struct {
+	Review string `json:"review" binding:"required" example:"dismissed"`
+}
+
+type ReviewQualityOutlier struct {
+	TranscriptId int    `json:"-"`
+	Review       string `json:"-"`
+	ReviewerId   string `json:"-"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+// TranscriberLimit holds the limits of one user or of every user with a role.
+// The limits of a user take precedence over those of their role.
+type TranscriberLimit struct {
+	Id                int      `json:"id"`
+	UserId            *string  `json:"user_id"`
+	Username          *string  `json:"username"`
+	Role              *string  `json:"role"`
+	MaxSegmentsPerDay *int     `json:"max_segments_per_day"`
+	MaxMinutesPerWeek *float64 `json:"max_minutes_per_week"`
+	WorkStart         *string  `json:"work_start"`
+	WorkEnd           *string  `json:"work_end"`
+	UpdatedAt         string   `json:"updated_at"`
+}
+
+type SetTranscriberLimit struct {
+	// Either UserId or Role.
+	UserId            string   `json:"user_id"`
+	Role              string   `json:"role" example:"transcriber"`
+	MaxSegmentsPerDay *int     `json:"max_segments_per_day" example:"300"`
+	MaxMinutesPerWeek *float64 `json:"max_minutes_per_week" example:"600"`
+	// WorkStart and WorkEnd as HH:MM in the working timezone. A window may wrap past midnight.
+	WorkStart *string `json:"work_start" example:"09:00"`
+	WorkEnd   *string `json:"work_end" example:"18:00"`
+}
+
+type TranscriberLimitList struct {
+	Limits []TranscriberLimit `json:"limits"`
+}
+
+// UserQuota is the usage of a transcriber against their effective limits.
+// Nil limits and remainders mean unlimited.
+type UserQuota struct {
+	Timezone               string   `json:"timezone"`
+	MaxSegmentsPerDay      *int     `json:"max_segments_per_day"`
+	SegmentsToday          int      `json:"segments_today"`
+	RemainingSegmentsToday *int     `json:"remaining_segments_today"`
+	MaxMinutesPerWeek      *float64 `json:"max_minutes_per_week"`
+	MinutesThisWeek        float64  `json:"minutes_this_week"`
+	RemainingMinutesWeek   *float64 `json:"remaining_minutes_this_week"`
+	WorkStart              *string  `json:"work_start"`
+	WorkEnd                *string  `json:"work_end"`
+	WithinWorkingHours     bool     `json:"within_working_hours"`
+}
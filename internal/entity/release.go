@@ -0,0 +1,71 @@
+package entity
+
+const (
+	ReleaseAdded   = "added"
+	ReleaseRemoved = "removed"
+	ReleaseChanged = "changed"
+)
+
+type CreateRelease struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Filter      ManifestReq `json:"filter"`
+	CreatedBy   *string     `json:"-"`
+}
+
+// ReleaseStats counts the segments of a release by language, split and emotion.
+type ReleaseStats struct {
+	Languages map[string]int `json:"languages"`
+	Splits    map[string]int `json:"splits"`
+	Emotions  map[string]int `json:"emotions"`
+}
+
+// Release is a frozen set of transcribed segments.
+type Release struct {
+	Id          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description"`
+	Filter      ManifestReq  `json:"filter"`
+	Segments    int          `json:"segments"`
+	AudioFiles  int          `json:"audio_files"`
+	Hours       float64      `json:"hours"`
+	Stats       ReleaseStats `json:"stats"`
+	// ManifestSha256 is the SHA-256 of the JSONL manifest the release exports as.
+	ManifestSha256 string  `json:"manifest_sha256"`
+	CreatedBy      *string `json:"created_by"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+type ReleaseList struct {
+	Releases []Release `json:"releases"`
+	Count    int       `json:"count"`
+}
+
+type ReleaseDiffReq struct {
+	From int
+	To   int
+	// Kind is added, removed or changed; empty for all.
+	Kind   string
+	Filter Filter
+}
+
+// ReleaseChange is a segment that is only in one of two releases, or whose text
+// differs between them.
+type ReleaseChange struct {
+	SegmentId int     `json:"segment_id"`
+	AudioId   int     `json:"audio_id"`
+	Kind      string  `json:"kind"`
+	OldText   *string `json:"old_text"`
+	NewText   *string `json:"new_text"`
+}
+
+type ReleaseDiff struct {
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Added   int             `json:"added"`
+	Removed int             `json:"removed"`
+	Changed int             `json:"changed"`
+	Changes []ReleaseChange `json:"changes"`
+	// Count is the number of changes of the requested kind, for pagination.
+	Count int `json:"count"`
+}
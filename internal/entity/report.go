@@ -0,0 +1,78 @@
+package entity
+
+const (
+	ReportActionAccept  = "accept"
+	ReportActionReopen  = "reopen"
+	ReportActionRechunk = "rechunk"
+)
+
+type ReportReason struct {
+	Id        int               `json:"id"`
+	Code      string            `json:"code"`
+	Name      MultilingualField `json:"name"`
+	IsActive  bool              `json:"is_active"`
+	SortOrder int               `json:"sort_order"`
+}
+
+type CreateReportReason struct {
+	Code      string            `json:"code"`
+	Name      MultilingualField `json:"name"`
+	SortOrder int               `json:"sort_order"`
+}
+
+type UpdateReportReason struct {
+	Id        int               `json:"-"`
+	Name      MultilingualField `json:"name"`
+	IsActive  *bool             `json:"is_active"`
+	SortOrder *int              `json:"sort_order"`
+}
+
+type ReportReasonList struct {
+	Reasons []ReportReason `json:"reasons"`
+}
+
+type ReportQueueReq struct {
+	Reason string `json:"reason"`
+	Filter Filter `json:"filter"`
+}
+
+type ReportQueueItem struct {
+	SegmentId      int     `json:"segment_id"`
+	AudioId        int     `json:"audio_id"`
+	AudioName      string  `json:"audio_name"`
+	ChunkUrl       string  `json:"chunk_url"`
+	Duration       float32 `json:"duration"`
+	TranscriptText *string `json:"transcribe_text"`
+	ReportText     *string `json:"report_text"`
+	ReporterId     *string `json:"reporter_id"`
+	Reporter       *string `json:"reporter"`
+	ReportedAt     string  `json:"reported_at"`
+}
+
+type ReportQueueGroup struct {
+	Reason string            `json:"reason"`
+	Name   MultilingualField `json:"name"`
+	Count  int               `json:"count"`
+	Items  []ReportQueueItem `json:"items"`
+}
+
+type ReportQueue struct {
+	Groups []ReportQueueGroup `json:"groups"`
+	Total  int                `json:"total"`
+}
+
+type ResolveReportBody struct {
+	// Action is one of accept, reopen or rechunk.
+	Action string `json:"action" example:"reopen"`
+	// EntireAudio applies the resolution to every open report of the segment's audio file.
+	EntireAudio bool   `json:"entire_audio"`
+	Note        string `json:"note"`
+}
+
+type ResolveReport struct {
+	SegmentId   int     `json:"segment_id"`
+	Action      string  `json:"action"`
+	EntireAudio bool    `json:"entire_audio"`
+	Note        string  `json:"note"`
+	UserID      *string `json:"user_id"`
+}
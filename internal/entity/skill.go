@@ -0,0 +1,35 @@
+package entity
+
+const (
+	SkillKindLanguage = "language"
+	SkillKindDialect  = "dialect"
+	SkillKindDomain   = "domain"
+)
+
+type Skill struct {
+	Id   int    `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+type CreateSkill struct {
+	Code string `json:"code" example:"ru"`
+	Name string `json:"name" example:"Russian"`
+	Kind string `json:"kind" example:"language"`
+}
+
+type UpdateSkill struct {
+	Id   int    `json:"-"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+type SkillList struct {
+	Skills []Skill `json:"skills"`
+}
+
+// SetSkills replaces the skills of a user or the required skills of an audio file.
+type SetSkills struct {
+	Codes []string `json:"codes" example:"ru"`
+}
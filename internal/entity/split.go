@@ -0,0 +1,50 @@
+package entity
+
+const (
+	StratifyNone     = "none"
+	StratifyDuration = "duration"
+	StratifyEmotion  = "emotion"
+)
+
+type SplitRatio struct {
+	Name  string  `json:"name"`
+	Ratio float64 `json:"ratio"`
+}
+
+// SplitAssignReq assigns audio files to dataset splits. Empty fields fall back to the
+// split section of the config.
+type SplitAssignReq struct {
+	Seed   string       `json:"seed"`
+	Ratios []SplitRatio `json:"ratios"`
+	// Stratify is none, duration (of the whole audio file) or emotion (the most common
+	// one of its done transcripts). Each stratum is split by the ratios on its own, and
+	// the stratum is recorded with the split.
+	Stratify string `json:"stratify"`
+	// Reassign drops every assignment and starts over. Otherwise audio files keep their
+	// split and only new ones are assigned.
+	Reassign bool `json:"reassign"`
+}
+
+type SplitAssignResult struct {
+	Seed     string       `json:"seed"`
+	Ratios   []SplitRatio `json:"ratios"`
+	Stratify string       `json:"stratify"`
+	Assigned int          `json:"assigned"`
+	// Kept counts audio files that already had a split.
+	Kept int `json:"kept"`
+}
+
+type SplitStats struct {
+	Split      string  `json:"split"`
+	AudioFiles int     `json:"audio_files"`
+	Segments   int     `json:"segments"`
+	Hours      float64 `json:"hours"`
+	// Share is the share of the hours of all splits.
+	Share float64 `json:"share"`
+}
+
+type SplitStatsList struct {
+	Splits []SplitStats `json:"splits"`
+	// Unassigned counts audio files without a split yet.
+	Unassigned int `json:"unassigned"`
+}
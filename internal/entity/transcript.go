@@ -1,5 +1,19 @@
 package entity
 
+import "errors"
+
+// ErrTranscriptVersionConflict is returned when a transcript was changed since the
+// version the client based its update on.
+var ErrTranscriptVersionConflict = errors.New("transcript version conflict")
+
+// ErrSegmentNotAssigned is returned when a user reports work on a segment of an
+// audio file assigned to someone else.
+var ErrSegmentNotAssigned = errors.New("segment is not assigned to the user")
+
+// ErrRevertLanguages is returned when the languages of the revision to revert to are
+// not valid any more, such as a language that is no longer configured.
+var ErrRevertLanguages = errors.New("invalid languages")
+
 type Transcript struct {
 	Id               int     `json:"id"`
 	AudioId          int     `json:"audio_id"`
@@ -10,10 +24,24 @@ type Transcript struct {
 	AIText           *string `json:"ai_text"`
 	TranscriptText   *string `json:"transcribe_text"`
 	ReportText       *string `json:"report_text"`
+	ReportReason     *string `json:"report_reason"`
+	ReportStatus     *string `json:"report_status"`
 	TranscriptOption *string `json:"transcribe_option"`
 	Status           string  `json:"status"`
 	Emotion          *string `json:"emotion"`
+	Version          int     `json:"version"`
 	CreatedAt        string  `json:"created_at"`
+	// Language is the primary language of the segment.
+	Language      string         `json:"language" example:"uz"`
+	LanguageSpans []LanguageSpan `json:"language_spans"`
+}
+
+// LanguageSpan is a passage of a transcript in another language than the segment,
+// over the characters [start, end) of its text.
+type LanguageSpan struct {
+	Start    int    `json:"start" example:"4"`
+	End      int    `json:"end" example:"10"`
+	Language string `json:"language" example:"ru"`
 }
 
 type CreateTranscript struct {
@@ -28,6 +56,11 @@ type UpdateTranscript struct {
 	UserID             *string `json:"user_id"`
 	EntireAudioInvalid bool    `json:"entire_audio_invalid"`
 	Emotion            string  `json:"emotion"`
+	Version            int     `json:"version"`
+	ReportReason       string  `json:"report_reason"`
+	// Language and LanguageSpans are saved with TranscriptText.
+	Language      string         `json:"language"`
+	LanguageSpans []LanguageSpan `json:"language_spans"`
 }
 
 type UpdateTranscriptBody struct {
@@ -35,6 +68,22 @@ type UpdateTranscriptBody struct {
 	ReportText         string `json:"report_text"`
 	EntireAudioInvalid bool   `json:"entire_audio_invalid"`
 	Emotion            string `json:"emotion"`
+	// ReportReason is a code from the report reason catalog.
+	ReportReason string `json:"report_reason"`
+	// Language is the primary language of the segment, the default one when empty.
+	Language string `json:"language" example:"uz"`
+	// LanguageSpans are the passages of transcribe_text in other languages. When they
+	// are left out, "(ru: ...)" passages typed into transcribe_text become spans.
+	LanguageSpans *[]LanguageSpan `json:"language_spans"`
+	// Version is the transcript version the edit is based on. It may be sent
+	// as an If-Match header instead.
+	Version *int `json:"version"`
+}
+
+type TranscriptConflictResponse struct {
+	Message string     `json:"message"`
+	Code    string     `json:"code"`
+	Current Transcript `json:"current"`
 }
 
 type GetTranscriptReq struct {
@@ -48,3 +97,71 @@ type TranscriptList struct {
 	Transcripts []Transcript `json:"transcripts"`
 	Count       int          `json:"count"`
 }
+
+type TranscriptRevision struct {
+	Id             int     `json:"id"`
+	UserId         *string `json:"user_id"`
+	Username       *string `json:"username"`
+	TranscriptText *string `json:"transcribe_text"`
+	ReportText     *string `json:"report_text"`
+	Emotion        *string `json:"emotion"`
+	Status         string  `json:"status"`
+	Action         string  `json:"action"`
+	Note           *string `json:"note"`
+	CreatedAt      string  `json:"created_at"`
+	// Language and LanguageSpans are null for revisions older than them.
+	Language      *string        `json:"language"`
+	LanguageSpans []LanguageSpan `json:"language_spans"`
+}
+
+type TranscriptHistory struct {
+	SegmentId int                  `json:"segment_id"`
+	AIText    *string              `json:"ai_text"`
+	Revisions []TranscriptRevision `json:"revisions"`
+}
+
+type TranscriptDiffReq struct {
+	SegmentId int `json:"segment_id"`
+	// FromRevision and ToRevision of 0 refer to the original ai_text;
+	// a nil ToRevision refers to the latest revision.
+	FromRevision int  `json:"from"`
+	ToRevision   *int `json:"to"`
+}
+
+type TranscriptDiff struct {
+	SegmentId    int      `json:"segment_id"`
+	FromRevision int      `json:"from"`
+	ToRevision   int      `json:"to"`
+	Ops          []DiffOp `json:"ops"`
+}
+
+type DiffOp struct {
+	Op   string `json:"op" example:"equal"`
+	Text string `json:"text"`
+}
+
+type RevertTranscript struct {
+	SegmentId  int     `json:"segment_id"`
+	RevisionId int     `json:"revision_id"`
+	UserID     *string `json:"user_id"`
+	// Languages are the configured transcript languages, the default one first.
+	Languages []string `json:"languages"`
+}
+
+type HeartbeatBody struct {
+	// End closes the session, e.g. when the editor is left.
+	End bool `json:"end"`
+}
+
+type Heartbeat struct {
+	SegmentId int    `json:"segment_id"`
+	UserId    string `json:"user_id"`
+	End       bool   `json:"end"`
+}
+
+type HeartbeatResult struct {
+	SessionId      int  `json:"session_id"`
+	SessionSeconds int  `json:"session_seconds"`
+	ActiveSeconds  int  `json:"active_seconds"`
+	Resumed        bool `json:"resumed"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+// TranslitReq writes Uzbek text in another alphabet.
+type TranslitReq struct {
+	Text string `json:"text" example:"Салом, дунё!"`
+	// Script is latin or cyrillic.
+	Script string `json:"script" binding:"required" example:"latin"`
+}
+
+type TranslitRes struct {
+	Text string `json:"text" example:"Salom, dunyo!"`
+	// SourceScript is the alphabet the text was written in: latin, cyrillic, mixed,
+	// or empty when it has no letters.
+	SourceScript string `json:"source_script" example:"cyrillic"`
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
 )
 
 //go:generate mockgen -source=interfaces.go -destination=./mocks_test.go -package=usecase_test
@@ -30,6 +31,11 @@ type (
 		// UpdateStatus(ctx context.Context, id *int, user_id string) error
 		Delete(ctx context.Context, id int) error
 		StartTranscripts(ctx context.Context, id int) error
+		GetHistory(ctx context.Context, id int) (*entity.TranscriptHistory, error)
+		Diff(ctx context.Context, req *entity.TranscriptDiffReq) (*entity.TranscriptDiff, error)
+		Revert(ctx context.Context, req *entity.RevertTranscript) error
+		Heartbeat(ctx context.Context, req *entity.Heartbeat) (*entity.HeartbeatResult, error)
+		MigrateLanguageSpans(ctx context.Context) (int, error)
 	}
 
 	// AudioSegmentRepo -.
@@ -40,8 +46,11 @@ type (
 		Delete(ctx context.Context, id int) error
 		GetTranscriptPercent(ctx context.Context) (*entity.TranscriptPersent, error)
 		GetUserTranscriptStatictics(ctx context.Context, user_id string) (*entity.UserTranscriptStatictics, error)
-		DatasetViewer(ctx context.Context, req *entity.Filter, user_id string, report, ruBool bool) (*entity.DatasetViewerListResponse, error)
+		DatasetViewer(ctx context.Context, req *entity.Filter, user_id, language string, report, includeFlagged bool) (*entity.DatasetViewerListResponse, error)
 		GetStatistics(ctx context.Context) (*entity.Statistics, error)
+		RefreshStatistics(ctx context.Context) (*entity.Statistics, error)
+		RefreshAudioFileTexts(ctx context.Context) (time.Time, error)
+		GetHistogram(ctx context.Context, req *entity.HistogramReq) (*entity.Histogram, error)
 		GetAudioTranscriptStats(ctx context.Context, fromDate, toDate time.Time) (*[]entity.TranscriptStatictics, error)
 		GetHourlyTranscripts(ctx context.Context, userId string, date time.Time) (*entity.ListDailyTranscriptResponse, error)
 	}
@@ -50,5 +59,113 @@ type (
 	AudioFileRepoI interface {
 		Create(ctx context.Context, req *entity.CreateAudioFile) (*int, error)
 		GetById(ctx context.Context, id int) (*entity.AudioFile, error)
+		UpdatePriority(ctx context.Context, req *entity.UpdateAudioFilePriority) error
+		GetDeadlineRisks(ctx context.Context, days int, atRiskOnly bool) (*entity.DeadlineRiskList, error)
+		Reopen(ctx context.Context, req *entity.ReopenAudioFile) (*entity.ReopenAudioFileResult, error)
+		Reassign(ctx context.Context, req *entity.ReassignAudioFiles) (*entity.ReassignAudioFilesResult, error)
+		GetEvents(ctx context.Context, audioId int) (*entity.AudioFileEventList, error)
+	}
+
+	// ReportRepo -.
+	ReportRepoI interface {
+		CreateReason(ctx context.Context, req *entity.CreateReportReason) (*int, error)
+		UpdateReason(ctx context.Context, req *entity.UpdateReportReason) error
+		DeleteReason(ctx context.Context, id int) error
+		GetReasons(ctx context.Context, activeOnly bool) (*entity.ReportReasonList, error)
+		IsActiveReason(ctx context.Context, code string) (bool, error)
+		GetQueue(ctx context.Context, req *entity.ReportQueueReq) (*entity.ReportQueue, error)
+		Resolve(ctx context.Context, req *entity.ResolveReport) error
+		ClaimRechunk(ctx context.Context, segmentId int, userId *string) error
+		ReleaseRechunk(ctx context.Context, segmentId int, userId *string) error
+		FinishRechunk(ctx context.Context, req *entity.ResolveReport, chunks []entity.CreateAudioSegment) error
+	}
+
+	// BenchmarkRepo -.
+	BenchmarkRepoI interface {
+		CreateModel(ctx context.Context, req *entity.CreateAsrModel) (*int, error)
+		GetModels(ctx context.Context, req *entity.Filter) (*entity.AsrModelList, error)
+		SaveHypotheses(ctx context.Context, modelId int, hyps []entity.AsrHypothesis) (*entity.AsrUploadResult, error)
+		GetLeaderboard(ctx context.Context) (*entity.AsrLeaderboard, error)
+	}
+
+	// SkillRepo -.
+	SkillRepoI interface {
+		Create(ctx context.Context, req *entity.CreateSkill) (*int, error)
+		Update(ctx context.Context, req *entity.UpdateSkill) error
+		Delete(ctx context.Context, id int) error
+		GetList(ctx context.Context, kind string) (*entity.SkillList, error)
+		GetUserSkills(ctx context.Context, userId string) (*entity.SkillList, error)
+		GetAudioSkills(ctx context.Context, audioId int) (*entity.SkillList, error)
+		SetUserSkills(ctx context.Context, userId string, codes []string) error
+		SetAudioSkills(ctx context.Context, audioId int, codes []string) error
+	}
+
+	// QuotaRepo -.
+	QuotaRepoI interface {
+		SetLimit(ctx context.Context, req *entity.SetTranscriberLimit) (*int, error)
+		GetLimits(ctx context.Context) (*entity.TranscriberLimitList, error)
+		DeleteLimit(ctx context.Context, id int) error
+		GetUserQuota(ctx context.Context, userId string) (*entity.UserQuota, error)
+	}
+
+	// QualityRepo -.
+	QualityRepoI interface {
+		Check(ctx context.Context) (*entity.QualityCheckResult, error)
+		GetLastCheck(ctx context.Context) (*entity.QualityCheckResult, error)
+		GetOutliers(ctx context.Context, req *entity.QualityOutlierReq) (*entity.QualityOutlierList, error)
+		Review(ctx context.Context, req *entity.ReviewQualityOutlier) error
+	}
+
+	// FraudRepo -.
+	FraudRepoI interface {
+		Check(ctx context.Context, segmentIds []int) error
+		CheckPending(ctx context.Context) (int, error)
+		GetUserRisks(ctx context.Context, days int) (*entity.UserRiskList, error)
+		GetFlags(ctx context.Context, req *entity.FraudFlagReq) (*entity.FraudFlagList, error)
+	}
+
+	// ExportRepo -.
+	ExportRepoI interface {
+		CountManifest(ctx context.Context, req *entity.ManifestReq) (int, error)
+		StreamManifest(ctx context.Context, req *entity.ManifestReq, fn func(*entity.ManifestEntry) error) error
+		CreateBundle(ctx context.Context, req *entity.ManifestReq, createdBy *string) (*int, error)
+		GetBundle(ctx context.Context, id int) (*entity.ExportBundle, error)
+		GetBundles(ctx context.Context, req *entity.Filter) (*entity.ExportBundleList, error)
+		ClaimBundle(ctx context.Context, lease time.Duration) (*entity.ExportBundle, error)
+		StartBundleUpload(ctx context.Context, id, attempt int, objectName, uploadId string) error
+		GetBundleParts(ctx context.Context, id int) ([]entity.ExportBundlePart, error)
+		StreamBundleManifest(ctx context.Context, id int, fn func(string) error) error
+		SaveBundlePart(ctx context.Context, id, attempt int, part *entity.ExportBundlePart) error
+		UpdateBundleProgress(ctx context.Context, id, attempt, processed, skipped int) error
+		RenewBundleLease(ctx context.Context, id, attempt int) error
+		RetryBundle(ctx context.Context, id, attempt int, message string) error
+		FinishBundle(ctx context.Context, id, attempt, processed, skipped int, size int64) error
+		FailBundle(ctx context.Context, id, attempt int, message string) error
+	}
+
+	// SplitRepo -.
+	SplitRepoI interface {
+		AssignSplits(ctx context.Context, req *entity.SplitAssignReq) (*entity.SplitAssignResult, error)
+		GetSplitStats(ctx context.Context) (*entity.SplitStatsList, error)
+	}
+
+	// ReleaseRepo -.
+	ReleaseRepoI interface {
+		CreateRelease(ctx context.Context, req *entity.CreateRelease) (*int, error)
+		GetRelease(ctx context.Context, id int) (*entity.Release, error)
+		GetReleases(ctx context.Context, req *entity.Filter) (*entity.ReleaseList, error)
+		StreamRelease(ctx context.Context, id int, fn func(*entity.ManifestEntry) error) error
+		StreamReleaseManifest(ctx context.Context, id int, fn func(string) error) error
+		DiffReleases(ctx context.Context, req *entity.ReleaseDiffReq) (*entity.ReleaseDiff, error)
+	}
+
+	// EventTagRepo -.
+	EventTagRepoI interface {
+		CreateTag(ctx context.Context, req *entity.CreateEventTag) (*int, error)
+		UpdateTag(ctx context.Context, req *entity.UpdateEventTag) error
+		DeleteTag(ctx context.Context, id int) error
+		GetTags(ctx context.Context, activeOnly bool) (*entity.EventTagList, error)
+		GetCatalog(ctx context.Context) (*eventtag.Catalog, error)
+		GetStats(ctx context.Context, req *entity.ManifestReq) (*entity.EventTagStats, error)
 	}
 )
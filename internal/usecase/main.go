@@ -12,6 +12,16 @@ type UseCase struct {
 	TranscriptRepo   TranscriptRepoI
 	AudioSegmentRepo AudioSegmentRepoI
 	AudioFileRepo    AudioFileRepoI
+	BenchmarkRepo    BenchmarkRepoI
+	ReportRepo       ReportRepoI
+	SkillRepo        SkillRepoI
+	QuotaRepo        QuotaRepoI
+	QualityRepo      QualityRepoI
+	FraudRepo        FraudRepoI
+	ExportRepo       ExportRepoI
+	SplitRepo        SplitRepoI
+	ReleaseRepo      ReleaseRepoI
+	EventTagRepo     EventTagRepoI
 }
 
 func New(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *UseCase {
@@ -20,5 +30,15 @@ func New(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *U
 		TranscriptRepo:   repo.NewTranscriptRepo(pg, config, logger),
 		AudioSegmentRepo: repo.NewAudioSegmentRepo(pg, config, logger),
 		AudioFileRepo:    repo.NewAudioFileRepo(pg, config, logger),
+		BenchmarkRepo:    repo.NewBenchmarkRepo(pg, config, logger),
+		ReportRepo:       repo.NewReportRepo(pg, config, logger),
+		SkillRepo:        repo.NewSkillRepo(pg, config, logger),
+		QuotaRepo:        repo.NewQuotaRepo(pg, config, logger),
+		QualityRepo:      repo.NewQualityRepo(pg, config, logger),
+		FraudRepo:        repo.NewFraudRepo(pg, config, logger),
+		ExportRepo:       repo.NewExportRepo(pg, config, logger),
+		SplitRepo:        repo.NewSplitRepo(pg, config, logger),
+		ReleaseRepo:      repo.NewReleaseRepo(pg, config, logger),
+		EventTagRepo:     repo.NewEventTagRepo(pg, config, logger),
 	}
 }
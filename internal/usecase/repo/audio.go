@@ -2,7 +2,13 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/mirjalilova/voice_transcribe/config"
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
 	"github.com/mirjalilova/voice_transcribe/pkg/logger"
@@ -26,10 +32,10 @@ func NewAudioFileRepo(pg *postgres.Postgres, config *config.Config, logger *logg
 
 func (r *AudioFileRepo) Create(ctx context.Context, req *entity.CreateAudioFile) (*int, error) {
 	query := `
-	INSERT INTO audio_files (filename, file_path) VALUES($1, $2) RETURNING id`
+	INSERT INTO audio_files (filename, file_path, priority, deadline) VALUES($1, $2, $3, $4::timestamptz) RETURNING id`
 
 	var id int
-	err := r.pg.Pool.QueryRow(ctx, query, req.Filename, req.FilePath).Scan(&id)
+	err := r.pg.Pool.QueryRow(ctx, query, req.Filename, req.FilePath, req.Priority, req.Deadline).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
@@ -39,12 +45,396 @@ func (r *AudioFileRepo) Create(ctx context.Context, req *entity.CreateAudioFile)
 
 func (r *AudioFileRepo) GetById(ctx context.Context, id int) (*entity.AudioFile, error) {
 	query := `
-	SELECT id, filename, status, user_id FROM audio_files WHERE id = $1`
+	SELECT id, filename, status, COALESCE(user_id::text, ''), priority, deadline FROM audio_files WHERE id = $1`
+	var deadline *time.Time
 	audioFile := &entity.AudioFile{}
-	err := r.pg.Pool.QueryRow(ctx, query, id).Scan(&audioFile.ID, &audioFile.Filename, &audioFile.Status, &audioFile.UserID)
+	err := r.pg.Pool.QueryRow(ctx, query, id).Scan(&audioFile.ID, &audioFile.Filename, &audioFile.Status, &audioFile.UserID, &audioFile.Priority, &deadline)
 	if err != nil {
 		return nil, err
 	}
+	if deadline != nil {
+		formatted := deadline.Format("2006-01-02 15:04:05")
+		audioFile.Deadline = &formatted
+	}
 
 	return audioFile, nil
-}
\ No newline at end of file
+}
+
+func (r *AudioFileRepo) UpdatePriority(ctx context.Context, req *entity.UpdateAudioFilePriority) error {
+	query := `
+	UPDATE
+		audio_files
+	SET`
+
+	var conditions []string
+	var args []interface{}
+
+	if req.Priority != nil {
+		conditions = append(conditions, " priority = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.Priority)
+	}
+	if req.Deadline != nil {
+		conditions = append(conditions, " deadline = $"+strconv.Itoa(len(args)+1)+"::timestamptz")
+		args = append(args, *req.Deadline)
+	} else if req.ClearDeadline {
+		conditions = append(conditions, " deadline = NULL")
+	}
+
+	if len(conditions) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	conditions = append(conditions, " updated_at = now()")
+	query += strings.Join(conditions, ", ")
+	query += " WHERE id = $" + strconv.Itoa(len(args)+1) + " AND deleted_at = 0"
+	args = append(args, req.AudioId)
+
+	tag, err := r.pg.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update audio file priority: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetDeadlineRisks estimates when each unfinished file with a deadline will be done.
+// Files are worked in assignment order, so a file finishes once the segments left in
+// it and in every file ahead of it are done at the throughput of the last days.
+func (r *AudioFileRepo) GetDeadlineRisks(ctx context.Context, days int, atRiskOnly bool) (*entity.DeadlineRiskList, error) {
+	res := entity.DeadlineRiskList{Days: days, Files: []entity.DeadlineRisk{}}
+
+	var now time.Time
+	var done int
+	query := `
+	SELECT NOW()::timestamp, COUNT(id)
+	FROM transcripts
+	WHERE status = 'done' AND deleted_at = 0 AND updated_at >= NOW() - make_interval(days => $1)
+	`
+	err := r.pg.Pool.QueryRow(ctx, query, days).Scan(&now, &done)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get throughput: %w", err)
+	}
+	res.ThroughputPerDay = float64(done) / float64(days)
+
+	query = `
+	WITH queue AS (
+		SELECT
+			a.id,
+			a.filename,
+			a.status,
+			a.priority,
+			a.deadline,
+			a.created_at,
+			(
+				SELECT COUNT(t.id)
+				FROM transcripts t
+				JOIN audio_file_segments s ON s.id = t.segment_id
+				WHERE s.audio_id = a.id AND s.deleted_at = 0 AND t.deleted_at = 0 AND t.status = 'ready'
+			) AS remaining
+		FROM audio_files a
+		WHERE a.deleted_at = 0 AND a.status IN ('pending', 'processing', 'unassigned')
+	), ordered AS (
+		SELECT
+			*,
+			SUM(remaining) OVER (ORDER BY priority DESC, deadline ASC NULLS LAST, created_at, id) AS backlog
+		FROM queue
+	)
+	SELECT id, filename, status, priority, deadline, remaining, backlog
+	FROM ordered
+	WHERE deadline IS NOT NULL
+	ORDER BY deadline, id
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deadline risks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deadline time.Time
+		file := entity.DeadlineRisk{}
+		err := rows.Scan(&file.AudioId, &file.Filename, &file.Status, &file.Priority, &deadline, &file.RemainingSegments, &file.BacklogAhead)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deadline risk: %w", err)
+		}
+		file.Deadline = deadline.Format("2006-01-02 15:04:05")
+
+		if res.ThroughputPerDay > 0 {
+			finish := now.Add(time.Duration(float64(file.BacklogAhead) / res.ThroughputPerDay * float64(24*time.Hour)))
+			formatted := finish.Format("2006-01-02 15:04:05")
+			file.EstimatedFinish = &formatted
+			file.AtRisk = finish.After(deadline)
+		} else {
+			file.AtRisk = file.RemainingSegments > 0 || now.After(deadline)
+		}
+
+		if file.AtRisk {
+			res.AtRisk++
+		} else if atRiskOnly {
+			continue
+		}
+		res.Files = append(res.Files, file)
+	}
+
+	return &res, rows.Err()
+}
+
+// Reopen sends a finished or failed audio file, or some of its segments, back into
+// the transcription pipeline and records why in the file's event log. Without a
+// user to assign it to, the file goes back to the pool. It returns
+// entity.ErrSegmentsNotInFile when a picked segment is not one of the file's.
+func (r *AudioFileRepo) Reopen(ctx context.Context, req *entity.ReopenAudioFile) (*entity.ReopenAudioFileResult, error) {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var fromUser *string
+	query := `SELECT user_id::text FROM audio_files WHERE id = $1 AND deleted_at = 0 FOR UPDATE`
+	err = tr.QueryRow(ctx, query, req.AudioId).Scan(&fromUser)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, err
+	}
+
+	query = `
+	SELECT s.id
+	FROM audio_file_segments s
+	JOIN transcripts t ON t.segment_id = s.id AND t.deleted_at = 0
+	WHERE s.audio_id = $1 AND s.deleted_at = 0 AND (COALESCE(cardinality($2::int[]), 0) = 0 OR s.id = ANY($2))
+	ORDER BY s.id
+	`
+	rows, err := tr.Query(ctx, query, req.AudioId, req.SegmentIds)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get audio file segments: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to scan audio file segment: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to iterate over audio file segments: %w", err)
+	}
+
+	if len(ids) == 0 || (len(req.SegmentIds) > 0 && len(ids) != len(req.SegmentIds)) {
+		tr.Rollback(ctx)
+		return nil, entity.ErrSegmentsNotInFile
+	}
+
+	err = reopenTranscripts(ctx, tr, ids, req.ActorId, req.Reason)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, err
+	}
+
+	var toUser *string
+	if req.UserId != "" {
+		toUser = &req.UserId
+		query = `UPDATE audio_files SET status = 'processing', user_id = $2, updated_at = now() WHERE id = $1`
+		_, err = tr.Exec(ctx, query, req.AudioId, req.UserId)
+	} else {
+		query = `UPDATE audio_files SET status = 'unassigned', user_id = NULL, updated_at = now() WHERE id = $1`
+		_, err = tr.Exec(ctx, query, req.AudioId)
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to assign audio file: %w", err)
+	}
+
+	query = `
+	INSERT INTO audio_file_events (audio_id, action, from_user_id, to_user_id, actor_id, reason, segment_ids)
+	VALUES ($1, 'reopen', $2, $3, $4, $5, $6)
+	`
+	_, err = tr.Exec(ctx, query, req.AudioId, fromUser, toUser, req.ActorId, req.Reason, ids)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to create audio file event: %w", err)
+	}
+
+	res := entity.ReopenAudioFileResult{AudioId: req.AudioId, Segments: len(ids)}
+	err = tr.QueryRow(ctx, `SELECT status FROM audio_files WHERE id = $1`, req.AudioId).Scan(&res.Status)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get audio file status: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Reassign moves unfinished audio files to another transcriber or, without a target
+// user, back to the pool. Every move is recorded in the file's event log.
+func (r *AudioFileRepo) Reassign(ctx context.Context, req *entity.ReassignAudioFiles) (*entity.ReassignAudioFilesResult, error) {
+	query := `
+	SELECT id, user_id::text
+	FROM audio_files
+	WHERE deleted_at = 0 AND status IN ('pending', 'processing', 'unassigned')
+	`
+
+	var conditions []string
+	var args []interface{}
+
+	if len(req.AudioIds) > 0 {
+		conditions = append(conditions, "id = ANY($"+strconv.Itoa(len(args)+1)+")")
+		args = append(args, req.AudioIds)
+	}
+	if req.FromUserId != "" {
+		conditions = append(conditions, "user_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.FromUserId)
+	}
+	if req.Status != "" {
+		conditions = append(conditions, "status = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Status)
+	}
+	if req.CreatedFrom != "" {
+		conditions = append(conditions, "created_at >= $"+strconv.Itoa(len(args)+1)+"::date")
+		args = append(args, req.CreatedFrom)
+	}
+	if req.CreatedTo != "" {
+		conditions = append(conditions, "created_at < $"+strconv.Itoa(len(args)+1)+"::date + 1")
+		args = append(args, req.CreatedTo)
+	}
+
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at"
+	if req.Limit > 0 {
+		query += " LIMIT $" + strconv.Itoa(len(args)+1)
+		args = append(args, req.Limit)
+	}
+	query += " FOR UPDATE"
+
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	rows, err := tr.Query(ctx, query, args...)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get audio files to reassign: %w", err)
+	}
+
+	res := entity.ReassignAudioFilesResult{AudioIds: []int{}}
+	fromUsers := []*string{}
+	for rows.Next() {
+		var id int
+		var fromUser *string
+		if err := rows.Scan(&id, &fromUser); err != nil {
+			rows.Close()
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to scan audio file: %w", err)
+		}
+		res.AudioIds = append(res.AudioIds, id)
+		fromUsers = append(fromUsers, fromUser)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to iterate over audio files: %w", err)
+	}
+
+	var toUser *string
+	if req.ToUserId != "" {
+		toUser = &req.ToUserId
+		query = `UPDATE audio_files SET status = 'processing', user_id = $2, updated_at = now() WHERE id = ANY($1)`
+		_, err = tr.Exec(ctx, query, res.AudioIds, req.ToUserId)
+	} else {
+		query = `UPDATE audio_files SET status = 'unassigned', user_id = NULL, updated_at = now() WHERE id = ANY($1)`
+		_, err = tr.Exec(ctx, query, res.AudioIds)
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to reassign audio files: %w", err)
+	}
+
+	query = `
+	INSERT INTO audio_file_events (audio_id, action, from_user_id, to_user_id, actor_id, reason)
+	VALUES ($1, 'reassign', $2, $3, $4, NULLIF($5, ''))
+	`
+	for i, id := range res.AudioIds {
+		_, err = tr.Exec(ctx, query, id, fromUsers[i], toUser, req.ActorId, req.Reason)
+		if err != nil {
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to create audio file event: %w", err)
+		}
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	res.Moved = len(res.AudioIds)
+	return &res, nil
+}
+
+func (r *AudioFileRepo) GetEvents(ctx context.Context, audioId int) (*entity.AudioFileEventList, error) {
+	query := `
+	SELECT
+		e.id,
+		e.audio_id,
+		e.action,
+		e.from_user_id::text,
+		fu.username,
+		e.to_user_id::text,
+		tu.username,
+		e.actor_id::text,
+		e.reason,
+		COALESCE(e.segment_ids, '{}'),
+		e.created_at
+	FROM audio_file_events e
+	LEFT JOIN users fu ON fu.id = e.from_user_id
+	LEFT JOIN users tu ON tu.id = e.to_user_id
+	WHERE e.audio_id = $1
+	ORDER BY e.id
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, audioId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file events: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.AudioFileEventList{Events: []entity.AudioFileEvent{}}
+	for rows.Next() {
+		var createdAt time.Time
+		event := entity.AudioFileEvent{}
+		err := rows.Scan(
+			&event.Id,
+			&event.AudioId,
+			&event.Action,
+			&event.FromUserId,
+			&event.FromUsername,
+			&event.ToUserId,
+			&event.ToUsername,
+			&event.ActorId,
+			&event.Reason,
+			&event.SegmentIds,
+			&createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audio file event: %w", err)
+		}
+		event.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		res.Events = append(res.Events, event)
+	}
+
+	return &res, rows.Err()
+}
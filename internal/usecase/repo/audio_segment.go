@@ -3,31 +3,36 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/mirjalilova/voice_transcribe/config"
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/cache"
 	"github.com/mirjalilova/voice_transcribe/pkg/logger"
 	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
 )
 
 type AudioSegmentRepo struct {
-	pg     *postgres.Postgres
-	config *config.Config
-	logger *logger.Logger
+	pg         *postgres.Postgres
+	config     *config.Config
+	logger     *logger.Logger
+	statsCache *cache.Cache[*entity.Statistics]
 }
 
 // New -.
 func NewAudioSegmentRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *AudioSegmentRepo {
 	return &AudioSegmentRepo{
-		pg:     pg,
-		config: config,
-		logger: logger,
+		pg:         pg,
+		config:     config,
+		logger:     logger,
+		statsCache: cache.New[*entity.Statistics](config.Stats.CacheTTL),
 	}
 }
 
@@ -37,12 +42,19 @@ func (r *AudioSegmentRepo) Create(ctx context.Context, req *entity.CreateAudioSe
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			_ = tr.Rollback(ctx)
-		}
-	}()
 
+	if err := insertSegment(ctx, tr, req); err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertSegment adds a segment with its empty transcript within tr.
+func insertSegment(ctx context.Context, tr pgx.Tx, req *entity.CreateAudioSegment) error {
 	query := `
 	INSERT INTO audio_file_segments (audio_id, filename, duration)
 	VALUES ($1, $2, $3)
@@ -51,9 +63,8 @@ func (r *AudioSegmentRepo) Create(ctx context.Context, req *entity.CreateAudioSe
 
 	var id int
 	row := tr.QueryRow(ctx, query, req.AudioId, req.FileName, req.Duration)
-	err = row.Scan(&id)
+	err := row.Scan(&id)
 	if err != nil {
-		tr.Rollback(ctx)
 		return fmt.Errorf("failed to create audio segment: %w", err)
 	}
 
@@ -64,12 +75,8 @@ func (r *AudioSegmentRepo) Create(ctx context.Context, req *entity.CreateAudioSe
 
 	_, err = tr.Exec(ctx, query, id, req.TranscribeOption)
 	if err != nil {
-		tr.Rollback(ctx)
 		return fmt.Errorf("failed to create transcript: %w", err)
 	}
-	if err := tr.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
 	return nil
 }
 
@@ -113,20 +120,28 @@ func (r *AudioSegmentRepo) GetList(ctx context.Context, req *entity.GetAudioSegm
 	query := `
 	SELECT id FROM audio_files
 	WHERE status = 'processing' AND deleted_at = 0 AND user_id = $1
-	ORDER BY created_at ASC
+	ORDER BY priority DESC, deadline ASC NULLS LAST, created_at ASC
 	LIMIT 1`
 
 	fmt.Println("UserID:", req.UserID)
 	err := r.pg.Pool.QueryRow(ctx, query, req.UserID).Scan(&audio_id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
+			// Only files whose required skills the transcriber has, most urgent first.
 			query = `
-			SELECT id FROM audio_files
-			WHERE (status = 'pending' OR status = 'unassigned') AND deleted_at = 0
-			ORDER BY created_at ASC
+			SELECT a.id FROM audio_files a
+			WHERE (a.status = 'pending' OR a.status = 'unassigned') AND a.deleted_at = 0
+				AND NOT EXISTS (
+					SELECT 1
+					FROM audio_file_skills fs
+					JOIN skills sk ON sk.id = fs.skill_id AND sk.deleted_at = 0
+					WHERE fs.audio_id = a.id
+						AND NOT EXISTS (SELECT 1 FROM user_skills us WHERE us.user_id = $1 AND us.skill_id = fs.skill_id)
+				)
+			ORDER BY a.priority DESC, a.deadline ASC NULLS LAST, a.created_at ASC
 			LIMIT 1`
 
-			err = r.pg.Pool.QueryRow(ctx, query).Scan(&audio_id)
+			err = r.pg.Pool.QueryRow(ctx, query, req.UserID).Scan(&audio_id)
 			if err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
 					return nil, fmt.Errorf("no pending audio files available")
@@ -240,6 +255,16 @@ func (r *AudioSegmentRepo) GetList(ctx context.Context, req *entity.GetAudioSegm
 	return &audioSegments, nil
 }
 
+// workDayStart returns the start of the day of now in the work timezone tz.
+func workDayStart(tz string, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid work timezone: %w", err)
+	}
+	now = now.In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc), nil
+}
+
 func (r *AudioSegmentRepo) Delete(ctx context.Context, id int) error {
 	query := `
 		UPDATE audio_file_segments
@@ -254,82 +279,55 @@ func (r *AudioSegmentRepo) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+// GetTranscriptPercent reads the file and segment counts from the counters the
+// triggers on audio_files and transcripts keep current. Each counter is spread
+// over shards, so the shards of a name are summed.
 func (r *AudioSegmentRepo) GetTranscriptPercent(ctx context.Context) (*entity.TranscriptPersent, error) {
-	// query := `SELECT * FROM calculate_transcription_percentage()`
-
 	res := entity.TranscriptPersent{}
 
-	query := "select count(id) from audio_files where deleted_at = 0"
-	err := r.pg.Pool.QueryRow(ctx, query).Scan(&res.TotalAudioFiles)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan total audio files: %w", err)
-	}
-
-	query = "select count(id) from audio_files where status = 'processing' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.ProcessingAudio)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan total processing audio files: %w", err)
-	}
-
-	query = "select count(id) from audio_files where status = 'pending' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.PendingAudioFiles)
+	query := `SELECT name, SUM(value)::BIGINT FROM stat_counters GROUP BY name`
+	rows, err := r.pg.Pool.Query(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan total pendning audio files: %w", err)
+		return nil, fmt.Errorf("failed to get stat counters: %w", err)
 	}
 
-	query = "select count(id) from audio_files where status = 'done' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.DoneAudioFiles)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan total done audio files: %w", err)
+	counters := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var value int
+		if err := rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stat counter: %w", err)
+		}
+		counters[name] = value
 	}
-
-	query = "select count(id) from audio_files where status = 'error' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.ErrorAudioFiles)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan total error audio files: %w", err)
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over stat counters: %w", err)
 	}
 
-	query = "select count(id) from transcripts where deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.TotalSegments)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan total  chunks: %w", err)
-	}
+	res.TotalAudioFiles = counters["audio_files"]
+	res.ProcessingAudio = counters["audio_files:processing"]
+	res.PendingAudioFiles = counters["audio_files:pending"]
+	res.DoneAudioFiles = counters["audio_files:done"]
+	res.ErrorAudioFiles = counters["audio_files:error"]
+	res.UnassignedAudio = counters["audio_files:unassigned"]
+	res.TotalSegments = counters["transcripts"]
+	res.CompletedSegments = counters["transcripts:done"]
+	res.ReportSegments = counters["transcripts:invalid"]
 
-	query = "select count(id) from transcripts where status = 'done' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.CompletedSegments)
+	today, err := workDayStart(r.config.Work.Timezone, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan total done chunks: %w", err)
+		return nil, err
 	}
 
-	query = "select count(id) from transcripts where status = 'invalid' and deleted_at = 0"
-	err = r.pg.Pool.QueryRow(ctx, query).Scan(&res.ReportSegments)
+	var refreshedAt time.Time
+	query = "select count(id), now()::timestamp from audio_file_events where action = 'reassign' and created_at::timestamptz >= $1"
+	err = r.pg.Pool.QueryRow(ctx, query, today).Scan(&res.ReassignedToday, &refreshedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan total report chunks: %w", err)
+		return nil, fmt.Errorf("failed to scan reassigned audio files: %w", err)
 	}
-
-	// rows, err := r.pg.Pool.Query(ctx, query)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// defer rows.Close()
-
-	// res := []entity.TranscriptPersent{}
-	// for rows.Next() {
-	// 	reps := entity.TranscriptPersent{}
-	// 	err := rows.Scan(
-	// 		&reps.AudioFileId,
-	// 		&reps.Filename,
-	// 		&reps.TotalSegments,
-	// 		&reps.CompletedSegments,
-	// 		&reps.Percent,
-	// 	)
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to scan calculate_transcription_percentage: %w", err)
-	// 	}
-
-	// 	reps.Percent = math.Round(reps.Percent*100) / 100
-	// 	res = append(res, reps)
-	// }
+	res.RefreshedAt = refreshedAt.Format("2006-01-02 15:04:05")
 
 	return &res, nil
 }
@@ -362,25 +360,28 @@ func (r *AudioSegmentRepo) GetUserTranscriptStatictics(ctx context.Context, user
 		return nil, fmt.Errorf("failed to scan user transcript statistics: %w", err)
 	}
 
+	query = `
+	SELECT
+		COALESCE(SUM(active_seconds) FILTER (WHERE day = (now() AT TIME ZONE $2)::date), 0) / 60.0,
+		COALESCE(SUM(active_seconds), 0) / 60.0
+	FROM user_activity_daily
+	WHERE user_id = $1 AND day >= date_trunc('week', now() AT TIME ZONE $2)::date
+	`
+	err = r.pg.Pool.QueryRow(ctx, query, user_id, r.config.Work.Timezone).Scan(&res.ActiveMinutesToday, &res.ActiveMinutesWeek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user active minutes: %w", err)
+	}
+
 	return &res, nil
 }
 
-func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter, user_id string, report, ruBool bool) (*entity.DatasetViewerListResponse, error) {
+func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter, user_id, language string, report, includeFlagged bool) (*entity.DatasetViewerListResponse, error) {
 	baseQuery := `
 		FROM audio_files af
 		JOIN audio_file_segments afs ON af.id = afs.audio_id
 		JOIN transcripts t ON afs.id = t.segment_id
 		LEFT JOIN users u ON af.user_id = u.id
-		JOIN (
-			SELECT
-				af.id AS audio_id,
-				STRING_AGG(t2.transcribe_text, ' ' ORDER BY t2.id) AS all_transcripts
-			FROM audio_files af
-			JOIN audio_file_segments afs2 ON af.id = afs2.audio_id
-			JOIN transcripts t2 ON afs2.id = t2.segment_id
-			WHERE t2.deleted_at = 0
-			GROUP BY af.id
-		) aggregated_segments ON af.id = aggregated_segments.audio_id
+		LEFT JOIN audio_file_texts aft ON aft.audio_id = af.id
 		WHERE
 			af.deleted_at = 0
 			AND afs.deleted_at = 0
@@ -398,12 +399,16 @@ func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter
 		argIdx++
 	}
 
-	if ruBool {
-		conditions = append(conditions, fmt.Sprintf("t.transcribe_text ILIKE $%d", argIdx))
-		args = append(args, "%(ru:%")
+	if language != "" {
+		conditions = append(conditions, anyLanguageCondition(argIdx))
+		args = append(args, language)
 		argIdx++
 	}
 
+	if !includeFlagged {
+		conditions = append(conditions, qualityPassedCondition)
+	}
+
 	statusCondition := "t.status = 'done'"
 	if report {
 		statusCondition = "t.status = 'invalid'"
@@ -431,12 +436,14 @@ func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter
 			t.transcribe_text AS chunk_text,
 			LAG(t.transcribe_text) OVER (PARTITION BY af.id ORDER BY afs.id) AS previous_text,
 			LEAD(t.transcribe_text) OVER (PARTITION BY af.id ORDER BY afs.id) AS next_text,
-			aggregated_segments.all_transcripts,
+			aft.all_transcripts,
 			t.report_text,
 			u.username,
 			u.id,
-			EXTRACT(EPOCH FROM t.updated_at - t.viewed_at) / 60 AS minutes_spent,
-			t.emotion
+			NULLIF(t.active_seconds, 0) / 60.0 AS minutes_spent,
+			t.emotion,
+			t.language,
+			t.language_spans
 	` + baseQuery + `
 		ORDER BY af.id, afs.id
 		LIMIT $` + fmt.Sprint(argIdx) + ` OFFSET $` + fmt.Sprint(argIdx+1)
@@ -466,6 +473,8 @@ func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter
 			&reps.TranscriberID,
 			&reps.MinutesSpent,
 			&reps.Emotion,
+			&reps.Language,
+			&reps.LanguageSpans,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dataset viewer: %w", err)
@@ -476,13 +485,135 @@ func (r *AudioSegmentRepo) DatasetViewer(ctx context.Context, req *entity.Filter
 		return nil, fmt.Errorf("failed to iterate over dataset viewer rows: %w", err)
 	}
 
+	refreshedAt, err := r.audioFileTextsRefreshedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &entity.DatasetViewerListResponse{
-		Total: total,
-		Data:  res,
+		Total:       total,
+		Data:        res,
+		RefreshedAt: refreshedAt.Format("2006-01-02 15:04:05"),
 	}, nil
 }
 
+const audioFileTextsSnapshot = "audio_file_texts"
+
+// audioFileTextsRefreshedAt returns when the file texts were last rebuilt,
+// rebuilding them if they never were.
+func (r *AudioSegmentRepo) audioFileTextsRefreshedAt(ctx context.Context) (time.Time, error) {
+	var refreshedAt time.Time
+	query := `SELECT refreshed_at FROM stats_snapshots WHERE name = $1`
+	err := r.pg.Pool.QueryRow(ctx, query, audioFileTextsSnapshot).Scan(&refreshedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.RefreshAudioFileTexts(ctx)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get audio file texts snapshot: %w", err)
+	}
+
+	return refreshedAt, nil
+}
+
+// RefreshAudioFileTexts rebuilds the text of every file the transcript
+// triggers marked dirty and records when it did so. Files locked by a
+// concurrent refresh are left for the next run.
+func (r *AudioSegmentRepo) RefreshAudioFileTexts(ctx context.Context) (time.Time, error) {
+	query := `
+	WITH pending AS (
+		SELECT audio_id FROM audio_file_texts
+		WHERE dirty
+		FOR UPDATE SKIP LOCKED
+	),
+	refreshed AS (
+		UPDATE audio_file_texts aft
+		SET all_transcripts = (
+				SELECT STRING_AGG(t.transcribe_text, ' ' ORDER BY t.id)
+				FROM audio_file_segments s
+				JOIN transcripts t ON t.segment_id = s.id
+				WHERE s.audio_id = aft.audio_id AND t.deleted_at = 0
+			),
+			dirty = FALSE,
+			updated_at = now()
+		FROM pending p
+		WHERE aft.audio_id = p.audio_id
+		RETURNING aft.audio_id
+	)
+	INSERT INTO stats_snapshots (name, payload, refreshed_at)
+	SELECT $1, jsonb_build_object('refreshed', COUNT(*)), now() FROM refreshed
+	ON CONFLICT (name) DO UPDATE SET payload = EXCLUDED.payload, refreshed_at = EXCLUDED.refreshed_at
+	RETURNING refreshed_at
+	`
+
+	var refreshedAt time.Time
+	err := r.pg.Pool.QueryRow(ctx, query, audioFileTextsSnapshot).Scan(&refreshedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to refresh audio file texts: %w", err)
+	}
+
+	return refreshedAt, nil
+}
+
+const statisticsSnapshot = "statistics"
+
+// GetStatistics returns the statistics from the in-memory cache, or else from the
+// snapshot the background job keeps, computing the snapshot if there is none yet.
 func (r *AudioSegmentRepo) GetStatistics(ctx context.Context) (*entity.Statistics, error) {
+	if stats, ok := r.statsCache.Get(statisticsSnapshot); ok {
+		return stats, nil
+	}
+
+	var payload []byte
+	var refreshedAt time.Time
+	query := `SELECT payload, refreshed_at FROM stats_snapshots WHERE name = $1`
+	err := r.pg.Pool.QueryRow(ctx, query, statisticsSnapshot).Scan(&payload, &refreshedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.RefreshStatistics(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statistics snapshot: %w", err)
+	}
+
+	stats := &entity.Statistics{}
+	if err := json.Unmarshal(payload, stats); err != nil {
+		return nil, fmt.Errorf("failed to decode statistics snapshot: %w", err)
+	}
+	stats.RefreshedAt = refreshedAt.Format("2006-01-02 15:04:05")
+
+	r.statsCache.Set(statisticsSnapshot, stats)
+	return stats, nil
+}
+
+// RefreshStatistics recomputes the statistics snapshot.
+func (r *AudioSegmentRepo) RefreshStatistics(ctx context.Context) (*entity.Statistics, error) {
+	stats, err := r.computeStatistics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statistics snapshot: %w", err)
+	}
+
+	var refreshedAt time.Time
+	query := `
+	INSERT INTO stats_snapshots (name, payload, refreshed_at)
+	VALUES ($1, $2, now())
+	ON CONFLICT (name) DO UPDATE SET payload = EXCLUDED.payload, refreshed_at = EXCLUDED.refreshed_at
+	RETURNING refreshed_at
+	`
+	err = r.pg.Pool.QueryRow(ctx, query, statisticsSnapshot, payload).Scan(&refreshedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save statistics snapshot: %w", err)
+	}
+	stats.RefreshedAt = refreshedAt.Format("2006-01-02 15:04:05")
+
+	r.statsCache.Set(statisticsSnapshot, stats)
+	return stats, nil
+}
+
+func (r *AudioSegmentRepo) computeStatistics(ctx context.Context) (*entity.Statistics, error) {
 	query := `
 	WITH transcribed AS (
 		SELECT 
@@ -524,81 +655,11 @@ func (r *AudioSegmentRepo) GetStatistics(ctx context.Context) (*entity.Statistic
 		transcriberStats[username]++
 
 		if duration.Valid {
-			dur := duration.Float64
-			var bucket string
-			switch {
-			case dur <= 1.0:
-				bucket = "0-1s"
-			case dur <= 2.0:
-				bucket = "1-2s"
-			case dur <= 3.0:
-				bucket = "2-3s"
-			case dur <= 4.0:
-				bucket = "3-4s"
-			case dur <= 5.0:
-				bucket = "4-5s"
-			case dur <= 6.0:
-				bucket = "5-6s"
-			case dur <= 7.0:
-				bucket = "6-7s"
-			case dur <= 8.0:
-				bucket = "7-8s"
-			case dur <= 9.0:
-				bucket = "8-9s"
-			case dur <= 10.0:
-				bucket = "9-10s"
-			case dur <= 11.0:
-				bucket = "10-11s"
-			case dur <= 12.0:
-				bucket = "11-12s"
-			case dur <= 13.0:
-				bucket = "12-13s"
-			case dur <= 14.0:
-				bucket = "13-14s"
-			case dur <= 15.0:
-				bucket = "14-15s"
-			case dur <= 16.0:
-				bucket = "15-16s"
-			case dur <= 17.0:
-				bucket = "16-17s"
-			case dur <= 18.0:
-				bucket = "17-18s"
-			case dur <= 19.0:
-				bucket = "18-19s"
-			case dur <= 20.0:
-				bucket = "19-20s"
-			case dur <= 21.0:
-				bucket = "20-21s"
-			case dur <= 22.0:
-				bucket = "21-22s"
-			default:
-				bucket = "22s+"
-			}
-			durationStats[bucket]++
+			durationStats[durationBucket(r.config.Stats.DurationEdges, duration.Float64)]++
 		}
 
 		bucketByLength := func(text string) string {
-			length := len(text)
-			switch {
-			case length <= 15:
-				return "0-15"
-			case length <= 30:
-				return "16-30"
-			case length <= 45:
-				return "31-45"
-			case length <= 60:
-				return "46-60"
-			case length <= 75:
-				return "61-75"
-			case length <= 90:
-				return "76-90"
-			case length <= 105:
-				return "91-105"
-			case length <= 120:
-				return "106-120"
-			default:
-				return "121+"
-			}
+			return lengthBucket(r.config.Stats.TextEdges, utf8.RuneCountInString(text))
 		}
 
 		if transcribeText.Valid {
@@ -668,15 +729,48 @@ func (r *AudioSegmentRepo) GetStatistics(ctx context.Context) (*entity.Statistic
 // }
 
 func (r *AudioSegmentRepo) GetAudioTranscriptStats(ctx context.Context, fromDate, toDate time.Time) (*[]entity.TranscriptStatictics, error) {
-	query := `SELECT * FROM get_audio_transcript_stats_by_range($1, $2)`
+	query := `
+	WITH days AS (
+		SELECT generate_series($1::date, $2::date, INTERVAL '1 day')::date AS day
+	),
+	seg AS (
+		SELECT
+			(updated_at::timestamptz AT TIME ZONE $3)::date AS day,
+			COUNT(*) FILTER (WHERE status = 'done') AS done,
+			COUNT(*) FILTER (WHERE status = 'invalid') AS invalid
+		FROM transcripts
+		WHERE deleted_at = 0
+			AND updated_at >= ($1::date::timestamp AT TIME ZONE $3)::timestamp
+			AND updated_at < (($2::date + 1)::timestamp AT TIME ZONE $3)::timestamp
+		GROUP BY 1
+	),
+	af AS (
+		SELECT
+			(updated_at::timestamptz AT TIME ZONE $3)::date AS day,
+			COUNT(*) FILTER (WHERE status = 'done') AS done,
+			COUNT(*) FILTER (WHERE status = 'error') AS error
+		FROM audio_files
+		WHERE deleted_at = 0
+			AND updated_at >= ($1::date::timestamp AT TIME ZONE $3)::timestamp
+			AND updated_at < (($2::date + 1)::timestamp AT TIME ZONE $3)::timestamp
+		GROUP BY 1
+	)
+	SELECT
+		d.day,
+		COALESCE(seg.done, 0),
+		COALESCE(seg.invalid, 0),
+		COALESCE(af.done, 0),
+		COALESCE(af.error, 0)
+	FROM days d
+	LEFT JOIN seg ON seg.day = d.day
+	LEFT JOIN af ON af.day = d.day
+	ORDER BY d.day
+	`
 
 	var stats []entity.TranscriptStatictics
 
-	rows, err := r.pg.Pool.Query(ctx, query, fromDate, toDate)
+	rows, err := r.pg.Pool.Query(ctx, query, fromDate, toDate, r.config.Work.Timezone)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no daily audio transcript stats found: %w", err)
-		}
 		return nil, fmt.Errorf("failed to get daily audio transcript stats: %w", err)
 	}
 	defer rows.Close()
@@ -691,7 +785,6 @@ func (r *AudioSegmentRepo) GetAudioTranscriptStats(ctx context.Context, fromDate
 			&stat.InvalidChunks,
 			&stat.DoneAudioFiles,
 			&stat.ErrorAudioFiles,
-			&stat.ActiveOperators,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan audio transcript stats: %w", err)
@@ -700,31 +793,20 @@ func (r *AudioSegmentRepo) GetAudioTranscriptStats(ctx context.Context, fromDate
 		stat.StateDate = stateDate.Format("2006-01-02")
 		stats = append(stats, stat)
 	}
-
-	query = "SELECT * FROM get_daily_active_blocks_per_user($1, $2)"
-	rows, err = r.pg.Pool.Query(ctx, query, fromDate, toDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get daily active operators: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over audio transcript stats: %w", err)
 	}
-	defer rows.Close()
 
-	blocksMap := make(map[string][]entity.DailyActiveBlock)
-
-	for rows.Next() {
-		var block entity.DailyActiveBlock
-		var statDate time.Time
-
-		if err := rows.Scan(&statDate, &block.OperatorID, &block.Username, &block.ActiveBlocks); err != nil {
-			return nil, fmt.Errorf("failed to scan daily active operator: %w", err)
-		}
-
-		block.StatDate = statDate.Format("2006-01-02")
-		blocksMap[block.StatDate] = append(blocksMap[block.StatDate], block)
+	blocksMap, totals, err := r.getActiveBlocks(ctx, fromDate, toDate)
+	if err != nil {
+		return nil, err
 	}
 
+	cfg := r.productivityConfig()
 	for i, stat := range stats {
 		if blocks, ok := blocksMap[stat.StateDate]; ok {
 			stats[i].ActiveOperatorsBlock = blocks
+			stats[i].ActiveOperators = float32(cfg.Score(totals[stat.StateDate]))
 		}
 	}
 
@@ -800,3 +882,30 @@ func (r *AudioSegmentRepo) GetHourlyTranscripts(ctx context.Context, userId stri
 
 	return &resp, nil
 }
+
+// durationBucket maps a segment duration in seconds to its statistics bucket, the
+// first of the ascending edges it does not exceed.
+func durationBucket(edges []float64, dur float64) string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	from := 0.0
+	for _, edge := range edges {
+		if dur <= edge {
+			return format(from) + "-" + format(edge) + "s"
+		}
+		from = edge
+	}
+	return format(from) + "s+"
+}
+
+// lengthBucket maps a text length in characters to its statistics bucket, the
+// first of the ascending edges it does not exceed.
+func lengthBucket(edges []int, length int) string {
+	from := 0
+	for _, edge := range edges {
+		if length <= edge {
+			return strconv.Itoa(from) + "-" + strconv.Itoa(edge)
+		}
+		from = edge + 1
+	}
+	return strconv.Itoa(from) + "+"
+}
@@ -0,0 +1,347 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+	"github.com/mirjalilova/voice_transcribe/pkg/wer"
+)
+
+type BenchmarkRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewBenchmarkRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *BenchmarkRepo {
+	return &BenchmarkRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *BenchmarkRepo) CreateModel(ctx context.Context, req *entity.CreateAsrModel) (*int, error) {
+	query := `
+	INSERT INTO asr_models (name, description, created_by) VALUES ($1, NULLIF($2, ''), $3) RETURNING id`
+
+	var id int
+	err := r.pg.Pool.QueryRow(ctx, query, req.Name, req.Description, req.CreatedBy).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asr model: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *BenchmarkRepo) GetModels(ctx context.Context, req *entity.Filter) (*entity.AsrModelList, error) {
+	query := `
+	SELECT
+		COUNT(m.id) OVER () AS total_count,
+		m.id,
+		m.name,
+		m.description,
+		(SELECT COUNT(*) FROM asr_hypotheses h WHERE h.model_id = m.id) AS segments,
+		m.created_at
+	FROM asr_models m
+	WHERE m.deleted_at = 0
+	ORDER BY m.created_at DESC OFFSET $1 LIMIT $2
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, req.Offset, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asr model list: %w", err)
+	}
+	defer rows.Close()
+
+	models := entity.AsrModelList{Models: []entity.AsrModel{}}
+	for rows.Next() {
+		var count int
+		var createdAt time.Time
+		model := entity.AsrModel{}
+		err := rows.Scan(&count, &model.Id, &model.Name, &model.Description, &model.Segments, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan asr model: %w", err)
+		}
+		model.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		models.Models = append(models.Models, model)
+		models.Count = count
+	}
+
+	return &models, nil
+}
+
+// hypothesisBatchSize is how many hypotheses are scored and stored at once.
+const hypothesisBatchSize = 1000
+
+// SaveHypotheses scores the hypotheses against the approved transcripts and stores
+// them, replacing any earlier hypothesis of the same model for the same segment. A
+// segment without an approved transcript loses its earlier hypothesis, so no stale
+// score is left behind. All hypotheses are saved in one transaction, or none. It
+// returns pgx.ErrNoRows for an unknown or deleted model.
+func (r *BenchmarkRepo) SaveHypotheses(ctx context.Context, modelId int, hyps []entity.AsrHypothesis) (*entity.AsrUploadResult, error) {
+	res := entity.AsrUploadResult{Received: len(hyps)}
+
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// lock the model so it cannot be deleted while its hypotheses are saved
+	var id int
+	err = tr.QueryRow(ctx, `SELECT id FROM asr_models WHERE id = $1 AND deleted_at = 0 FOR SHARE`, modelId).Scan(&id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, err
+	}
+
+	for start := 0; start < len(hyps); start += hypothesisBatchSize {
+		end := start + hypothesisBatchSize
+		if end > len(hyps) {
+			end = len(hyps)
+		}
+		if err := saveHypothesisBatch(ctx, tr, modelId, hyps[start:end], &res); err != nil {
+			tr.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &res, nil
+}
+
+func saveHypothesisBatch(ctx context.Context, tr pgx.Tx, modelId int, hyps []entity.AsrHypothesis, res *entity.AsrUploadResult) error {
+	ids := make([]int, 0, len(hyps))
+	for _, h := range hyps {
+		ids = append(ids, h.SegmentId)
+	}
+
+	query := `
+	SELECT t.segment_id, t.transcribe_text
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	WHERE t.segment_id = ANY($1) AND t.status = 'done' AND t.deleted_at = 0 AND s.deleted_at = 0
+	`
+
+	rows, err := tr.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get reference transcripts: %w", err)
+	}
+
+	refs := make(map[int]string, len(ids))
+	for rows.Next() {
+		var segmentId int
+		var text *string
+		if err := rows.Scan(&segmentId, &text); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reference transcript: %w", err)
+		}
+		if text != nil {
+			refs[segmentId] = *text
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over reference transcripts: %w", err)
+	}
+
+	upsert := `
+	INSERT INTO asr_hypotheses (model_id, segment_id, hypothesis, word_errors, ref_words, char_errors, ref_chars, reference_hash)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, md5($8))
+	ON CONFLICT (model_id, segment_id) DO UPDATE SET
+		hypothesis = EXCLUDED.hypothesis,
+		word_errors = EXCLUDED.word_errors,
+		ref_words = EXCLUDED.ref_words,
+		char_errors = EXCLUDED.char_errors,
+		ref_chars = EXCLUDED.ref_chars,
+		reference_hash = EXCLUDED.reference_hash,
+		updated_at = now()
+	`
+	remove := `DELETE FROM asr_hypotheses WHERE model_id = $1 AND segment_id = $2`
+
+	batch := &pgx.Batch{}
+	for _, h := range hyps {
+		ref, ok := refs[h.SegmentId]
+		if !ok {
+			batch.Queue(remove, modelId, h.SegmentId)
+			res.Skipped++
+			continue
+		}
+
+		score := wer.Compute(ref, h.Hypothesis)
+		batch.Queue(upsert, modelId, h.SegmentId, h.Hypothesis, score.WordErrors, score.RefWords, score.CharErrors, score.RefChars, ref)
+		res.Scored++
+	}
+
+	br := tr.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return fmt.Errorf("failed to save hypothesis: %w", err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("failed to save hypotheses: %w", err)
+	}
+
+	return nil
+}
+
+// GetLeaderboard ranks the models by overall WER and breaks their scores down by
+// duration bucket, emotion and language flag. Hypotheses whose transcript is no
+// longer approved, or was edited after they were scored, are left out.
+func (r *BenchmarkRepo) GetLeaderboard(ctx context.Context) (*entity.AsrLeaderboard, error) {
+	query := `
+	SELECT
+		m.id,
+		m.name,
+		s.duration,
+		COALESCE(NULLIF(t.emotion, ''), 'unknown') AS emotion,
+		(t.language = 'ru' OR t.language_spans @> '[{"language": "ru"}]') AS ru,
+		h.word_errors,
+		h.ref_words,
+		h.char_errors,
+		h.ref_chars
+	FROM asr_hypotheses h
+	JOIN asr_models m ON m.id = h.model_id
+	JOIN audio_file_segments s ON s.id = h.segment_id
+	JOIN transcripts t ON t.segment_id = h.segment_id
+	WHERE m.deleted_at = 0 AND s.deleted_at = 0 AND t.deleted_at = 0 AND t.status = 'done'
+		AND h.reference_hash = md5(t.transcribe_text)
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asr hypotheses: %w", err)
+	}
+	defer rows.Close()
+
+	type modelScores struct {
+		entry    entity.AsrLeaderboardEntry
+		duration map[string]*entity.AsrScore
+		emotion  map[string]*entity.AsrScore
+		language map[string]*entity.AsrScore
+	}
+
+	models := make(map[int]*modelScores)
+	for rows.Next() {
+		var (
+			modelId   int
+			modelName string
+			duration  *float64
+			emotion   string
+			ru        bool
+			score     entity.AsrScore
+		)
+		err := rows.Scan(&modelId, &modelName, &duration, &emotion, &ru,
+			&score.WordErrors, &score.RefWords, &score.CharErrors, &score.RefChars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan asr hypothesis: %w", err)
+		}
+		score.Segments = 1
+
+		m, ok := models[modelId]
+		if !ok {
+			m = &modelScores{
+				entry:    entity.AsrLeaderboardEntry{ModelId: modelId, ModelName: modelName},
+				duration: make(map[string]*entity.AsrScore),
+				emotion:  make(map[string]*entity.AsrScore),
+				language: make(map[string]*entity.AsrScore),
+			}
+			models[modelId] = m
+		}
+
+		addAsrScore(&m.entry.Overall, score)
+		if duration != nil {
+			addAsrScoreTo(m.duration, durationBucket(r.config.Stats.DurationEdges, *duration), score)
+		}
+		addAsrScoreTo(m.emotion, emotion, score)
+		if ru {
+			addAsrScoreTo(m.language, "ru", score)
+		} else {
+			addAsrScoreTo(m.language, "uz", score)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over asr hypotheses: %w", err)
+	}
+
+	res := entity.AsrLeaderboard{Models: []entity.AsrLeaderboardEntry{}}
+	for _, m := range models {
+		finishAsrScore(&m.entry.Overall)
+		m.entry.Duration = asrBuckets(m.duration, func(a, b string) bool {
+			return durationBucketStart(a) < durationBucketStart(b)
+		})
+		m.entry.Emotion = asrBuckets(m.emotion, func(a, b string) bool { return a < b })
+		m.entry.Language = asrBuckets(m.language, func(a, b string) bool { return a < b })
+		res.Models = append(res.Models, m.entry)
+	}
+
+	sort.Slice(res.Models, func(i, j int) bool {
+		if res.Models[i].Overall.WER != res.Models[j].Overall.WER {
+			return res.Models[i].Overall.WER < res.Models[j].Overall.WER
+		}
+		return res.Models[i].ModelName < res.Models[j].ModelName
+	})
+	for i := range res.Models {
+		res.Models[i].Rank = i + 1
+	}
+
+	return &res, nil
+}
+
+func addAsrScore(dst *entity.AsrScore, src entity.AsrScore) {
+	dst.Segments += src.Segments
+	dst.WordErrors += src.WordErrors
+	dst.RefWords += src.RefWords
+	dst.CharErrors += src.CharErrors
+	dst.RefChars += src.RefChars
+}
+
+func addAsrScoreTo(scores map[string]*entity.AsrScore, bucket string, src entity.AsrScore) {
+	dst, ok := scores[bucket]
+	if !ok {
+		dst = &entity.AsrScore{}
+		scores[bucket] = dst
+	}
+	addAsrScore(dst, src)
+}
+
+// finishAsrScore derives the corpus-level error rates from the summed edit counts.
+func finishAsrScore(s *entity.AsrScore) {
+	if s.RefWords > 0 {
+		s.WER = float64(s.WordErrors) / float64(s.RefWords)
+	}
+	if s.RefChars > 0 {
+		s.CER = float64(s.CharErrors) / float64(s.RefChars)
+	}
+}
+
+func asrBuckets(scores map[string]*entity.AsrScore, less func(a, b string) bool) []entity.AsrLeaderboardBucket {
+	res := make([]entity.AsrLeaderboardBucket, 0, len(scores))
+	for bucket, score := range scores {
+		finishAsrScore(score)
+		res = append(res, entity.AsrLeaderboardBucket{Bucket: bucket, Score: *score})
+	}
+	sort.Slice(res, func(i, j int) bool { return less(res[i].Bucket, res[j].Bucket) })
+	return res
+}
+
+// durationBucketStart returns the lower bound in seconds of a durationBucket label.
+func durationBucketStart(bucket string) float64 {
+	n, _ := strconv.ParseFloat(strings.TrimRight(strings.SplitN(bucket, "-", 2)[0], "s+"), 64)
+	return n
+}
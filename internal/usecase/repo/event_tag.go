@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+type EventTagRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewEventTagRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *EventTagRepo {
+	return &EventTagRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// tagAliases trims aliases and leaves out empty ones. It never returns nil, which
+// would be saved as NULL.
+func tagAliases(aliases []string) []string {
+	res := []string{}
+	for _, alias := range aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			res = append(res, alias)
+		}
+	}
+	return res
+}
+
+func (r *EventTagRepo) CreateTag(ctx context.Context, req *entity.CreateEventTag) (*int, error) {
+	query := `
+	INSERT INTO event_tags (code, tag, aliases, export_as, name_uz, name_ru, name_cy, sort_order)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id`
+
+	var id int
+	err := r.pg.Pool.QueryRow(ctx, query, req.Code, strings.TrimSpace(req.Tag), tagAliases(req.Aliases), req.ExportAs,
+		req.Name.Uz, req.Name.Ru, req.Name.Cy, req.SortOrder).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event tag: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *EventTagRepo) UpdateTag(ctx context.Context, req *entity.UpdateEventTag) error {
+	query := `
+	UPDATE
+		event_tags
+	SET`
+
+	var conditions []string
+	var args []interface{}
+
+	if tag := strings.TrimSpace(req.Tag); tag != "" {
+		conditions = append(conditions, " tag = $"+strconv.Itoa(len(args)+1))
+		args = append(args, tag)
+	}
+	if req.Aliases != nil {
+		conditions = append(conditions, " aliases = $"+strconv.Itoa(len(args)+1))
+		args = append(args, tagAliases(*req.Aliases))
+	}
+	if req.ExportAs != nil {
+		conditions = append(conditions, " export_as = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.ExportAs)
+	}
+	if req.Name.Uz != "" {
+		conditions = append(conditions, " name_uz = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Uz)
+	}
+	if req.Name.Ru != "" {
+		conditions = append(conditions, " name_ru = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Ru)
+	}
+	if req.Name.Cy != "" {
+		conditions = append(conditions, " name_cy = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Cy)
+	}
+	if req.IsActive != nil {
+		conditions = append(conditions, " is_active = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.IsActive)
+	}
+	if req.SortOrder != nil {
+		conditions = append(conditions, " sort_order = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.SortOrder)
+	}
+
+	if len(conditions) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	conditions = append(conditions, " updated_at = now()")
+	query += strings.Join(conditions, ", ")
+	query += " WHERE id = $" + strconv.Itoa(len(args)+1) + " AND deleted_at = 0"
+	args = append(args, req.Id)
+
+	tag, err := r.pg.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update event tag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *EventTagRepo) DeleteTag(ctx context.Context, id int) error {
+	query := `
+	UPDATE event_tags
+	SET deleted_at = EXTRACT(EPOCH FROM NOW())
+	WHERE id = $1 AND deleted_at = 0
+	`
+	tag, err := r.pg.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete event tag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func getEventTags(ctx context.Context, q querier, activeOnly bool) ([]entity.EventTag, error) {
+	query := `
+	SELECT id, code, tag, aliases, export_as, name_uz, name_ru, name_cy, is_active, sort_order
+	FROM event_tags
+	WHERE deleted_at = 0
+	`
+	if activeOnly {
+		query += " AND is_active"
+	}
+	query += " ORDER BY sort_order, code"
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []entity.EventTag{}
+	for rows.Next() {
+		tag := entity.EventTag{}
+		err := rows.Scan(&tag.Id, &tag.Code, &tag.Tag, &tag.Aliases, &tag.ExportAs,
+			&tag.Name.Uz, &tag.Name.Ru, &tag.Name.Cy, &tag.IsActive, &tag.SortOrder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over event tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (r *EventTagRepo) GetTags(ctx context.Context, activeOnly bool) (*entity.EventTagList, error) {
+	tags, err := getEventTags(ctx, r.pg.Pool, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.EventTagList{Tags: tags}, nil
+}
+
+// loadEventTagCatalog reads the catalog of the active tags.
+func loadEventTagCatalog(ctx context.Context, q querier) (*eventtag.Catalog, error) {
+	list, err := getEventTags(ctx, q, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []eventtag.Tag
+	for _, tag := range list {
+		tags = append(tags, eventtag.Tag{Code: tag.Code, Tag: tag.Tag, Aliases: tag.Aliases, ExportAs: tag.ExportAs})
+	}
+	return eventtag.New(tags), nil
+}
+
+// GetCatalog returns the catalog of the active tags, which transcripts are
+// normalized and checked against.
+func (r *EventTagRepo) GetCatalog(ctx context.Context) (*eventtag.Catalog, error) {
+	return loadEventTagCatalog(ctx, r.pg.Pool)
+}
+
+// GetStats counts the segments matching req that have each tag of the catalog,
+// written canonically or by an alias. Transcripts saved before the catalog may
+// still use an alias.
+func (r *EventTagRepo) GetStats(ctx context.Context, req *entity.ManifestReq) (*entity.EventTagStats, error) {
+	conditions, args := manifestConditions(req)
+
+	segments := `
+	WITH segments AS (
+		SELECT lower(COALESCE(t.transcribe_text, '')) AS text, COALESCE(s.duration, 0) AS duration
+		FROM transcripts t
+		JOIN audio_file_segments s ON s.id = t.segment_id
+		JOIN audio_files a ON a.id = s.audio_id
+		` + manifestSplitJoin + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+	)`
+
+	res := entity.EventTagStats{Tags: []entity.EventTagStat{}}
+	err := r.pg.Pool.QueryRow(ctx, segments+`
+	SELECT COUNT(*), COALESCE(SUM(duration), 0) / 3600 FROM segments`, args...).Scan(&res.Segments, &res.Hours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count segments: %w", err)
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, segments+`
+	SELECT e.code, e.tag, COUNT(g.text), COALESCE(SUM(g.duration), 0) / 3600
+	FROM event_tags e
+	LEFT JOIN segments g ON EXISTS (
+		SELECT 1 FROM unnest(array_prepend(e.tag::text, e.aliases)) n
+		WHERE strpos(g.text, '[' || lower(n) || ']') > 0
+	)
+	WHERE e.deleted_at = 0
+	GROUP BY e.id
+	ORDER BY e.sort_order, e.code`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event tag stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat entity.EventTagStat
+		if err := rows.Scan(&stat.Code, &stat.Tag, &stat.Segments, &stat.Hours); err != nil {
+			return nil, fmt.Errorf("failed to scan event tag stats: %w", err)
+		}
+		if res.Segments > 0 {
+			stat.Share = float64(stat.Segments) / float64(res.Segments)
+		}
+		res.Tags = append(res.Tags, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over event tag stats: %w", err)
+	}
+
+	return &res, nil
+}
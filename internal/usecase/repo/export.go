@@ -0,0 +1,260 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/langspan"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/normalizer"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+	"github.com/mirjalilova/voice_transcribe/pkg/translit"
+)
+
+type ExportRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewExportRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *ExportRepo {
+	return &ExportRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// textNormalizer returns the normalizer set up by the normalize section of the config.
+func textNormalizer(cfg config.Normalize) (normalizer.Config, error) {
+	norm := normalizer.Config{
+		Lowercase:        cfg.Lowercase,
+		StripPunctuation: cfg.StripPunctuation,
+		LanguageTags:     normalizer.TagMode(cfg.LanguageTags),
+		EventTags:        normalizer.TagMode(cfg.EventTags),
+		Apostrophe:       cfg.Apostrophe,
+		Numbers:          cfg.Numbers,
+		Language:         "uz",
+	}
+	if err := norm.Validate(); err != nil {
+		return norm, fmt.Errorf("invalid normalize config: %w", err)
+	}
+	return norm, nil
+}
+
+// manifestText returns the text of a transcript and its language spans with the
+// Uzbek in it written in script. An empty script keeps the text as typed.
+func manifestText(text, language string, spans []entity.LanguageSpan, script string) (string, []entity.LanguageSpan) {
+	if script == "" {
+		return text, spans
+	}
+	text, converted := langspan.Map(text, language, toLangspans(spans), func(part, lang string) string {
+		if lang != "uz" {
+			return part
+		}
+		return translit.Convert(part, translit.Script(script))
+	})
+	return text, fromLangspans(converted)
+}
+
+// normalizedText returns the text of a transcript normalized for training, with its
+// passages in other languages normalized in their own language. With a catalog the
+// event tags are handled by mode first, and the normalizer keeps what is left of them
+// instead of dropping them: kept tags with their markers, so they stay tags. Mapped
+// tags are normalized in their catalog spelling and mapped afterwards, since
+// normalizing would strip the markers of their export tokens.
+func normalizedText(norm normalizer.Config, catalog *eventtag.Catalog, mode, text, language string, spans []entity.LanguageSpan) string {
+	if catalog != nil {
+		applied := eventtag.Mode(mode)
+		if applied == eventtag.Map {
+			applied = eventtag.Keep
+		}
+		text, spans = taggedText(catalog, string(applied), text, language, spans)
+		norm.EventTags = normalizer.TagRaw
+		if applied == eventtag.Drop {
+			norm.EventTags = normalizer.TagKeep
+		}
+	}
+
+	norm.Language = language
+	normalized := norm.Normalize(langspan.Inline(text, toLangspans(spans)))
+	if catalog != nil && eventtag.Mode(mode) == eventtag.Map {
+		normalized = catalog.Apply(normalized, eventtag.Map)
+	}
+	return normalized
+}
+
+// taggedText returns the text of a transcript and its language spans with the event
+// tags in it handled by mode.
+func taggedText(catalog *eventtag.Catalog, mode, text, language string, spans []entity.LanguageSpan) (string, []entity.LanguageSpan) {
+	text, converted := langspan.Map(text, language, toLangspans(spans), func(part, _ string) string {
+		return catalog.Apply(part, eventtag.Mode(mode))
+	})
+	return text, fromLangspans(converted)
+}
+
+// manifestSplitJoin joins the dataset split of the audio file of a segment.
+const manifestSplitJoin = "LEFT JOIN audio_splits sp ON sp.audio_id = s.audio_id"
+
+// manifestConditions returns the WHERE conditions of the segments matching req. The
+// query has to name transcripts t, audio_file_segments s, audio_files a and, joined
+// with manifestSplitJoin, audio_splits sp.
+func manifestConditions(req *entity.ManifestReq) ([]string, []interface{}) {
+	status := req.Status
+	if status == "" {
+		status = "done"
+	}
+
+	conditions := []string{"a.deleted_at = 0", "s.deleted_at = 0", "t.deleted_at = 0"}
+	args := []interface{}{}
+
+	conditions = append(conditions, "t.status = $"+strconv.Itoa(len(args)+1))
+	args = append(args, status)
+
+	if req.FromDate != "" {
+		conditions = append(conditions, "t.updated_at >= $"+strconv.Itoa(len(args)+1)+"::date")
+		args = append(args, req.FromDate)
+	}
+	if req.ToDate != "" {
+		conditions = append(conditions, "t.updated_at < $"+strconv.Itoa(len(args)+1)+"::date + 1")
+		args = append(args, req.ToDate)
+	}
+	if req.UserId != "" {
+		conditions = append(conditions, "t.user_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.UserId)
+	}
+	if req.Language != "" {
+		conditions = append(conditions, transcriptLanguage+" = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Language)
+	}
+	if req.SpanLanguage != "" {
+		conditions = append(conditions, spanLanguageCondition(len(args)+1))
+		args = append(args, req.SpanLanguage)
+	}
+	if !req.IncludeFlagged {
+		conditions = append(conditions, qualityPassedCondition)
+	}
+	if req.Split != "" {
+		conditions = append(conditions, "sp.split = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Split)
+	}
+	if req.AfterAudioId > 0 || req.AfterSegmentId > 0 {
+		conditions = append(conditions, "(s.audio_id, s.id) > ($"+strconv.Itoa(len(args)+1)+", $"+strconv.Itoa(len(args)+2)+")")
+		args = append(args, req.AfterAudioId, req.AfterSegmentId)
+	}
+
+	return conditions, args
+}
+
+// CountManifest returns the number of segments matching req.
+func (r *ExportRepo) CountManifest(ctx context.Context, req *entity.ManifestReq) (int, error) {
+	conditions, args := manifestConditions(req)
+
+	query := `
+	SELECT COUNT(*)
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	JOIN audio_files a ON a.id = s.audio_id
+	` + manifestSplitJoin + `
+	WHERE ` + strings.Join(conditions, " AND ")
+
+	var count int
+	if err := r.pg.Pool.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count manifest: %w", err)
+	}
+
+	return count, nil
+}
+
+// StreamManifest calls fn with every segment matching req, in audio and segment order.
+// Rows are read from the database as fn consumes them, so the export never has to fit
+// in memory. An error from fn stops the export and is returned as is.
+func (r *ExportRepo) StreamManifest(ctx context.Context, req *entity.ManifestReq, fn func(*entity.ManifestEntry) error) error {
+	var norm normalizer.Config
+	if req.Normalize {
+		var err error
+		if norm, err = textNormalizer(r.config.Normalize); err != nil {
+			return err
+		}
+	}
+	var catalog *eventtag.Catalog
+	if req.Tags != "" {
+		var err error
+		if catalog, err = loadEventTagCatalog(ctx, r.pg.Pool); err != nil {
+			return err
+		}
+	}
+
+	conditions, args := manifestConditions(req)
+
+	query := `
+	SELECT
+		s.filename,
+		COALESCE(s.duration, 0),
+		COALESCE(t.transcribe_text, ''),
+		COALESCE(t.emotion, ''),
+		s.audio_id,
+		s.id,
+		` + transcriptLanguage + `,
+		COALESCE(t.user_id::text, ''),
+		COALESCE(sp.split, ''),
+		t.language_spans
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	JOIN audio_files a ON a.id = s.audio_id
+	` + manifestSplitJoin + `
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY s.audio_id, s.id
+	`
+	if req.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(req.Limit)
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry entity.ManifestEntry
+		err := rows.Scan(
+			&entry.AudioFilepath,
+			&entry.Duration,
+			&entry.Text,
+			&entry.Emotion,
+			&entry.AudioId,
+			&entry.SegmentId,
+			&entry.Language,
+			&entry.TranscriberId,
+			&entry.Split,
+			&entry.LanguageSpans)
+		if err != nil {
+			return fmt.Errorf("failed to scan manifest entry: %w", err)
+		}
+		entry.Speaker = "audio_" + strconv.Itoa(entry.AudioId)
+		entry.Text, entry.LanguageSpans = manifestText(entry.Text, entry.Language, entry.LanguageSpans, req.Script)
+		text, spans := entry.Text, entry.LanguageSpans
+		if catalog != nil {
+			entry.Text, entry.LanguageSpans = taggedText(catalog, req.Tags, text, entry.Language, spans)
+		}
+		if req.Normalize {
+			entry.TextNormalized = normalizedText(norm, catalog, req.Tags, text, entry.Language, spans)
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,400 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+const bundleColumns = `
+	b.id,
+	b.status,
+	b.filter,
+	b.total,
+	b.processed,
+	b.skipped,
+	b.size,
+	b.object_name,
+	COALESCE(b.upload_id, ''),
+	b.error,
+	b.attempts,
+	b.created_by::text,
+	b.created_at,
+	b.updated_at,
+	b.finished_at`
+
+// scanBundle scans a row of bundleColumns.
+func scanBundle(row pgx.Row, dest ...interface{}) (*entity.ExportBundle, error) {
+	var (
+		bundle               entity.ExportBundle
+		filter               []byte
+		createdAt, updatedAt time.Time
+		finishedAt           *time.Time
+	)
+	err := row.Scan(append(dest,
+		&bundle.Id,
+		&bundle.Status,
+		&filter,
+		&bundle.Total,
+		&bundle.Processed,
+		&bundle.Skipped,
+		&bundle.Size,
+		&bundle.ObjectName,
+		&bundle.UploadId,
+		&bundle.Error,
+		&bundle.Attempts,
+		&bundle.CreatedBy,
+		&createdAt,
+		&updatedAt,
+		&finishedAt)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filter, &bundle.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle filter: %w", err)
+	}
+	switch {
+	case bundle.Status == entity.BundleDone:
+		bundle.Progress = 1
+	case bundle.Total > 0:
+		bundle.Progress = min(float64(bundle.Processed)/float64(bundle.Total), 1)
+	}
+	bundle.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	bundle.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+	if finishedAt != nil {
+		finished := finishedAt.Format("2006-01-02 15:04:05")
+		bundle.FinishedAt = &finished
+	}
+
+	return &bundle, nil
+}
+
+// CreateBundle queues a bundle of the segments matching req.
+func (r *ExportRepo) CreateBundle(ctx context.Context, req *entity.ManifestReq, createdBy *string) (*int, error) {
+	total, err := r.CountManifest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle filter: %w", err)
+	}
+
+	query := `
+	INSERT INTO export_bundles (filter, total, created_by) VALUES ($1, $2, $3) RETURNING id`
+
+	var id int
+	if err := r.pg.Pool.QueryRow(ctx, query, filter, total, createdBy).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create export bundle: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *ExportRepo) GetBundle(ctx context.Context, id int) (*entity.ExportBundle, error) {
+	query := `SELECT ` + bundleColumns + ` FROM export_bundles b WHERE b.id = $1`
+
+	bundle, err := scanBundle(r.pg.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+func (r *ExportRepo) GetBundles(ctx context.Context, req *entity.Filter) (*entity.ExportBundleList, error) {
+	query := `
+	SELECT COUNT(b.id) OVER () AS total_count, ` + bundleColumns + `
+	FROM export_bundles b
+	ORDER BY b.id DESC OFFSET $1 LIMIT $2
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, req.Offset, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export bundle list: %w", err)
+	}
+	defer rows.Close()
+
+	bundles := entity.ExportBundleList{Bundles: []entity.ExportBundle{}}
+	for rows.Next() {
+		bundle, err := scanBundle(rows, &bundles.Count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export bundle: %w", err)
+		}
+		bundles.Bundles = append(bundles.Bundles, *bundle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over export bundles: %w", err)
+	}
+
+	return &bundles, nil
+}
+
+// ClaimBundle marks the oldest pending bundle as running and returns it. A running
+// bundle whose lease was not renewed for longer than lease counts as pending, so that
+// the build of a worker that went away is resumed. Every claim counts an attempt, and
+// the attempt identifies the claim in the updates that follow: once another worker
+// claimed the bundle, they return entity.ErrBundleLeaseLost. It returns
+// pgx.ErrNoRows when there is nothing to build.
+func (r *ExportRepo) ClaimBundle(ctx context.Context, lease time.Duration) (*entity.ExportBundle, error) {
+	query := `
+	UPDATE export_bundles b SET
+		status = 'running',
+		attempts = b.attempts + 1,
+		updated_at = NOW()
+	WHERE b.id = (
+		SELECT id FROM export_bundles
+		WHERE status = 'pending' OR (status = 'running' AND updated_at < NOW() - make_interval(secs => $1))
+		ORDER BY id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + bundleColumns
+
+	bundle, err := scanBundle(r.pg.Pool.QueryRow(ctx, query, lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export bundle: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// StartBundleUpload records the multipart upload the bundle is written to.
+func (r *ExportRepo) StartBundleUpload(ctx context.Context, id, attempt int, objectName, uploadId string) error {
+	query := `
+	UPDATE export_bundles SET object_name = $3, upload_id = $4, updated_at = NOW()
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt, objectName, uploadId)
+	if err != nil {
+		return fmt.Errorf("failed to start export bundle upload: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	return nil
+}
+
+// GetBundleParts returns the uploaded parts of a bundle in order, without their
+// manifest lines.
+func (r *ExportRepo) GetBundleParts(ctx context.Context, id int) ([]entity.ExportBundlePart, error) {
+	query := `
+	SELECT part_number, etag, size, segments, skipped, last_audio_id, last_segment_id
+	FROM export_bundle_parts
+	WHERE bundle_id = $1
+	ORDER BY part_number
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export bundle parts: %w", err)
+	}
+	defer rows.Close()
+
+	parts := []entity.ExportBundlePart{}
+	for rows.Next() {
+		var part entity.ExportBundlePart
+		err := rows.Scan(&part.Number, &part.ETag, &part.Size, &part.Segments, &part.Skipped, &part.LastAudioId, &part.LastSegmentId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export bundle part: %w", err)
+		}
+		parts = append(parts, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over export bundle parts: %w", err)
+	}
+
+	return parts, nil
+}
+
+// StreamBundleManifest calls fn with the manifest lines of every uploaded part in order.
+func (r *ExportRepo) StreamBundleManifest(ctx context.Context, id int, fn func(string) error) error {
+	rows, err := r.pg.Pool.Query(ctx, `
+	SELECT manifest FROM export_bundle_parts WHERE bundle_id = $1 ORDER BY part_number`, id)
+	if err != nil {
+		return fmt.Errorf("failed to get export bundle manifest: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var manifest string
+		if err := rows.Scan(&manifest); err != nil {
+			return fmt.Errorf("failed to scan export bundle manifest: %w", err)
+		}
+		if err := fn(manifest); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over export bundle manifest: %w", err)
+	}
+
+	return nil
+}
+
+// SaveBundlePart records an uploaded part and sets the progress of the bundle to the
+// segments in its parts.
+func (r *ExportRepo) SaveBundlePart(ctx context.Context, id, attempt int, part *entity.ExportBundlePart) error {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Locking the bundle first keeps a worker that lost the claim from saving a part.
+	var status string
+	err = tr.QueryRow(ctx, `
+	SELECT status FROM export_bundles WHERE id = $1 AND attempts = $2 FOR UPDATE`, id, attempt).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && status != entity.BundleRunning) {
+		tr.Rollback(ctx)
+		return entity.ErrBundleLeaseLost
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to lock export bundle: %w", err)
+	}
+
+	_, err = tr.Exec(ctx, `
+	INSERT INTO export_bundle_parts (
+		bundle_id, part_number, etag, size, segments, skipped, last_audio_id, last_segment_id, manifest
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (bundle_id, part_number) DO UPDATE SET
+		etag = EXCLUDED.etag,
+		size = EXCLUDED.size,
+		segments = EXCLUDED.segments,
+		skipped = EXCLUDED.skipped,
+		last_audio_id = EXCLUDED.last_audio_id,
+		last_segment_id = EXCLUDED.last_segment_id,
+		manifest = EXCLUDED.manifest`,
+		id, part.Number, part.ETag, part.Size, part.Segments, part.Skipped, part.LastAudioId, part.LastSegmentId, part.Manifest)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to save export bundle part: %w", err)
+	}
+
+	_, err = tr.Exec(ctx, `
+	UPDATE export_bundles b SET
+		processed = p.segments + p.skipped,
+		skipped = p.skipped,
+		size = p.size,
+		updated_at = NOW()
+	FROM (
+		SELECT SUM(segments) AS segments, SUM(skipped) AS skipped, SUM(size) AS size
+		FROM export_bundle_parts
+		WHERE bundle_id = $1
+	) p
+	WHERE b.id = $1`, id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to update export bundle: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBundleProgress sets the progress of a running bundle and renews its lease.
+func (r *ExportRepo) UpdateBundleProgress(ctx context.Context, id, attempt, processed, skipped int) error {
+	query := `
+	UPDATE export_bundles SET processed = $3, skipped = $4, updated_at = NOW()
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt, processed, skipped)
+	if err != nil {
+		return fmt.Errorf("failed to update export bundle progress: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	return nil
+}
+
+// RenewBundleLease keeps the claim of a running bundle for another lease.
+func (r *ExportRepo) RenewBundleLease(ctx context.Context, id, attempt int) error {
+	query := `
+	UPDATE export_bundles SET updated_at = NOW()
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt)
+	if err != nil {
+		return fmt.Errorf("failed to renew export bundle lease: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	return nil
+}
+
+// RetryBundle records the error of a failed attempt and leaves the bundle running
+// without renewing its lease, so that it is claimed and resumed once the lease runs out.
+func (r *ExportRepo) RetryBundle(ctx context.Context, id, attempt int, message string) error {
+	query := `
+	UPDATE export_bundles SET error = $3
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt, message)
+	if err != nil {
+		return fmt.Errorf("failed to retry export bundle: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	return nil
+}
+
+func (r *ExportRepo) FinishBundle(ctx context.Context, id, attempt, processed, skipped int, size int64) error {
+	query := `
+	UPDATE export_bundles SET
+		status = 'done',
+		processed = $3,
+		skipped = $4,
+		size = $5,
+		error = NULL,
+		updated_at = NOW(),
+		finished_at = NOW()
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt, processed, skipped, size)
+	if err != nil {
+		return fmt.Errorf("failed to finish export bundle: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	// The manifest lines are in the bundle now.
+	if _, err := r.pg.Pool.Exec(ctx, `UPDATE export_bundle_parts SET manifest = '' WHERE bundle_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to clear export bundle manifest: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExportRepo) FailBundle(ctx context.Context, id, attempt int, message string) error {
+	query := `
+	UPDATE export_bundles SET status = 'failed', error = $3, updated_at = NOW(), finished_at = NOW()
+	WHERE id = $1 AND attempts = $2 AND status = 'running'`
+
+	tag, err := r.pg.Pool.Exec(ctx, query, id, attempt, message)
+	if err != nil {
+		return fmt.Errorf("failed to fail export bundle: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrBundleLeaseLost
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/fraud"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+// fraudBatchSize is the number of transcripts checked per round of a batch check.
+const fraudBatchSize = 500
+
+type FraudRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewFraudRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *FraudRepo {
+	return &FraudRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *FraudRepo) fraudConfig() fraud.Config {
+	cfg := fraud.DefaultConfig
+	if r.config.Fraud.MinChars > 0 {
+		cfg.MinChars = r.config.Fraud.MinChars
+	}
+	if r.config.Fraud.MaxAiEditRate > 0 {
+		cfg.MaxAiEditRate = r.config.Fraud.MaxAiEditRate
+	}
+	if r.config.Fraud.MaxNeighbourEditRate > 0 {
+		cfg.MaxNeighbourEditRate = r.config.Fraud.MaxNeighbourEditRate
+	}
+	if r.config.Fraud.MinSpentRatio > 0 {
+		cfg.MinSpentRatio = r.config.Fraud.MinSpentRatio
+	}
+	return cfg
+}
+
+// Check checks the transcripts of the segments as they are now. Transcripts that are
+// no longer done lose their findings.
+func (r *FraudRepo) Check(ctx context.Context, segmentIds []int) error {
+	query := `
+	DELETE FROM transcript_fraud_checks f
+	USING transcripts t
+	WHERE f.transcript_id = t.id AND t.segment_id = ANY($1::int[])
+		AND (t.status <> 'done' OR t.deleted_at <> 0)
+	`
+	_, err := r.pg.Pool.Exec(ctx, query, segmentIds)
+	if err != nil {
+		return fmt.Errorf("failed to drop stale fraud checks: %w", err)
+	}
+
+	_, err = r.check(ctx, "t.segment_id = ANY($1::int[])", []interface{}{segmentIds}, len(segmentIds))
+	return err
+}
+
+// CheckPending checks every done transcript that is new or was edited since its last
+// check, in rounds of fraudBatchSize, and returns how many were checked.
+func (r *FraudRepo) CheckPending(ctx context.Context) (int, error) {
+	query := `
+	DELETE FROM transcript_fraud_checks f
+	WHERE NOT EXISTS (
+		SELECT 1 FROM transcripts t
+		WHERE t.id = f.transcript_id AND t.status = 'done' AND t.deleted_at = 0
+	)
+	`
+	_, err := r.pg.Pool.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop stale fraud checks: %w", err)
+	}
+
+	total := 0
+	for {
+		n, err := r.check(ctx, "(f.transcript_id IS NULL OR f.transcript_updated_at <> t.updated_at)", nil, fraudBatchSize)
+		total += n
+		if err != nil || n < fraudBatchSize {
+			return total, err
+		}
+	}
+}
+
+// check runs the detector on at most limit done transcripts matching condition and
+// saves the findings. The time spent on a transcript is its heartbeat time or, for
+// editors without heartbeats, the time from opening it to submitting it. A
+// neighbour's text only counts once it was submitted before the transcript, so the
+// author of a copied text is not flagged along with the copier.
+func (r *FraudRepo) check(ctx context.Context, condition string, args []interface{}, limit int) (int, error) {
+	query := `
+	SELECT
+		t.id,
+		t.user_id::text,
+		t.updated_at,
+		COALESCE(t.transcribe_text, ''),
+		COALESCE(t.ai_text, ''),
+		COALESCE(p.transcribe_text, ''),
+		COALESCE(p.ai_text, ''),
+		COALESCE(n.transcribe_text, ''),
+		COALESCE(n.ai_text, ''),
+		COALESCE(s.duration, 0),
+		CASE
+			WHEN t.active_seconds > 0 THEN t.active_seconds::float
+			WHEN t.viewed_at IS NOT NULL AND t.updated_at > t.viewed_at THEN EXTRACT(EPOCH FROM t.updated_at - t.viewed_at)::float
+		END
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	LEFT JOIN transcript_fraud_checks f ON f.transcript_id = t.id
+	LEFT JOIN LATERAL (
+		SELECT CASE WHEN nt.updated_at < t.updated_at THEN nt.transcribe_text END AS transcribe_text, nt.ai_text
+		FROM audio_file_segments ns
+		JOIN transcripts nt ON nt.segment_id = ns.id AND nt.deleted_at = 0
+		WHERE ns.audio_id = s.audio_id AND ns.deleted_at = 0 AND ns.id < s.id
+		ORDER BY ns.id DESC
+		LIMIT 1
+	) p ON true
+	LEFT JOIN LATERAL (
+		SELECT CASE WHEN nt.updated_at < t.updated_at THEN nt.transcribe_text END AS transcribe_text, nt.ai_text
+		FROM audio_file_segments ns
+		JOIN transcripts nt ON nt.segment_id = ns.id AND nt.deleted_at = 0
+		WHERE ns.audio_id = s.audio_id AND ns.deleted_at = 0 AND ns.id > s.id
+		ORDER BY ns.id
+		LIMIT 1
+	) n ON true
+	WHERE t.status = 'done' AND t.deleted_at = 0 AND s.deleted_at = 0 AND t.user_id IS NOT NULL
+		AND ` + condition + `
+	ORDER BY t.id
+	LIMIT $` + strconv.Itoa(len(args)+1)
+
+	rows, err := r.pg.Pool.Query(ctx, query, append(args, limit)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transcripts to check: %w", err)
+	}
+
+	cfg := r.fraudConfig()
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var (
+			id               int
+			userId           string
+			updatedAt        time.Time
+			sub              fraud.Submission
+			prevText, prevAi string
+			nextText, nextAi string
+		)
+		err := rows.Scan(&id, &userId, &updatedAt, &sub.Text, &sub.AiText,
+			&prevText, &prevAi, &nextText, &nextAi, &sub.Duration, &sub.Spent)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan transcript to check: %w", err)
+		}
+		sub.Neighbours = []string{prevText, prevAi, nextText, nextAi}
+
+		reasons := cfg.Check(sub)
+		if reasons == nil {
+			reasons = []string{}
+		}
+		batch.Queue(`
+		INSERT INTO transcript_fraud_checks (transcript_id, user_id, reasons, risk, duration, spent_seconds, transcript_updated_at, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+		ON CONFLICT (transcript_id) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			reasons = EXCLUDED.reasons,
+			risk = EXCLUDED.risk,
+			duration = EXCLUDED.duration,
+			spent_seconds = EXCLUDED.spent_seconds,
+			transcript_updated_at = EXCLUDED.transcript_updated_at,
+			checked_at = EXCLUDED.checked_at`,
+			id, userId, reasons, fraud.Risk(reasons), sub.Duration, sub.Spent, updatedAt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate over transcripts to check: %w", err)
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+
+	br := r.pg.Pool.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return 0, fmt.Errorf("failed to save fraud checks: %w", err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return 0, fmt.Errorf("failed to save fraud checks: %w", err)
+	}
+
+	return batch.Len(), nil
+}
+
+// GetUserRisks ranks the transcribers by the risk of what they submitted in the last
+// days, riskiest first.
+func (r *FraudRepo) GetUserRisks(ctx context.Context, days int) (*entity.UserRiskList, error) {
+	query := `
+	SELECT
+		f.user_id::text,
+		u.username,
+		COUNT(*),
+		COUNT(*) FILTER (WHERE cardinality(f.reasons) > 0),
+		COUNT(*) FILTER (WHERE $2 = ANY(f.reasons)),
+		COUNT(*) FILTER (WHERE $3 = ANY(f.reasons)),
+		COUNT(*) FILTER (WHERE $4 = ANY(f.reasons)),
+		COUNT(*) FILTER (WHERE $5 = ANY(f.reasons)),
+		ROUND(AVG(f.risk)::numeric * 100, 2)::float AS risk_score
+	FROM transcript_fraud_checks f
+	LEFT JOIN users u ON u.id = f.user_id
+	WHERE f.transcript_updated_at >= now() - make_interval(days => $1)
+	GROUP BY f.user_id, u.username
+	ORDER BY risk_score DESC, COUNT(*) DESC
+	`
+	rows, err := r.pg.Pool.Query(ctx, query, days,
+		fraud.UnchangedAi, fraud.DuplicateNeighbour, fraud.TooFast, fraud.RepetitiveFiller)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user risks: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.UserRiskList{Days: days, Users: []entity.UserRisk{}}
+	for rows.Next() {
+		var item entity.UserRisk
+		err := rows.Scan(&item.UserId, &item.Username, &item.Transcripts, &item.Flagged,
+			&item.UnchangedAi, &item.DuplicateNeighbour, &item.TooFast, &item.RepetitiveFiller, &item.RiskScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user risk: %w", err)
+		}
+		res.Users = append(res.Users, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over user risks: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetFlags returns the flagged submissions of the last days, riskiest and latest first.
+func (r *FraudRepo) GetFlags(ctx context.Context, req *entity.FraudFlagReq) (*entity.FraudFlagList, error) {
+	conditions := []string{"cardinality(f.reasons) > 0", "t.deleted_at = 0", "s.deleted_at = 0"}
+	args := []interface{}{}
+
+	conditions = append(conditions, "f.transcript_updated_at >= now() - make_interval(days => $"+strconv.Itoa(len(args)+1)+")")
+	args = append(args, req.Days)
+
+	if req.UserId != "" {
+		conditions = append(conditions, "f.user_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.UserId)
+	}
+	if req.Reason != "" {
+		conditions = append(conditions, "$"+strconv.Itoa(len(args)+1)+" = ANY(f.reasons)")
+		args = append(args, req.Reason)
+	}
+
+	from := `
+	FROM transcript_fraud_checks f
+	JOIN transcripts t ON t.id = f.transcript_id
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	LEFT JOIN users u ON u.id = f.user_id
+	WHERE ` + strings.Join(conditions, " AND ")
+
+	res := entity.FraudFlagList{Flags: []entity.FraudFlag{}}
+
+	err := r.pg.Pool.QueryRow(ctx, "SELECT COUNT(*) "+from, args...).Scan(&res.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count fraud flags: %w", err)
+	}
+
+	query := `
+	SELECT
+		f.transcript_id,
+		t.segment_id,
+		s.audio_id,
+		s.filename,
+		f.user_id::text,
+		u.username,
+		t.transcribe_text,
+		t.ai_text,
+		f.duration,
+		f.spent_seconds,
+		f.reasons,
+		f.risk,
+		f.transcript_updated_at
+	` + from + `
+	ORDER BY f.risk DESC, f.transcript_updated_at DESC
+	OFFSET $` + strconv.Itoa(len(args)+1) + ` LIMIT $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.pg.Pool.Query(ctx, query, append(args, req.Filter.Offset, req.Filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fraud flags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item        entity.FraudFlag
+			submittedAt time.Time
+		)
+		err := rows.Scan(
+			&item.TranscriptId,
+			&item.SegmentId,
+			&item.AudioId,
+			&item.ChunkUrl,
+			&item.UserId,
+			&item.Username,
+			&item.Text,
+			&item.AiText,
+			&item.Duration,
+			&item.SpentSeconds,
+			&item.Reasons,
+			&item.Risk,
+			&submittedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fraud flag: %w", err)
+		}
+		item.SubmittedAt = submittedAt.Format("2006-01-02 15:04:05")
+
+		res.Flags = append(res.Flags, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over fraud flags: %w", err)
+	}
+
+	return &res, nil
+}
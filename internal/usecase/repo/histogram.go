@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+)
+
+// maxHistogramBuckets bounds the number of equal width buckets of a histogram.
+const maxHistogramBuckets = 1000
+
+var histogramValues = map[string]string{
+	entity.HistogramDuration: "s.duration",
+	entity.HistogramChars:    "char_length(t.transcribe_text)",
+	entity.HistogramWords: `CASE WHEN btrim(t.transcribe_text) = '' THEN 0
+		ELSE array_length(regexp_split_to_array(btrim(t.transcribe_text), '\s+'), 1) END`,
+	entity.HistogramCharsPerSec:  "char_length(t.transcribe_text) / NULLIF(s.duration, 0)",
+	entity.HistogramMinutesSpent: "NULLIF(t.active_seconds, 0) / 60.0",
+}
+
+// GetHistogram counts the segments per bucket of the dimension. Buckets come back in
+// order; with a width, empty buckets between the smallest and the largest are included,
+// up to maxHistogramBuckets, beyond which it returns entity.ErrTooManyBuckets.
+func (r *AudioSegmentRepo) GetHistogram(ctx context.Context, req *entity.HistogramReq) (*entity.Histogram, error) {
+	value, ok := histogramValues[req.Dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown histogram dimension %q", req.Dimension)
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	var bucket string
+	if len(req.Edges) > 0 {
+		bucket = "width_bucket((" + value + ")::float8, $" + strconv.Itoa(len(args)+1) + "::float8[])"
+		args = append(args, req.Edges)
+	} else {
+		bucket = "floor((" + value + ")::float8 / $" + strconv.Itoa(len(args)+1) + ")::int"
+		args = append(args, req.Width)
+	}
+
+	if req.Status != "" {
+		conditions = append(conditions, "t.status = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Status)
+	}
+	if req.FromDate != "" {
+		conditions = append(conditions, "t.updated_at >= $"+strconv.Itoa(len(args)+1)+"::date")
+		args = append(args, req.FromDate)
+	}
+	if req.ToDate != "" {
+		conditions = append(conditions, "t.updated_at < $"+strconv.Itoa(len(args)+1)+"::date + 1")
+		args = append(args, req.ToDate)
+	}
+	if req.UserId != "" {
+		conditions = append(conditions, "t.user_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.UserId)
+	}
+	if req.AudioId != 0 {
+		conditions = append(conditions, "s.audio_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.AudioId)
+	}
+
+	query := `
+	SELECT ` + bucket + ` AS bucket, COUNT(*)
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	WHERE t.deleted_at = 0 AND s.deleted_at = 0 AND (` + value + `) IS NOT NULL
+	`
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+	query += " GROUP BY 1 ORDER BY 1"
+
+	rows, err := r.pg.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get histogram: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	first, last := math.MaxInt, math.MinInt
+	res := entity.Histogram{Dimension: req.Dimension, Buckets: []entity.HistogramBucket{}}
+	for rows.Next() {
+		var idx, count int
+		if err := rows.Scan(&idx, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram bucket: %w", err)
+		}
+		counts[idx] = count
+		first, last = min(first, idx), max(last, idx)
+		res.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over histogram buckets: %w", err)
+	}
+
+	if len(req.Edges) > 0 {
+		// width_bucket puts values below the first edge in 0 and from the last edge on in len(edges).
+		for idx := 0; idx <= len(req.Edges); idx++ {
+			b := entity.HistogramBucket{Count: counts[idx]}
+			if idx > 0 {
+				b.From = &req.Edges[idx-1]
+			}
+			if idx < len(req.Edges) {
+				b.To = &req.Edges[idx]
+			}
+			if (idx == 0 || idx == len(req.Edges)) && b.Count == 0 {
+				continue
+			}
+			b.Label = bucketLabel(b.From, b.To)
+			res.Buckets = append(res.Buckets, b)
+		}
+		return &res, nil
+	}
+
+	if res.Total == 0 {
+		return &res, nil
+	}
+	if last-first >= maxHistogramBuckets {
+		return nil, entity.ErrTooManyBuckets
+	}
+	for idx := first; idx <= last; idx++ {
+		from, to := float64(idx)*req.Width, float64(idx+1)*req.Width
+		b := entity.HistogramBucket{From: &from, To: &to, Count: counts[idx]}
+		b.Label = bucketLabel(b.From, b.To)
+		res.Buckets = append(res.Buckets, b)
+	}
+
+	return &res, nil
+}
+
+func bucketLabel(from, to *float64) string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	switch {
+	case from == nil:
+		return "<" + format(*to)
+	case to == nil:
+		return ">=" + format(*from)
+	default:
+		return format(*from) + "-" + format(*to)
+	}
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/langspan"
+)
+
+// spanLanguageCondition keeps transcripts aliased as t with a passage in the language
+// bound to parameter n.
+func spanLanguageCondition(n int) string {
+	return "t.language_spans @> jsonb_build_array(jsonb_build_object('language', $" + strconv.Itoa(n) + "::text))"
+}
+
+// anyLanguageCondition keeps transcripts aliased as t in the language bound to
+// parameter n, as their primary language or in a passage.
+func anyLanguageCondition(n int) string {
+	return "(t.language = $" + strconv.Itoa(n) + " OR " + spanLanguageCondition(n) + ")"
+}
+
+// spansJSON encodes spans for a JSONB column, [] when there are none.
+func spansJSON(spans []entity.LanguageSpan) []byte {
+	if len(spans) == 0 {
+		return []byte("[]")
+	}
+	data, _ := json.Marshal(spans)
+	return data
+}
+
+func toLangspans(spans []entity.LanguageSpan) []langspan.Span {
+	res := make([]langspan.Span, len(spans))
+	for i, s := range spans {
+		res[i] = langspan.Span(s)
+	}
+	return res
+}
+
+func fromLangspans(spans []langspan.Span) []entity.LanguageSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	res := make([]entity.LanguageSpan, len(spans))
+	for i, s := range spans {
+		res[i] = entity.LanguageSpan(s)
+	}
+	return res
+}
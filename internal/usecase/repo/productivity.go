@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/productivity"
+)
+
+func (r *AudioSegmentRepo) productivityConfig() productivity.Config {
+	cfg := productivity.DefaultConfig
+	if r.config.Work.BlockLength > 0 {
+		cfg.Block = r.config.Work.BlockLength
+	}
+	if r.config.Work.ShiftLength > 0 {
+		cfg.Shift = r.config.Work.ShiftLength
+	}
+	return cfg
+}
+
+// getActiveBlocks returns the active blocks of each operator and the number of active
+// blocks of all operators per day, keyed by day. Days are read from the daily rollup,
+// which is brought up to date first.
+func (r *AudioSegmentRepo) getActiveBlocks(ctx context.Context, fromDate, toDate time.Time) (map[string][]entity.DailyActiveBlock, map[string]int, error) {
+	cfg := r.productivityConfig()
+
+	err := r.refreshActiveBlocks(ctx, cfg, fromDate, toDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+	SELECT b.day, b.user_id::text, COALESCE(u.username, ''), b.blocks
+	FROM user_daily_blocks b
+	LEFT JOIN users u ON u.id = b.user_id
+	WHERE b.day BETWEEN $1::date AND $2::date
+	ORDER BY b.day, u.username
+	`
+	rows, err := r.pg.Pool.Query(ctx, query, fromDate, toDate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get daily active operators: %w", err)
+	}
+	defer rows.Close()
+
+	res := make(map[string][]entity.DailyActiveBlock)
+	totals := make(map[string]int)
+	for rows.Next() {
+		var block entity.DailyActiveBlock
+		var statDate time.Time
+		var blocks int
+
+		if err := rows.Scan(&statDate, &block.OperatorID, &block.Username, &blocks); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan daily active operator: %w", err)
+		}
+
+		block.StatDate = statDate.Format("2006-01-02")
+		block.ActiveBlocks = cfg.Score(blocks)
+		res[block.StatDate] = append(res[block.StatDate], block)
+		totals[block.StatDate] += blocks
+	}
+
+	return res, totals, rows.Err()
+}
+
+// refreshActiveBlocks recomputes the days of the range that are not in the rollup yet,
+// were computed with another block length, were computed before they were over, or
+// got work finished after they were computed.
+func (r *AudioSegmentRepo) refreshActiveBlocks(ctx context.Context, cfg productivity.Config, fromDate, toDate time.Time) error {
+	tz := r.config.Work.Timezone
+	blockSeconds := int(cfg.Block / time.Second)
+
+	query := `
+	SELECT d.day::date
+	FROM generate_series($1::date, $2::date, INTERVAL '1 day') d(day)
+	LEFT JOIN daily_block_rollups ro ON ro.day = d.day::date
+	WHERE ro.day IS NULL
+		OR ro.block_seconds <> $3
+		OR ro.computed_at < ((d.day::date + 1)::timestamp AT TIME ZONE $4)::timestamp
+		OR EXISTS (
+			SELECT 1
+			FROM transcripts t
+			WHERE t.status = 'done' AND t.deleted_at = 0 AND t.updated_at > ro.computed_at
+				AND (t.updated_at::timestamptz AT TIME ZONE $4)::date = d.day::date
+		)
+	ORDER BY 1
+	`
+	rows, err := r.pg.Pool.Query(ctx, query, fromDate, toDate, blockSeconds, tz)
+	if err != nil {
+		return fmt.Errorf("failed to get stale rollup days: %w", err)
+	}
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan rollup day: %w", err)
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over rollup days: %w", err)
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	query = `
+	SELECT t.user_id::text, (t.updated_at::timestamptz AT TIME ZONE $3)
+	FROM transcripts t
+	WHERE t.status = 'done' AND t.deleted_at = 0 AND t.user_id IS NOT NULL
+		AND t.updated_at >= ($1::date::timestamp AT TIME ZONE $3)::timestamp
+		AND t.updated_at < (($2::date + 1)::timestamp AT TIME ZONE $3)::timestamp
+		AND (t.updated_at::timestamptz AT TIME ZONE $3)::date = ANY($4::date[])
+	`
+	rows, err = r.pg.Pool.Query(ctx, query, days[0], days[len(days)-1], tz, days)
+	if err != nil {
+		return fmt.Errorf("failed to get finished transcripts: %w", err)
+	}
+
+	var events []productivity.Event
+	for rows.Next() {
+		var event productivity.Event
+		if err := rows.Scan(&event.UserId, &event.At); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan finished transcript: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over finished transcripts: %w", err)
+	}
+
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	_, err = tr.Exec(ctx, `DELETE FROM user_daily_blocks WHERE day = ANY($1::date[])`, days)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to clear daily blocks: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for day, blocks := range cfg.Blocks(events) {
+		batch.Queue(`
+		INSERT INTO user_daily_blocks (user_id, day, blocks) VALUES ($1, $2, $3)
+		ON CONFLICT (day, user_id) DO UPDATE SET blocks = EXCLUDED.blocks`, day.UserId, day.Date, blocks)
+	}
+	batch.Queue(`
+	INSERT INTO daily_block_rollups (day, block_seconds, computed_at)
+	SELECT unnest($1::date[]), $2, now()
+	ON CONFLICT (day) DO UPDATE SET block_seconds = EXCLUDED.block_seconds, computed_at = EXCLUDED.computed_at
+	`, days, blockSeconds)
+
+	br := tr.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			tr.Rollback(ctx)
+			return fmt.Errorf("failed to save daily blocks: %w", err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to save daily blocks: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,396 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+const qualitySnapshot = "quality_check"
+
+// transcriptLanguage is the primary language of a transcript aliased as t.
+const transcriptLanguage = `t.language`
+
+// qualityPassedCondition keeps out transcripts flagged by the quality check unless a
+// reviewer dismissed the flag. Transcripts must be aliased as t.
+const qualityPassedCondition = `NOT EXISTS (
+	SELECT 1 FROM transcript_quality q
+	WHERE q.transcript_id = t.id AND q.flagged AND q.review <> 'dismissed'
+)`
+
+type QualityRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewQualityRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *QualityRepo {
+	return &QualityRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Check measures the done transcripts that are new or edited since the last check and
+// flags, per language, those whose chars or words per second fall outside Tukey's
+// fences, as well as empty texts on long segments. A review is reopened only when the
+// text or the flag reasons change.
+func (r *QualityRepo) Check(ctx context.Context) (*entity.QualityCheckResult, error) {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	query := `
+	INSERT INTO transcript_quality (
+		transcript_id, language, duration, chars, words, chars_per_sec, words_per_sec, transcript_updated_at, text_hash
+	)
+	SELECT
+		m.id, m.language, m.duration, m.chars, m.words,
+		m.chars / m.duration, m.words / m.duration, m.updated_at, m.text_hash
+	FROM (
+		SELECT
+			t.id,
+			t.updated_at,
+			` + transcriptLanguage + ` AS language,
+			s.duration,
+			char_length(btrim(COALESCE(t.transcribe_text, ''))) AS chars,
+			CASE WHEN btrim(COALESCE(t.transcribe_text, '')) = '' THEN 0
+				ELSE cardinality(regexp_split_to_array(btrim(t.transcribe_text), '\s+'))
+			END AS words,
+			md5(btrim(COALESCE(t.transcribe_text, ''))) AS text_hash
+		FROM transcripts t
+		JOIN audio_file_segments s ON s.id = t.segment_id
+		LEFT JOIN transcript_quality q ON q.transcript_id = t.id
+		WHERE t.status = 'done' AND t.deleted_at = 0 AND s.deleted_at = 0 AND s.duration > 0
+			AND (q.transcript_id IS NULL OR q.transcript_updated_at <> t.updated_at)
+	) m
+	ON CONFLICT (transcript_id) DO UPDATE SET
+		language = EXCLUDED.language,
+		duration = EXCLUDED.duration,
+		chars = EXCLUDED.chars,
+		words = EXCLUDED.words,
+		chars_per_sec = EXCLUDED.chars_per_sec,
+		words_per_sec = EXCLUDED.words_per_sec,
+		transcript_updated_at = EXCLUDED.transcript_updated_at,
+		text_hash = EXCLUDED.text_hash,
+		review = CASE WHEN transcript_quality.text_hash = EXCLUDED.text_hash
+			THEN transcript_quality.review ELSE 'open' END,
+		reviewed_by = CASE WHEN transcript_quality.text_hash = EXCLUDED.text_hash
+			THEN transcript_quality.reviewed_by END,
+		reviewed_at = CASE WHEN transcript_quality.text_hash = EXCLUDED.text_hash
+			THEN transcript_quality.reviewed_at END
+	`
+	_, err = tr.Exec(ctx, query)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to measure transcripts: %w", err)
+	}
+
+	query = `
+	DELETE FROM transcript_quality q
+	WHERE NOT EXISTS (
+		SELECT 1
+		FROM transcripts t
+		JOIN audio_file_segments s ON s.id = t.segment_id
+		WHERE t.id = q.transcript_id
+			AND t.status = 'done' AND t.deleted_at = 0 AND s.deleted_at = 0 AND s.duration > 0
+	)
+	`
+	_, err = tr.Exec(ctx, query)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to drop stale measurements: %w", err)
+	}
+
+	// Quartiles leave empty texts out so that they do not drag the lower fence down.
+	query = `
+	SELECT
+		language,
+		COUNT(*),
+		PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY chars_per_sec) FILTER (WHERE chars > 0),
+		PERCENTILE_CONT(0.75) WITHIN GROUP (ORDER BY chars_per_sec) FILTER (WHERE chars > 0),
+		PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY words_per_sec) FILTER (WHERE chars > 0),
+		PERCENTILE_CONT(0.75) WITHIN GROUP (ORDER BY words_per_sec) FILTER (WHERE chars > 0)
+	FROM transcript_quality
+	GROUP BY language
+	ORDER BY language
+	`
+	rows, err := tr.Query(ctx, query)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get speaking rate quartiles: %w", err)
+	}
+
+	res := entity.QualityCheckResult{Languages: []entity.QualityLanguageStats{}}
+	for rows.Next() {
+		var (
+			stats            entity.QualityLanguageStats
+			charsQ1, charsQ3 *float64
+			wordsQ1, wordsQ3 *float64
+		)
+		err := rows.Scan(&stats.Language, &stats.Transcripts, &charsQ1, &charsQ3, &wordsQ1, &wordsQ3)
+		if err != nil {
+			rows.Close()
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to scan speaking rate quartiles: %w", err)
+		}
+
+		if stats.Transcripts >= r.config.Quality.MinSamples {
+			stats.CharsPerSecLower, stats.CharsPerSecUpper = r.fences(charsQ1, charsQ3)
+			stats.WordsPerSecLower, stats.WordsPerSecUpper = r.fences(wordsQ1, wordsQ3)
+		}
+		res.Languages = append(res.Languages, stats)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to iterate over speaking rate quartiles: %w", err)
+	}
+
+	// A flag that does not change keeps its review.
+	query = `
+	WITH judged AS (
+		SELECT
+			transcript_id,
+			ARRAY_REMOVE(ARRAY[
+				CASE WHEN chars = 0 AND duration >= $2 THEN '` + entity.QualityEmptyText + `' END,
+				CASE WHEN chars > 0 AND chars_per_sec > $3 THEN '` + entity.QualityCharsPerSecHigh + `' END,
+				CASE WHEN chars > 0 AND chars_per_sec < $4 THEN '` + entity.QualityCharsPerSecLow + `' END,
+				CASE WHEN chars > 0 AND words_per_sec > $5 THEN '` + entity.QualityWordsPerSecHigh + `' END,
+				CASE WHEN chars > 0 AND words_per_sec < $6 THEN '` + entity.QualityWordsPerSecLow + `' END
+			], NULL) AS reasons
+		FROM transcript_quality
+		WHERE language = $1
+	)
+	UPDATE transcript_quality q
+	SET reasons = j.reasons,
+		flagged = cardinality(j.reasons) > 0,
+		review = CASE WHEN q.reasons = j.reasons THEN q.review ELSE 'open' END,
+		reviewed_by = CASE WHEN q.reasons = j.reasons THEN q.reviewed_by END,
+		reviewed_at = CASE WHEN q.reasons = j.reasons THEN q.reviewed_at END,
+		computed_at = now()
+	FROM judged j
+	WHERE q.transcript_id = j.transcript_id
+	`
+	for i, stats := range res.Languages {
+		_, err = tr.Exec(ctx, query, stats.Language, r.config.Quality.EmptyMinDuration.Seconds(),
+			stats.CharsPerSecUpper, stats.CharsPerSecLower, stats.WordsPerSecUpper, stats.WordsPerSecLower)
+		if err != nil {
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to flag outliers: %w", err)
+		}
+
+		err = tr.QueryRow(ctx, `SELECT COUNT(*) FROM transcript_quality WHERE language = $1 AND flagged`, stats.Language).
+			Scan(&res.Languages[i].Flagged)
+		if err != nil {
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to count outliers: %w", err)
+		}
+	}
+
+	payload, err := json.Marshal(res.Languages)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to encode quality check: %w", err)
+	}
+
+	var checkedAt time.Time
+	query = `
+	INSERT INTO stats_snapshots (name, payload, refreshed_at)
+	VALUES ($1, $2, now())
+	ON CONFLICT (name) DO UPDATE SET payload = EXCLUDED.payload, refreshed_at = EXCLUDED.refreshed_at
+	RETURNING refreshed_at
+	`
+	err = tr.QueryRow(ctx, query, qualitySnapshot, payload).Scan(&checkedAt)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to save quality check: %w", err)
+	}
+	res.CheckedAt = checkedAt.Format("2006-01-02 15:04:05")
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &res, nil
+}
+
+// fences returns Tukey's fences of the quartiles. The lower fence is nil when it is
+// not above zero, since no rate can fall below it.
+func (r *QualityRepo) fences(q1, q3 *float64) (lower, upper *float64) {
+	if q1 == nil || q3 == nil {
+		return nil, nil
+	}
+
+	spread := r.config.Quality.FenceFactor * (*q3 - *q1)
+	hi := *q3 + spread
+	upper = &hi
+	if lo := *q1 - spread; lo > 0 {
+		lower = &lo
+	}
+	return lower, upper
+}
+
+// GetOutliers returns the flagged transcripts, most recently flagged first, together
+// with the fences of the last check.
+func (r *QualityRepo) GetOutliers(ctx context.Context, req *entity.QualityOutlierReq) (*entity.QualityOutlierList, error) {
+	conditions := []string{"q.flagged", "t.deleted_at = 0", "s.deleted_at = 0"}
+	args := []interface{}{}
+
+	if req.Language != "" {
+		conditions = append(conditions, "q.language = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Language)
+	}
+	if req.Reason != "" {
+		conditions = append(conditions, "$"+strconv.Itoa(len(args)+1)+" = ANY(q.reasons)")
+		args = append(args, req.Reason)
+	}
+	if req.Review != "" {
+		conditions = append(conditions, "q.review = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Review)
+	}
+	if req.UserId != "" {
+		conditions = append(conditions, "t.user_id = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.UserId)
+	}
+
+	from := `
+	FROM transcript_quality q
+	JOIN transcripts t ON t.id = q.transcript_id
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	LEFT JOIN users u ON u.id = t.user_id
+	WHERE ` + strings.Join(conditions, " AND ")
+
+	res := entity.QualityOutlierList{Outliers: []entity.QualityOutlier{}}
+
+	err := r.pg.Pool.QueryRow(ctx, "SELECT COUNT(*) "+from, args...).Scan(&res.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count quality outliers: %w", err)
+	}
+
+	query := `
+	SELECT
+		q.transcript_id,
+		t.segment_id,
+		s.audio_id,
+		s.filename,
+		t.transcribe_text,
+		q.language,
+		q.duration,
+		q.chars,
+		q.words,
+		q.chars_per_sec,
+		q.words_per_sec,
+		q.reasons,
+		q.review,
+		t.user_id::text,
+		u.username,
+		q.reviewed_by::text,
+		q.reviewed_at,
+		q.computed_at
+	` + from + `
+	ORDER BY q.computed_at DESC, q.transcript_id DESC
+	OFFSET $` + strconv.Itoa(len(args)+1) + ` LIMIT $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.pg.Pool.Query(ctx, query, append(args, req.Filter.Offset, req.Filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quality outliers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item       entity.QualityOutlier
+			reviewedAt *time.Time
+			computedAt time.Time
+		)
+		err := rows.Scan(
+			&item.TranscriptId,
+			&item.SegmentId,
+			&item.AudioId,
+			&item.ChunkUrl,
+			&item.Text,
+			&item.Language,
+			&item.Duration,
+			&item.Chars,
+			&item.Words,
+			&item.CharsPerSec,
+			&item.WordsPerSec,
+			&item.Reasons,
+			&item.Review,
+			&item.UserId,
+			&item.Username,
+			&item.ReviewedBy,
+			&reviewedAt,
+			&computedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quality outlier: %w", err)
+		}
+		if reviewedAt != nil {
+			formatted := reviewedAt.Format("2006-01-02 15:04:05")
+			item.ReviewedAt = &formatted
+		}
+		item.ComputedAt = computedAt.Format("2006-01-02 15:04:05")
+
+		res.Outliers = append(res.Outliers, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over quality outliers: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetLastCheck returns the fences and counts of the last quality check.
+func (r *QualityRepo) GetLastCheck(ctx context.Context) (*entity.QualityCheckResult, error) {
+	var (
+		payload   []byte
+		checkedAt time.Time
+	)
+	query := `SELECT payload, refreshed_at FROM stats_snapshots WHERE name = $1`
+	err := r.pg.Pool.QueryRow(ctx, query, qualitySnapshot).Scan(&payload, &checkedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &entity.QualityCheckResult{Languages: []entity.QualityLanguageStats{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quality check: %w", err)
+	}
+
+	res := entity.QualityCheckResult{CheckedAt: checkedAt.Format("2006-01-02 15:04:05")}
+	if err := json.Unmarshal(payload, &res.Languages); err != nil {
+		return nil, fmt.Errorf("failed to decode quality check: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Review records whether a flag is right. Dismissed transcripts go back into exports.
+func (r *QualityRepo) Review(ctx context.Context, req *entity.ReviewQualityOutlier) error {
+	query := `
+	UPDATE transcript_quality
+	SET review = $2, reviewed_by = $3, reviewed_at = now()
+	WHERE transcript_id = $1 AND flagged
+	`
+	tag, err := r.pg.Pool.Exec(ctx, query, req.TranscriptId, req.Review, req.ReviewerId)
+	if err != nil {
+		return fmt.Errorf("failed to review quality outlier: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
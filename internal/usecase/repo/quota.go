@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+type QuotaRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewQuotaRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *QuotaRepo {
+	return &QuotaRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// SetLimit creates or replaces the limits of a user or a role.
+func (r *QuotaRepo) SetLimit(ctx context.Context, req *entity.SetTranscriberLimit) (*int, error) {
+	values := `$1::uuid, $2::role, $3, $4, $5::time, $6::time`
+	args := []interface{}{nil, nil, req.MaxSegmentsPerDay, req.MaxMinutesPerWeek, req.WorkStart, req.WorkEnd}
+
+	var target string
+	if req.UserId != "" {
+		target = "user_id"
+		args[0] = req.UserId
+	} else {
+		target = "role"
+		args[1] = req.Role
+	}
+
+	query := `
+	INSERT INTO transcriber_limits (user_id, role, max_segments_per_day, max_minutes_per_week, work_start, work_end)
+	VALUES (` + values + `)
+	ON CONFLICT (` + target + `) DO UPDATE SET
+		max_segments_per_day = EXCLUDED.max_segments_per_day,
+		max_minutes_per_week = EXCLUDED.max_minutes_per_week,
+		work_start = EXCLUDED.work_start,
+		work_end = EXCLUDED.work_end,
+		updated_at = now()
+	RETURNING id`
+
+	var id int
+	err := r.pg.Pool.QueryRow(ctx, query, args...).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set transcriber limit: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *QuotaRepo) GetLimits(ctx context.Context) (*entity.TranscriberLimitList, error) {
+	query := `
+	SELECT
+		l.id,
+		l.user_id::text,
+		u.username,
+		l.role::text,
+		l.max_segments_per_day,
+		l.max_minutes_per_week::float8,
+		to_char(l.work_start, 'HH24:MI'),
+		to_char(l.work_end, 'HH24:MI'),
+		l.updated_at
+	FROM transcriber_limits l
+	LEFT JOIN users u ON u.id = l.user_id
+	ORDER BY l.role NULLS LAST, u.username
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcriber limits: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.TranscriberLimitList{Limits: []entity.TranscriberLimit{}}
+	for rows.Next() {
+		var updatedAt time.Time
+		limit := entity.TranscriberLimit{}
+		err := rows.Scan(
+			&limit.Id,
+			&limit.UserId,
+			&limit.Username,
+			&limit.Role,
+			&limit.MaxSegmentsPerDay,
+			&limit.MaxMinutesPerWeek,
+			&limit.WorkStart,
+			&limit.WorkEnd,
+			&updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcriber limit: %w", err)
+		}
+		limit.UpdatedAt = updatedAt.Format("2006-01-02 15:04:05")
+		res.Limits = append(res.Limits, limit)
+	}
+
+	return &res, rows.Err()
+}
+
+func (r *QuotaRepo) DeleteLimit(ctx context.Context, id int) error {
+	tag, err := r.pg.Pool.Exec(ctx, `DELETE FROM transcriber_limits WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete transcriber limit: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetUserQuota returns the effective limits of the user and their usage. Days and
+// weeks start at midnight and on Monday in the working timezone. A segment counts
+// once the user has finished or reported it.
+func (r *QuotaRepo) GetUserQuota(ctx context.Context, userId string) (*entity.UserQuota, error) {
+	res := entity.UserQuota{Timezone: r.config.Work.Timezone}
+
+	query := `
+	SELECT
+		COALESCE(ul.max_segments_per_day, rl.max_segments_per_day),
+		COALESCE(ul.max_minutes_per_week, rl.max_minutes_per_week)::float8,
+		to_char(CASE WHEN ul.work_start IS NOT NULL THEN ul.work_start ELSE rl.work_start END, 'HH24:MI'),
+		to_char(CASE WHEN ul.work_start IS NOT NULL THEN ul.work_end ELSE rl.work_end END, 'HH24:MI'),
+		CASE
+			WHEN COALESCE(ul.work_start, rl.work_start) IS NULL THEN true
+			WHEN ul.work_start IS NOT NULL AND ul.work_start <= ul.work_end
+				THEN (now() AT TIME ZONE $2)::time >= ul.work_start AND (now() AT TIME ZONE $2)::time < ul.work_end
+			WHEN ul.work_start IS NOT NULL
+				THEN (now() AT TIME ZONE $2)::time >= ul.work_start OR (now() AT TIME ZONE $2)::time < ul.work_end
+			WHEN rl.work_start <= rl.work_end
+				THEN (now() AT TIME ZONE $2)::time >= rl.work_start AND (now() AT TIME ZONE $2)::time < rl.work_end
+			ELSE (now() AT TIME ZONE $2)::time >= rl.work_start OR (now() AT TIME ZONE $2)::time < rl.work_end
+		END
+	FROM users u
+	LEFT JOIN transcriber_limits ul ON ul.user_id = u.id
+	LEFT JOIN transcriber_limits rl ON rl.role = u.role
+	WHERE u.id = $1 AND u.deleted_at = 0
+	`
+	err := r.pg.Pool.QueryRow(ctx, query, userId, res.Timezone).Scan(
+		&res.MaxSegmentsPerDay,
+		&res.MaxMinutesPerWeek,
+		&res.WorkStart,
+		&res.WorkEnd,
+		&res.WithinWorkingHours)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get transcriber limits: %w", err)
+	}
+
+	query = `
+	WITH bounds AS (
+		SELECT
+			(date_trunc('day', now() AT TIME ZONE $2) AT TIME ZONE $2)::timestamp AS day_start,
+			(date_trunc('week', now() AT TIME ZONE $2) AT TIME ZONE $2)::timestamp AS week_start
+	)
+	SELECT
+		COUNT(t.id) FILTER (WHERE t.updated_at >= b.day_start),
+		COALESCE(SUM(s.duration), 0) / 60
+	FROM bounds b
+	LEFT JOIN transcripts t ON t.user_id = $1 AND t.deleted_at = 0 AND t.status IN ('done', 'invalid')
+		AND t.updated_at >= b.week_start
+	LEFT JOIN audio_file_segments s ON s.id = t.segment_id
+	`
+	err = r.pg.Pool.QueryRow(ctx, query, userId, res.Timezone).Scan(&res.SegmentsToday, &res.MinutesThisWeek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcriber usage: %w", err)
+	}
+
+	if res.MaxSegmentsPerDay != nil {
+		remaining := max(*res.MaxSegmentsPerDay-res.SegmentsToday, 0)
+		res.RemainingSegmentsToday = &remaining
+	}
+	if res.MaxMinutesPerWeek != nil {
+		remaining := max(*res.MaxMinutesPerWeek-res.MinutesThisWeek, 0)
+		res.RemainingMinutesWeek = &remaining
+	}
+
+	return &res, nil
+}
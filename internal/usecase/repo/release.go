@@ -0,0 +1,543 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/eventtag"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/normalizer"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+// releaseRewriteBatch is the number of release items whose text is rewritten at a time.
+const releaseRewriteBatch = 1000
+
+type ReleaseRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewReleaseRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *ReleaseRepo {
+	return &ReleaseRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// querier is a pool or a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// releaseItemColumns are the columns scanReleaseItem reads.
+const releaseItemColumns = `
+	audio_filepath, duration, text, text_normalized, emotion, audio_id, segment_id, language, transcriber_id, split,
+	language_spans, manifest_line`
+
+// scanReleaseItem scans a row of releaseItemColumns into a manifest entry and the
+// manifest line frozen with it.
+func scanReleaseItem(rows pgx.Rows) (*entity.ManifestEntry, string, error) {
+	var entry entity.ManifestEntry
+	var line string
+	err := rows.Scan(
+		&entry.AudioFilepath,
+		&entry.Duration,
+		&entry.Text,
+		&entry.TextNormalized,
+		&entry.Emotion,
+		&entry.AudioId,
+		&entry.SegmentId,
+		&entry.Language,
+		&entry.TranscriberId,
+		&entry.Split,
+		&entry.LanguageSpans,
+		&line)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to scan release item: %w", err)
+	}
+	entry.Speaker = "audio_" + strconv.Itoa(entry.AudioId)
+
+	return &entry, line, nil
+}
+
+// manifestLine encodes entry the way the JSONL export writes it: one JSON object
+// without HTML escaping and a newline.
+func manifestLine(entry *entity.ManifestEntry) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return "", fmt.Errorf("failed to encode manifest line: %w", err)
+	}
+	return b.String(), nil
+}
+
+// streamReleaseItems calls fn with the manifest entries of a release and their frozen
+// manifest lines in audio and segment order, the order StreamManifest exports in.
+func streamReleaseItems(ctx context.Context, q querier, id int, fn func(*entity.ManifestEntry, string) error) error {
+	query := `SELECT ` + releaseItemColumns + `
+	FROM dataset_release_items
+	WHERE release_id = $1
+	ORDER BY audio_id, segment_id
+	`
+
+	rows, err := q.Query(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to get release items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, line, err := scanReleaseItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry, line); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over release items: %w", err)
+	}
+
+	return nil
+}
+
+// freezeReleaseManifest stores the manifest line of every item of release id, in
+// pages of releaseRewriteBatch, and returns the SHA-256 of the whole manifest.
+func freezeReleaseManifest(ctx context.Context, tr pgx.Tx, id int) (string, error) {
+	h := sha256.New()
+	afterAudio, afterSegment := 0, 0
+	for {
+		rows, err := tr.Query(ctx, `SELECT `+releaseItemColumns+`
+		FROM dataset_release_items
+		WHERE release_id = $1 AND (audio_id, segment_id) > ($2, $3)
+		ORDER BY audio_id, segment_id
+		LIMIT $4`, id, afterAudio, afterSegment, releaseRewriteBatch)
+		if err != nil {
+			return "", fmt.Errorf("failed to get release items: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for rows.Next() {
+			entry, _, err := scanReleaseItem(rows)
+			if err != nil {
+				rows.Close()
+				return "", err
+			}
+			line, err := manifestLine(entry)
+			if err != nil {
+				rows.Close()
+				return "", err
+			}
+			h.Write([]byte(line))
+			afterAudio, afterSegment = entry.AudioId, entry.SegmentId
+			batch.Queue(`
+			UPDATE dataset_release_items SET manifest_line = $3 WHERE release_id = $1 AND segment_id = $2`,
+				id, entry.SegmentId, line)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", fmt.Errorf("failed to iterate over release items: %w", err)
+		}
+		if batch.Len() > 0 {
+			if err := tr.SendBatch(ctx, batch).Close(); err != nil {
+				return "", fmt.Errorf("failed to save release manifest: %w", err)
+			}
+		}
+		if batch.Len() < releaseRewriteBatch {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+	}
+}
+
+// CreateRelease freezes the segments matching req.Filter into a new release: the
+// text and everything else the manifest needs is copied, so that the release
+// exports the same bytes however the transcripts change later.
+func (r *ReleaseRepo) CreateRelease(ctx context.Context, req *entity.CreateRelease) (*int, error) {
+	filter, err := json.Marshal(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode release filter: %w", err)
+	}
+
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var id int
+	err = tr.QueryRow(ctx, `
+	INSERT INTO dataset_releases (name, description, filter, created_by) VALUES ($1, NULLIF($2, ''), $3, $4)
+	RETURNING id`, req.Name, req.Description, filter, req.CreatedBy).Scan(&id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, err
+	}
+
+	conditions, args := manifestConditions(&req.Filter)
+	args = append(args, id)
+
+	_, err = tr.Exec(ctx, `
+	INSERT INTO dataset_release_items (
+		release_id, segment_id, audio_id, transcript_id, audio_filepath, duration, text, text_hash,
+		emotion, language, split, transcriber_id, language_spans
+	)
+	SELECT
+		$`+strconv.Itoa(len(args))+`,
+		s.id,
+		s.audio_id,
+		t.id,
+		s.filename,
+		COALESCE(s.duration, 0),
+		COALESCE(t.transcribe_text, ''),
+		encode(sha256(convert_to(COALESCE(t.transcribe_text, ''), 'UTF8')), 'hex'),
+		COALESCE(t.emotion, ''),
+		`+transcriptLanguage+`,
+		COALESCE(sp.split, ''),
+		COALESCE(t.user_id::text, ''),
+		t.language_spans
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	JOIN audio_files a ON a.id = s.audio_id
+	`+manifestSplitJoin+`
+	WHERE `+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to freeze release items: %w", err)
+	}
+
+	if req.Filter.Normalize || req.Filter.Script != "" || req.Filter.Tags != "" {
+		if err := r.rewriteReleaseText(ctx, tr, id, &req.Filter); err != nil {
+			tr.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	var segments, audioFiles int
+	var hours float64
+	err = tr.QueryRow(ctx, `
+	SELECT COUNT(*), COUNT(DISTINCT audio_id), COALESCE(SUM(duration), 0) / 3600
+	FROM dataset_release_items
+	WHERE release_id = $1`, id).Scan(&segments, &audioFiles, &hours)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to count release items: %w", err)
+	}
+
+	stats := entity.ReleaseStats{Languages: map[string]int{}, Splits: map[string]int{}, Emotions: map[string]int{}}
+	rows, err := tr.Query(ctx, `
+	SELECT 'language', language, COUNT(*) FROM dataset_release_items WHERE release_id = $1 GROUP BY language
+	UNION ALL
+	SELECT 'split', split, COUNT(*) FROM dataset_release_items WHERE release_id = $1 GROUP BY split
+	UNION ALL
+	SELECT 'emotion', emotion, COUNT(*) FROM dataset_release_items WHERE release_id = $1 GROUP BY emotion`, id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get release stats: %w", err)
+	}
+	for rows.Next() {
+		var group, value string
+		var count int
+		if err := rows.Scan(&group, &value, &count); err != nil {
+			rows.Close()
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to scan release stats: %w", err)
+		}
+		switch group {
+		case "language":
+			stats.Languages[value] = count
+		case "split":
+			stats.Splits[value] = count
+		case "emotion":
+			stats.Emotions[value] = count
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to iterate over release stats: %w", err)
+	}
+
+	manifestSha256, err := freezeReleaseManifest(ctx, tr, id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, err
+	}
+
+	statsJSON, err := json.Marshal(stats)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to encode release stats: %w", err)
+	}
+
+	_, err = tr.Exec(ctx, `
+	UPDATE dataset_releases SET segments = $2, audio_files = $3, hours = $4, stats = $5, manifest_sha256 = $6
+	WHERE id = $1`, id, segments, audioFiles, hours, statsJSON, manifestSha256)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to update release: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &id, nil
+}
+
+// rewriteReleaseText writes the frozen text of the items of release id in the script
+// and with the event tags of filter and fills in its normalized text when asked, in
+// pages of releaseRewriteBatch.
+func (r *ReleaseRepo) rewriteReleaseText(ctx context.Context, tr pgx.Tx, id int, filter *entity.ManifestReq) error {
+	var norm normalizer.Config
+	if filter.Normalize {
+		var err error
+		if norm, err = textNormalizer(r.config.Normalize); err != nil {
+			return err
+		}
+	}
+	var catalog *eventtag.Catalog
+	if filter.Tags != "" {
+		var err error
+		if catalog, err = loadEventTagCatalog(ctx, tr); err != nil {
+			return err
+		}
+	}
+
+	after := 0
+	for {
+		rows, err := tr.Query(ctx, `
+		SELECT segment_id, text, language, language_spans FROM dataset_release_items
+		WHERE release_id = $1 AND segment_id > $2
+		ORDER BY segment_id
+		LIMIT $3`, id, after, releaseRewriteBatch)
+		if err != nil {
+			return fmt.Errorf("failed to get release items: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for rows.Next() {
+			var text, language string
+			var spans []entity.LanguageSpan
+			if err := rows.Scan(&after, &text, &language, &spans); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan release item: %w", err)
+			}
+			text, spans = manifestText(text, language, spans, filter.Script)
+			typed, typedSpans := text, spans
+			if catalog != nil {
+				text, spans = taggedText(catalog, filter.Tags, typed, language, typedSpans)
+			}
+			normalized := ""
+			if filter.Normalize {
+				normalized = normalizedText(norm, catalog, filter.Tags, typed, language, typedSpans)
+			}
+			hash := sha256.Sum256([]byte(text))
+			batch.Queue(`
+			UPDATE dataset_release_items SET text = $3, text_hash = $4, text_normalized = $5, language_spans = $6
+			WHERE release_id = $1 AND segment_id = $2`,
+				id, after, text, hex.EncodeToString(hash[:]), normalized, spansJSON(spans))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate over release items: %w", err)
+		}
+		if batch.Len() == 0 {
+			return nil
+		}
+
+		if err := tr.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to save release text: %w", err)
+		}
+		if batch.Len() < releaseRewriteBatch {
+			return nil
+		}
+	}
+}
+
+const releaseColumns = `
+	r.id,
+	r.name,
+	r.description,
+	r.filter,
+	r.segments,
+	r.audio_files,
+	r.hours,
+	r.stats,
+	r.manifest_sha256,
+	r.created_by::text,
+	r.created_at`
+
+// scanRelease scans a row of releaseColumns.
+func scanRelease(row pgx.Row, dest ...interface{}) (*entity.Release, error) {
+	var (
+		release       entity.Release
+		filter, stats []byte
+		createdAt     time.Time
+	)
+	err := row.Scan(append(dest,
+		&release.Id,
+		&release.Name,
+		&release.Description,
+		&filter,
+		&release.Segments,
+		&release.AudioFiles,
+		&release.Hours,
+		&stats,
+		&release.ManifestSha256,
+		&release.CreatedBy,
+		&createdAt)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filter, &release.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode release filter: %w", err)
+	}
+	if err := json.Unmarshal(stats, &release.Stats); err != nil {
+		return nil, fmt.Errorf("failed to decode release stats: %w", err)
+	}
+	release.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+
+	return &release, nil
+}
+
+func (r *ReleaseRepo) GetRelease(ctx context.Context, id int) (*entity.Release, error) {
+	query := `SELECT ` + releaseColumns + ` FROM dataset_releases r WHERE r.id = $1`
+
+	return scanRelease(r.pg.Pool.QueryRow(ctx, query, id))
+}
+
+func (r *ReleaseRepo) GetReleases(ctx context.Context, req *entity.Filter) (*entity.ReleaseList, error) {
+	query := `
+	SELECT COUNT(r.id) OVER () AS total_count, ` + releaseColumns + `
+	FROM dataset_releases r
+	ORDER BY r.id DESC OFFSET $1 LIMIT $2
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, req.Offset, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release list: %w", err)
+	}
+	defer rows.Close()
+
+	releases := entity.ReleaseList{Releases: []entity.Release{}}
+	for rows.Next() {
+		release, err := scanRelease(rows, &releases.Count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan release: %w", err)
+		}
+		releases.Releases = append(releases.Releases, *release)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over releases: %w", err)
+	}
+
+	return &releases, nil
+}
+
+// StreamRelease calls fn with the frozen manifest entries of a release, in the order
+// and with the content they had when the release was made.
+func (r *ReleaseRepo) StreamRelease(ctx context.Context, id int, fn func(*entity.ManifestEntry) error) error {
+	return streamReleaseItems(ctx, r.pg.Pool, id, func(entry *entity.ManifestEntry, _ string) error {
+		return fn(entry)
+	})
+}
+
+// StreamReleaseManifest calls fn with the manifest lines of a release as they were
+// hashed into its manifest_sha256, newline included.
+func (r *ReleaseRepo) StreamReleaseManifest(ctx context.Context, id int, fn func(string) error) error {
+	return streamReleaseItems(ctx, r.pg.Pool, id, func(entry *entity.ManifestEntry, line string) error {
+		if line == "" {
+			var err error
+			if line, err = manifestLine(entry); err != nil {
+				return err
+			}
+		}
+		return fn(line)
+	})
+}
+
+// releaseDiff pairs the items of releases $1 and $2 by segment and keeps those that
+// were added, removed or whose text changed.
+const releaseDiff = `
+	SELECT
+		COALESCE(n.segment_id, o.segment_id) AS segment_id,
+		COALESCE(n.audio_id, o.audio_id) AS audio_id,
+		CASE
+			WHEN o.segment_id IS NULL THEN 'added'
+			WHEN n.segment_id IS NULL THEN 'removed'
+			ELSE 'changed'
+		END AS kind,
+		o.text AS old_text,
+		n.text AS new_text
+	FROM (SELECT * FROM dataset_release_items WHERE release_id = $1) o
+	FULL JOIN (SELECT * FROM dataset_release_items WHERE release_id = $2) n ON n.segment_id = o.segment_id
+	WHERE o.segment_id IS NULL OR n.segment_id IS NULL OR o.text_hash <> n.text_hash`
+
+// DiffReleases compares release req.From with release req.To.
+func (r *ReleaseRepo) DiffReleases(ctx context.Context, req *entity.ReleaseDiffReq) (*entity.ReleaseDiff, error) {
+	res := entity.ReleaseDiff{From: req.From, To: req.To, Changes: []entity.ReleaseChange{}}
+
+	for _, id := range []int{req.From, req.To} {
+		var exists bool
+		err := r.pg.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM dataset_releases WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get release: %w", err)
+		}
+		if !exists {
+			return nil, pgx.ErrNoRows
+		}
+	}
+
+	err := r.pg.Pool.QueryRow(ctx, `
+	SELECT
+		COUNT(*) FILTER (WHERE kind = 'added'),
+		COUNT(*) FILTER (WHERE kind = 'removed'),
+		COUNT(*) FILTER (WHERE kind = 'changed')
+	FROM (`+releaseDiff+`) d`, req.From, req.To).Scan(&res.Added, &res.Removed, &res.Changed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count release diff: %w", err)
+	}
+
+	query := `
+	SELECT COUNT(*) OVER () AS total_count, d.segment_id, d.audio_id, d.kind, d.old_text, d.new_text
+	FROM (` + releaseDiff + `) d
+	WHERE $3 = '' OR d.kind = $3
+	ORDER BY d.audio_id, d.segment_id
+	OFFSET $4 LIMIT $5
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, req.From, req.To, req.Kind, req.Filter.Offset, req.Filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release diff: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change entity.ReleaseChange
+		err := rows.Scan(&res.Count, &change.SegmentId, &change.AudioId, &change.Kind, &change.OldText, &change.NewText)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan release change: %w", err)
+		}
+		res.Changes = append(res.Changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over release diff: %w", err)
+	}
+
+	return &res, nil
+}
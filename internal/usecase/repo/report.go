@@ -0,0 +1,456 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+// openReport matches transcripts t whose report waits for a resolution. A re-chunking
+// claim older than an hour counts as open again, since its chunking never finished.
+const openReport = `(t.report_status = 'open' OR (t.report_status = 'rechunking' AND t.report_resolved_at < now() - interval '1 hour'))`
+
+type ReportRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewReportRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *ReportRepo {
+	return &ReportRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *ReportRepo) CreateReason(ctx context.Context, req *entity.CreateReportReason) (*int, error) {
+	query := `
+	INSERT INTO report_reasons (code, name_uz, name_ru, name_cy, sort_order)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
+	var id int
+	err := r.pg.Pool.QueryRow(ctx, query, req.Code, req.Name.Uz, req.Name.Ru, req.Name.Cy, req.SortOrder).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report reason: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *ReportRepo) UpdateReason(ctx context.Context, req *entity.UpdateReportReason) error {
+	query := `
+	UPDATE
+		report_reasons
+	SET`
+
+	var conditions []string
+	var args []interface{}
+
+	if req.Name.Uz != "" {
+		conditions = append(conditions, " name_uz = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Uz)
+	}
+	if req.Name.Ru != "" {
+		conditions = append(conditions, " name_ru = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Ru)
+	}
+	if req.Name.Cy != "" {
+		conditions = append(conditions, " name_cy = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name.Cy)
+	}
+	if req.IsActive != nil {
+		conditions = append(conditions, " is_active = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.IsActive)
+	}
+	if req.SortOrder != nil {
+		conditions = append(conditions, " sort_order = $"+strconv.Itoa(len(args)+1))
+		args = append(args, *req.SortOrder)
+	}
+
+	if len(conditions) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	conditions = append(conditions, " updated_at = now()")
+	query += strings.Join(conditions, ", ")
+	query += " WHERE id = $" + strconv.Itoa(len(args)+1) + " AND deleted_at = 0"
+	args = append(args, req.Id)
+
+	tag, err := r.pg.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update report reason: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *ReportRepo) DeleteReason(ctx context.Context, id int) error {
+	query := `
+	UPDATE report_reasons
+	SET deleted_at = EXTRACT(EPOCH FROM NOW())
+	WHERE id = $1 AND deleted_at = 0
+	`
+	tag, err := r.pg.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete report reason: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *ReportRepo) GetReasons(ctx context.Context, activeOnly bool) (*entity.ReportReasonList, error) {
+	query := `
+	SELECT id, code, name_uz, name_ru, name_cy, is_active, sort_order
+	FROM report_reasons
+	WHERE deleted_at = 0
+	`
+	if activeOnly {
+		query += " AND is_active"
+	}
+	query += " ORDER BY sort_order, code"
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report reasons: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.ReportReasonList{Reasons: []entity.ReportReason{}}
+	for rows.Next() {
+		reason := entity.ReportReason{}
+		err := rows.Scan(&reason.Id, &reason.Code, &reason.Name.Uz, &reason.Name.Ru, &reason.Name.Cy, &reason.IsActive, &reason.SortOrder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report reason: %w", err)
+		}
+		res.Reasons = append(res.Reasons, reason)
+	}
+
+	return &res, rows.Err()
+}
+
+// IsActiveReason reports whether code is an active reason of the catalog.
+func (r *ReportRepo) IsActiveReason(ctx context.Context, code string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM report_reasons WHERE code = $1 AND is_active AND deleted_at = 0)`
+
+	var exists bool
+	err := r.pg.Pool.QueryRow(ctx, query, code).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check report reason: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetQueue lists the open reports grouped by reason. Each group holds its total
+// count and one page of its oldest reports.
+func (r *ReportRepo) GetQueue(ctx context.Context, req *entity.ReportQueueReq) (*entity.ReportQueue, error) {
+	query := `
+	SELECT
+		t.report_reason,
+		COALESCE(rr.name_uz, t.report_reason),
+		COALESCE(rr.name_ru, t.report_reason),
+		COALESCE(rr.name_cy, t.report_reason),
+		COUNT(*)
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	LEFT JOIN report_reasons rr ON rr.code = t.report_reason AND rr.deleted_at = 0
+	WHERE t.deleted_at = 0 AND s.deleted_at = 0 AND t.status = 'invalid' AND ` + openReport + `
+		AND ($1 = '' OR t.report_reason = $1)
+	GROUP BY t.report_reason, rr.name_uz, rr.name_ru, rr.name_cy, rr.sort_order
+	ORDER BY rr.sort_order NULLS LAST, t.report_reason
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query, req.Reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report queue: %w", err)
+	}
+
+	res := entity.ReportQueue{Groups: []entity.ReportQueueGroup{}}
+	groups := make(map[string]int)
+	for rows.Next() {
+		group := entity.ReportQueueGroup{Items: []entity.ReportQueueItem{}}
+		err := rows.Scan(&group.Reason, &group.Name.Uz, &group.Name.Ru, &group.Name.Cy, &group.Count)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan report queue group: %w", err)
+		}
+		groups[group.Reason] = len(res.Groups)
+		res.Groups = append(res.Groups, group)
+		res.Total += group.Count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over report queue groups: %w", err)
+	}
+
+	query = `
+	SELECT * FROM (
+		SELECT
+			t.report_reason,
+			t.segment_id,
+			s.audio_id,
+			a.filename,
+			s.filename,
+			COALESCE(s.duration, 0),
+			t.transcribe_text,
+			t.report_text,
+			t.user_id::text,
+			u.username,
+			t.updated_at,
+			ROW_NUMBER() OVER (PARTITION BY t.report_reason ORDER BY t.updated_at, t.segment_id) AS rn
+		FROM transcripts t
+		JOIN audio_file_segments s ON s.id = t.segment_id
+		JOIN audio_files a ON a.id = s.audio_id
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE t.deleted_at = 0 AND s.deleted_at = 0 AND t.status = 'invalid' AND ` + openReport + `
+			AND ($1 = '' OR t.report_reason = $1)
+	) q
+	WHERE rn > $2 AND rn <= $2 + $3
+	ORDER BY report_reason, rn
+	`
+
+	rows, err = r.pg.Pool.Query(ctx, query, req.Reason, req.Filter.Offset, req.Filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report queue items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			reason     string
+			reportedAt time.Time
+			rn         int
+		)
+		item := entity.ReportQueueItem{}
+		err := rows.Scan(
+			&reason,
+			&item.SegmentId,
+			&item.AudioId,
+			&item.AudioName,
+			&item.ChunkUrl,
+			&item.Duration,
+			&item.TranscriptText,
+			&item.ReportText,
+			&item.ReporterId,
+			&item.Reporter,
+			&reportedAt,
+			&rn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report queue item: %w", err)
+		}
+		item.ReportedAt = reportedAt.Format("2006-01-02 15:04:05")
+
+		if i, ok := groups[reason]; ok {
+			res.Groups[i].Items = append(res.Groups[i].Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over report queue items: %w", err)
+	}
+
+	return &res, nil
+}
+
+// reportedSegments returns the segment itself or, with entireAudio, every segment
+// of its audio file that has an open report, and locks their transcripts so a
+// concurrent resolve waits and then finds nothing open.
+func reportedSegments(ctx context.Context, tr pgx.Tx, segmentId int, entireAudio bool) ([]int, error) {
+	query := `
+	SELECT t.segment_id
+	FROM transcripts t
+	JOIN audio_file_segments s ON s.id = t.segment_id
+	WHERE t.deleted_at = 0 AND s.deleted_at = 0 AND ` + openReport + `
+		AND (t.segment_id = $1 OR ($2 AND s.audio_id = (SELECT audio_id FROM audio_file_segments WHERE id = $1)))
+	ORDER BY t.segment_id
+	FOR UPDATE OF t
+	`
+
+	rows, err := tr.Query(ctx, query, segmentId, entireAudio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reported segments: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan reported segment: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over reported segments: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return ids, nil
+}
+
+// Resolve closes open reports. Accepting keeps the segment invalid; reopening sends
+// it back to transcription. Re-chunking goes through ClaimRechunk and FinishRechunk.
+func (r *ReportRepo) Resolve(ctx context.Context, req *entity.ResolveReport) error {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	ids, err := reportedSegments(ctx, tr, req.SegmentId, req.EntireAudio)
+	if err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+
+	switch req.Action {
+	case entity.ReportActionAccept:
+		query := `
+		UPDATE transcripts
+		SET report_status = 'accepted', report_resolved_by = $2, report_resolved_at = now(), updated_at = now()
+		WHERE segment_id = ANY($1) AND deleted_at = 0
+		`
+		_, err = tr.Exec(ctx, query, ids, req.UserID)
+		if err == nil {
+			err = insertRevision(ctx, tr, ids, req.UserID, "report_accept", req.Note)
+		}
+
+	case entity.ReportActionReopen:
+		err = reopenTranscripts(ctx, tr, ids, req.UserID, req.Note)
+
+	default:
+		err = fmt.Errorf("unknown report action %q", req.Action)
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to resolve report: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimRechunk marks the open report of the segment as being re-chunked by the user
+// and commits right away, so the chunking runs without holding locks and a
+// concurrent resolve finds nothing open. It returns pgx.ErrNoRows when the segment
+// has no open report.
+func (r *ReportRepo) ClaimRechunk(ctx context.Context, segmentId int, userId *string) error {
+	query := `
+	UPDATE transcripts t
+	SET report_status = 'rechunking', report_resolved_by = $2, report_resolved_at = now()
+	FROM audio_file_segments s
+	WHERE s.id = t.segment_id AND t.segment_id = $1 AND t.deleted_at = 0 AND s.deleted_at = 0 AND ` + openReport
+
+	tag, err := r.pg.Pool.Exec(ctx, query, segmentId, userId)
+	if err != nil {
+		return fmt.Errorf("failed to claim report: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// ReleaseRechunk hands the user's re-chunking claim back to the queue after the
+// chunking failed.
+func (r *ReportRepo) ReleaseRechunk(ctx context.Context, segmentId int, userId *string) error {
+	query := `
+	UPDATE transcripts
+	SET report_status = 'open', report_resolved_by = NULL, report_resolved_at = NULL
+	WHERE segment_id = $1 AND deleted_at = 0 AND report_status = 'rechunking' AND report_resolved_by = $2
+	`
+
+	_, err := r.pg.Pool.Exec(ctx, query, segmentId, userId)
+	if err != nil {
+		return fmt.Errorf("failed to release report: %w", err)
+	}
+
+	return nil
+}
+
+// FinishRechunk adds the new chunks, retires the claimed segment and closes its
+// report in one transaction, so a failure leaves neither the chunks nor a closed
+// report behind. It returns pgx.ErrNoRows when the user no longer holds the claim.
+func (r *ReportRepo) FinishRechunk(ctx context.Context, req *entity.ResolveReport, chunks []entity.CreateAudioSegment) error {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var id int
+	err = tr.QueryRow(ctx, `
+	SELECT segment_id
+	FROM transcripts
+	WHERE segment_id = $1 AND deleted_at = 0 AND report_status = 'rechunking' AND report_resolved_by = $2
+	FOR UPDATE`, req.SegmentId, req.UserID).Scan(&id)
+	if err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+	ids := []int{id}
+
+	for i := range chunks {
+		if err = insertSegment(ctx, tr, &chunks[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = insertRevision(ctx, tr, ids, req.UserID, "rechunk", req.Note)
+	}
+	if err == nil {
+		// retire the segment first so the trigger ignores it
+		_, err = tr.Exec(ctx, `
+		UPDATE audio_file_segments
+		SET deleted_at = EXTRACT(EPOCH FROM NOW()), updated_at = now()
+		WHERE id = ANY($1) AND deleted_at = 0`, ids)
+	}
+	if err == nil {
+		_, err = tr.Exec(ctx, `
+		UPDATE transcripts
+		SET report_status = 'rechunked', report_resolved_by = $2, report_resolved_at = now(),
+			deleted_at = EXTRACT(EPOCH FROM NOW()), updated_at = now()
+		WHERE segment_id = ANY($1) AND deleted_at = 0`, ids, req.UserID)
+	}
+	if err == nil {
+		// touch a remaining transcript of the file so its status is recalculated
+		_, err = tr.Exec(ctx, `
+		UPDATE transcripts
+		SET updated_at = updated_at
+		WHERE deleted_at = 0 AND segment_id = (
+			SELECT MIN(s.id) FROM audio_file_segments s
+			WHERE s.deleted_at = 0 AND s.audio_id = (SELECT audio_id FROM audio_file_segments WHERE id = $1)
+		)`, req.SegmentId)
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to re-chunk segment: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+)
+
+type SkillRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewSkillRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *SkillRepo {
+	return &SkillRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *SkillRepo) Create(ctx context.Context, req *entity.CreateSkill) (*int, error) {
+	query := `INSERT INTO skills (code, name, kind) VALUES ($1, $2, $3) RETURNING id`
+
+	var id int
+	err := r.pg.Pool.QueryRow(ctx, query, req.Code, req.Name, req.Kind).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create skill: %w", err)
+	}
+
+	return &id, nil
+}
+
+func (r *SkillRepo) Update(ctx context.Context, req *entity.UpdateSkill) error {
+	query := `
+	UPDATE
+		skills
+	SET`
+
+	var conditions []string
+	var args []interface{}
+
+	if req.Name != "" {
+		conditions = append(conditions, " name = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Name)
+	}
+	if req.Kind != "" {
+		conditions = append(conditions, " kind = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.Kind)
+	}
+
+	if len(conditions) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	conditions = append(conditions, " updated_at = now()")
+	query += strings.Join(conditions, ", ")
+	query += " WHERE id = $" + strconv.Itoa(len(args)+1) + " AND deleted_at = 0"
+	args = append(args, req.Id)
+
+	tag, err := r.pg.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update skill: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Delete removes the skill from the catalog. Users and audio files keep their links
+// to it, but a deleted skill no longer restricts assignment.
+func (r *SkillRepo) Delete(ctx context.Context, id int) error {
+	query := `
+	UPDATE skills
+	SET deleted_at = EXTRACT(EPOCH FROM NOW())
+	WHERE id = $1 AND deleted_at = 0
+	`
+	tag, err := r.pg.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete skill: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *SkillRepo) GetList(ctx context.Context, kind string) (*entity.SkillList, error) {
+	query := `
+	SELECT id, code, name, kind
+	FROM skills
+	WHERE deleted_at = 0 AND ($1 = '' OR kind = $1)
+	ORDER BY kind, code
+	`
+
+	return r.list(ctx, query, kind)
+}
+
+func (r *SkillRepo) GetUserSkills(ctx context.Context, userId string) (*entity.SkillList, error) {
+	query := `
+	SELECT sk.id, sk.code, sk.name, sk.kind
+	FROM user_skills us
+	JOIN skills sk ON sk.id = us.skill_id
+	WHERE us.user_id = $1 AND sk.deleted_at = 0
+	ORDER BY sk.kind, sk.code
+	`
+
+	return r.list(ctx, query, userId)
+}
+
+func (r *SkillRepo) GetAudioSkills(ctx context.Context, audioId int) (*entity.SkillList, error) {
+	query := `
+	SELECT sk.id, sk.code, sk.name, sk.kind
+	FROM audio_file_skills fs
+	JOIN skills sk ON sk.id = fs.skill_id
+	WHERE fs.audio_id = $1 AND sk.deleted_at = 0
+	ORDER BY sk.kind, sk.code
+	`
+
+	return r.list(ctx, query, audioId)
+}
+
+func (r *SkillRepo) list(ctx context.Context, query string, args ...interface{}) (*entity.SkillList, error) {
+	rows, err := r.pg.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get skills: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.SkillList{Skills: []entity.Skill{}}
+	for rows.Next() {
+		skill := entity.Skill{}
+		err := rows.Scan(&skill.Id, &skill.Code, &skill.Name, &skill.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan skill: %w", err)
+		}
+		res.Skills = append(res.Skills, skill)
+	}
+
+	return &res, rows.Err()
+}
+
+// SetUserSkills replaces the skills of the user.
+func (r *SkillRepo) SetUserSkills(ctx context.Context, userId string, codes []string) error {
+	var exists bool
+	err := r.pg.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at = 0)`, userId).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return pgx.ErrNoRows
+	}
+
+	return r.set(ctx, "user_skills", "user_id", "uuid", userId, codes)
+}
+
+// SetAudioSkills replaces the skills a transcriber needs to get the audio file.
+func (r *SkillRepo) SetAudioSkills(ctx context.Context, audioId int, codes []string) error {
+	var exists bool
+	err := r.pg.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM audio_files WHERE id = $1 AND deleted_at = 0)`, audioId).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check audio file: %w", err)
+	}
+	if !exists {
+		return pgx.ErrNoRows
+	}
+
+	return r.set(ctx, "audio_file_skills", "audio_id", "int", audioId, codes)
+}
+
+func (r *SkillRepo) set(ctx context.Context, table, column, columnType string, owner interface{}, codes []string) error {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var ids []int
+	rows, err := tr.Query(ctx, `SELECT id FROM skills WHERE code = ANY($1) AND deleted_at = 0`, codes)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to get skills: %w", err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tr.Rollback(ctx)
+			return fmt.Errorf("failed to scan skill: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to iterate over skills: %w", err)
+	}
+
+	if len(ids) != len(uniqueStrings(codes)) {
+		tr.Rollback(ctx)
+		return errors.New("unknown skill code")
+	}
+
+	_, err = tr.Exec(ctx, "DELETE FROM "+table+" WHERE "+column+" = $1", owner)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to clear skills: %w", err)
+	}
+
+	_, err = tr.Exec(ctx, "INSERT INTO "+table+" ("+column+", skill_id) SELECT $1::"+columnType+", unnest($2::int[])", owner, ids)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to set skills: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,271 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/mirjalilova/voice_transcribe/config"
+	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/logger"
+	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
+	"github.com/mirjalilova/voice_transcribe/pkg/split"
+)
+
+type SplitRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewSplitRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *SplitRepo {
+	return &SplitRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// callDurationBucket returns the stratum of an audio file of dur seconds.
+func callDurationBucket(dur float64) string {
+	switch {
+	case dur < 60:
+		return "0-1m"
+	case dur < 180:
+		return "1-3m"
+	case dur < 600:
+		return "3-10m"
+	default:
+		return "10m+"
+	}
+}
+
+// splitSettings fills the empty fields of req from the config and checks them.
+func (r *SplitRepo) splitSettings(req *entity.SplitAssignReq) ([]split.Ratio, error) {
+	if req.Seed == "" {
+		req.Seed = r.config.Split.Seed
+	}
+	if req.Stratify == "" {
+		req.Stratify = r.config.Split.Stratify
+	}
+	if req.Stratify == "" {
+		req.Stratify = entity.StratifyNone
+	}
+	if req.Stratify != entity.StratifyNone && req.Stratify != entity.StratifyDuration && req.Stratify != entity.StratifyEmotion {
+		return nil, fmt.Errorf("unknown stratification %q", req.Stratify)
+	}
+
+	var ratios []split.Ratio
+	if len(req.Ratios) == 0 {
+		parsed, err := split.ParseRatios(r.config.Split.Ratios)
+		if err != nil {
+			return nil, err
+		}
+		ratios = parsed
+		for _, ratio := range ratios {
+			req.Ratios = append(req.Ratios, entity.SplitRatio{Name: ratio.Name, Ratio: ratio.Weight})
+		}
+	} else {
+		for _, ratio := range req.Ratios {
+			ratios = append(ratios, split.Ratio{Name: ratio.Name, Weight: ratio.Ratio})
+		}
+	}
+	if err := split.Validate(ratios); err != nil {
+		return nil, err
+	}
+
+	return ratios, nil
+}
+
+// AssignSplits puts every audio file without a split, or every audio file when
+// req.Reassign is set, into a split. Without stratification the split comes from the
+// hash of the file id alone, so a file lands in the same split whichever run assigns
+// it. With it, the files are grouped by stratum, the duration of the whole call or
+// its most common emotion, and each stratum is balanced on its own, counting the
+// files of the stratum that already have a split. Files without done transcripts wait
+// until they have some, as their stratum is not known yet. A file keeps its split
+// once assigned, whatever comes later, which is what keeps a call or speaker out of
+// two splits.
+func (r *SplitRepo) AssignSplits(ctx context.Context, req *entity.SplitAssignReq) (*entity.SplitAssignResult, error) {
+	ratios, err := r.splitSettings(req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT
+		a.id,
+		(
+			SELECT COALESCE(SUM(s.duration), 0)
+			FROM audio_file_segments s
+			WHERE s.audio_id = a.id AND s.deleted_at = 0
+		),
+		(
+			SELECT COALESCE(MODE() WITHIN GROUP (ORDER BY t.emotion), '')
+			FROM transcripts t
+			JOIN audio_file_segments s ON s.id = t.segment_id
+			WHERE s.audio_id = a.id AND s.deleted_at = 0 AND t.deleted_at = 0 AND t.status = 'done' AND t.emotion <> ''
+		),
+		(SELECT sp.split FROM audio_splits sp WHERE sp.audio_id = a.id)
+	FROM audio_files a
+	WHERE a.deleted_at = 0
+		AND EXISTS (
+			SELECT 1
+			FROM audio_file_segments s
+			JOIN transcripts t ON t.segment_id = s.id
+			WHERE s.audio_id = a.id AND s.deleted_at = 0 AND t.deleted_at = 0 AND t.status = 'done'
+		)
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio files: %w", err)
+	}
+	defer rows.Close()
+
+	res := entity.SplitAssignResult{Seed: req.Seed, Ratios: req.Ratios, Stratify: req.Stratify}
+	keys := []int{}
+	strata := make(map[int]string)
+	counts := make(map[string]map[string]int)
+	for rows.Next() {
+		var (
+			id       int
+			duration float64
+			emotion  string
+			assigned *string
+		)
+		if err := rows.Scan(&id, &duration, &emotion, &assigned); err != nil {
+			return nil, fmt.Errorf("failed to scan audio file: %w", err)
+		}
+
+		var stratum string
+		switch req.Stratify {
+		case entity.StratifyDuration:
+			stratum = callDurationBucket(duration)
+		case entity.StratifyEmotion:
+			stratum = emotion
+		}
+
+		if assigned != nil && !req.Reassign {
+			if counts[stratum] == nil {
+				counts[stratum] = make(map[string]int)
+			}
+			counts[stratum][*assigned]++
+			res.Kept++
+			continue
+		}
+
+		keys = append(keys, id)
+		strata[id] = stratum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over audio files: %w", err)
+	}
+	rows.Close()
+
+	var splits map[int]string
+	if req.Stratify == entity.StratifyNone {
+		splits = split.Assign(req.Seed, ratios, keys)
+	} else {
+		splits = split.Stratified(req.Seed, ratios, strata, counts)
+	}
+
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if req.Reassign {
+		if _, err := tr.Exec(ctx, `DELETE FROM audio_splits`); err != nil {
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to clear audio splits: %w", err)
+		}
+	}
+
+	batch := &pgx.Batch{}
+	for _, key := range keys {
+		batch.Queue(`
+		INSERT INTO audio_splits (audio_id, split, stratum, seed) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (audio_id) DO NOTHING`,
+			key, splits[key], strata[key], req.Seed)
+	}
+	br := tr.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := br.Exec()
+		if err != nil {
+			br.Close()
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to save audio splits: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			res.Assigned++
+		} else {
+			// Assigned concurrently since it was read.
+			res.Kept++
+		}
+	}
+	if err := br.Close(); err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to save audio splits: %w", err)
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetSplitStats returns the audio files, done segments and hours of every split.
+func (r *SplitRepo) GetSplitStats(ctx context.Context) (*entity.SplitStatsList, error) {
+	query := `
+	SELECT
+		sp.split,
+		COUNT(DISTINCT sp.audio_id),
+		COUNT(t.id),
+		COALESCE(SUM(s.duration) FILTER (WHERE t.id IS NOT NULL), 0) / 3600
+	FROM audio_splits sp
+	JOIN audio_files a ON a.id = sp.audio_id AND a.deleted_at = 0
+	LEFT JOIN audio_file_segments s ON s.audio_id = sp.audio_id AND s.deleted_at = 0
+	LEFT JOIN transcripts t ON t.segment_id = s.id AND t.deleted_at = 0 AND t.status = 'done'
+	GROUP BY sp.split
+	`
+
+	rows, err := r.pg.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get split stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := entity.SplitStatsList{Splits: []entity.SplitStats{}}
+	var hours float64
+	for rows.Next() {
+		var s entity.SplitStats
+		if err := rows.Scan(&s.Split, &s.AudioFiles, &s.Segments, &s.Hours); err != nil {
+			return nil, fmt.Errorf("failed to scan split stats: %w", err)
+		}
+		hours += s.Hours
+		stats.Splits = append(stats.Splits, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over split stats: %w", err)
+	}
+
+	for i := range stats.Splits {
+		if hours > 0 {
+			stats.Splits[i].Share = stats.Splits[i].Hours / hours
+		}
+	}
+	sort.Slice(stats.Splits, func(i, j int) bool { return stats.Splits[i].Split < stats.Splits[j].Split })
+
+	err = r.pg.Pool.QueryRow(ctx, `
+	SELECT COUNT(*) FROM audio_files a
+	WHERE a.deleted_at = 0 AND NOT EXISTS (SELECT 1 FROM audio_splits sp WHERE sp.audio_id = a.id)`).Scan(&stats.Unassigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unassigned audio files: %w", err)
+	}
+
+	return &stats, nil
+}
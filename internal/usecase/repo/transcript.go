@@ -2,14 +2,18 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/mirjalilova/voice_transcribe/config"
 	"github.com/mirjalilova/voice_transcribe/internal/entity"
+	"github.com/mirjalilova/voice_transcribe/pkg/diff"
+	"github.com/mirjalilova/voice_transcribe/pkg/langspan"
 	"github.com/mirjalilova/voice_transcribe/pkg/logger"
 	"github.com/mirjalilova/voice_transcribe/pkg/postgres"
 )
@@ -65,6 +69,12 @@ func (r *TranscriptRepo) Update(ctx context.Context, req *entity.UpdateTranscrip
 	if req.TranscriptText != "" && req.TranscriptText != "string" {
 		conditions = append(conditions, " transcribe_text = $"+strconv.Itoa(len(args)+1))
 		args = append(args, req.TranscriptText)
+		if req.Language != "" {
+			conditions = append(conditions, " language = $"+strconv.Itoa(len(args)+1))
+			args = append(args, req.Language)
+		}
+		conditions = append(conditions, " language_spans = $"+strconv.Itoa(len(args)+1))
+		args = append(args, spansJSON(req.LanguageSpans))
 	}
 	if req.ReportText != "" && req.ReportText != "string" {
 		conditions = append(conditions, " report_text = $"+strconv.Itoa(len(args)+1))
@@ -74,24 +84,45 @@ func (r *TranscriptRepo) Update(ctx context.Context, req *entity.UpdateTranscrip
 		conditions = append(conditions, " emotion = $"+strconv.Itoa(len(args)+1))
 		args = append(args, req.Emotion)
 	}
+	if req.ReportReason != "" {
+		conditions = append(conditions, " report_reason = $"+strconv.Itoa(len(args)+1))
+		args = append(args, req.ReportReason)
+	}
 
 	if len(conditions) == 0 {
 		slog.Warn("nothing to update")
 		return nil
 	}
 
-	conditions = append(conditions, " updated_at = now()")
+	conditions = append(conditions, " updated_at = now()", " version = version + 1")
 	query += strings.Join(conditions, ", ")
 	query += " WHERE segment_id = $" + strconv.Itoa(len(args)+1) + " AND deleted_at = 0"
 	args = append(args, req.Id)
+	query += " AND version = $" + strconv.Itoa(len(args)+1)
+	args = append(args, req.Version)
 
-	_, err = tr.Exec(ctx, query, args...)
+	tag, err := tr.Exec(ctx, query, args...)
 	if err != nil {
 		tr.Rollback(ctx)
 		return fmt.Errorf("failed to update transcript: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		tr.Rollback(ctx)
 
-	if req.ReportText != "" && req.ReportText != "string" {
+		var exists bool
+		err = r.pg.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM transcripts WHERE segment_id = $1 AND deleted_at = 0)`, req.Id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check transcript: %w", err)
+		}
+		if !exists {
+			return pgx.ErrNoRows
+		}
+		return entity.ErrTranscriptVersionConflict
+	}
+
+	revised := []int{req.Id}
+	reported := (req.ReportText != "" && req.ReportText != "string") || req.ReportReason != ""
+	if reported {
 		if req.EntireAudioInvalid {
 			query1 := `
 					WITH target_audio_file AS (
@@ -106,15 +137,35 @@ func (r *TranscriptRepo) Update(ctx context.Context, req *entity.UpdateTranscrip
 					)
 					UPDATE transcripts
 					SET status = 'invalid',
-						updated_at = NOW()
-					WHERE segment_id IN (SELECT id FROM segments_of_audio)
+						report_reason = COALESCE(NULLIF($2, ''), 'other'),
+						report_status = 'open',
+						report_resolved_by = NULL,
+						report_resolved_at = NULL,
+						updated_at = NOW(),
+						version = version + 1
+					WHERE segment_id IN (SELECT id FROM segments_of_audio) AND segment_id <> $1 AND deleted_at = 0
+					RETURNING segment_id
 				`
 
-			_, err := tr.Exec(ctx, query1, req.Id)
+			rows, err := tr.Query(ctx, query1, req.Id, req.ReportReason)
 			if err != nil {
 				tr.Rollback(ctx)
 				return fmt.Errorf("failed to update transcript status: %w", err)
 			}
+			for rows.Next() {
+				var segmentId int
+				if err := rows.Scan(&segmentId); err != nil {
+					rows.Close()
+					tr.Rollback(ctx)
+					return fmt.Errorf("failed to scan transcript: %w", err)
+				}
+				revised = append(revised, segmentId)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				tr.Rollback(ctx)
+				return fmt.Errorf("failed to update transcript status: %w", err)
+			}
 
 			query2 := `
 					UPDATE audio_files
@@ -134,7 +185,16 @@ func (r *TranscriptRepo) Update(ctx context.Context, req *entity.UpdateTranscrip
 			}
 		}
 
-		query = `UPDATE transcripts SET status = 'invalid', updated_at = now() WHERE segment_id = $1 AND deleted_at = 0`
+		query = `
+		UPDATE transcripts
+		SET
+			status = 'invalid',
+			report_reason = COALESCE(report_reason, 'other'),
+			report_status = 'open',
+			report_resolved_by = NULL,
+			report_resolved_at = NULL,
+			updated_at = now()
+		WHERE segment_id = $1 AND deleted_at = 0`
 		_, err = tr.Exec(ctx, query, req.Id)
 		if err != nil {
 			tr.Rollback(ctx)
@@ -143,6 +203,12 @@ func (r *TranscriptRepo) Update(ctx context.Context, req *entity.UpdateTranscrip
 
 	}
 
+	err = insertRevision(ctx, tr, revised, req.UserID, "update", "")
+	if err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+
 	if err := tr.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -189,10 +255,15 @@ func (r *TranscriptRepo) GetById(ctx context.Context, id int) (*entity.Transcrip
 		COALESCE(t.ai_text::text, '') AS ai_text,
 		COALESCE(NULLIF(t.transcribe_text, ''), '') AS transcribe_text,
 		COALESCE(NULLIF(t.report_text, ''), '') AS report_text,
+		t.report_reason,
+		t.report_status::text,
 		COALESCE(NULLIF(t.transcribe_option, ''), '') AS transcribe_option,
 		t.status,
 		t.created_at,
-		COALESCE(NULLIF(t.emotion, ''), '') AS emotion
+		COALESCE(NULLIF(t.emotion, ''), '') AS emotion,
+		t.version,
+		t.language,
+		t.language_spans
 	FROM transcripts t
 	LEFT JOIN users u ON t.user_id = u.id
 	JOIN audio_file_segments s ON t.segment_id = s.id
@@ -210,10 +281,15 @@ func (r *TranscriptRepo) GetById(ctx context.Context, id int) (*entity.Transcrip
 		&transcript.AIText,
 		&transcript.TranscriptText,
 		&transcript.ReportText,
+		&transcript.ReportReason,
+		&transcript.ReportStatus,
 		&transcript.TranscriptOption,
 		&transcript.Status,
 		&createdAt,
-		&transcript.Emotion)
+		&transcript.Emotion,
+		&transcript.Version,
+		&transcript.Language,
+		&transcript.LanguageSpans)
 	if err != nil {
 		tr.Rollback(ctx)
 		return nil, fmt.Errorf("failed to get transcripts: %w", err)
@@ -257,7 +333,10 @@ func (r *TranscriptRepo) GetList(ctx context.Context, req *entity.GetTranscriptR
 		COALESCE(NULLIF(t.transcribe_text, ''), '') AS transcribe_text,
 		COALESCE(NULLIF(t.report_text, ''), '') AS report_text,
 		t.status,
-		t.created_at
+		t.version,
+		t.created_at,
+		t.language,
+		t.language_spans
 	FROM transcripts t
 	LEFT JOIN users u ON t.user_id = u.id
 	JOIN audio_file_segments s ON t.segment_id = s.id
@@ -316,7 +395,10 @@ func (r *TranscriptRepo) GetList(ctx context.Context, req *entity.GetTranscriptR
 			&transcript.TranscriptText,
 			&transcript.ReportText,
 			&transcript.Status,
-			&createdAt)
+			&transcript.Version,
+			&createdAt,
+			&transcript.Language,
+			&transcript.LanguageSpans)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transcript: %w", err)
 		}
@@ -341,3 +423,512 @@ func (r *TranscriptRepo) StartTranscripts(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// insertRevision snapshots the current state of the segments' transcripts into their history.
+func insertRevision(ctx context.Context, tr pgx.Tx, segmentIds []int, userId *string, action, note string) error {
+	query := `
+	INSERT INTO transcript_revisions (
+		transcript_id, segment_id, user_id, transcribe_text, report_text, emotion, status, action, note, version,
+		language, language_spans
+	)
+	SELECT id, segment_id, $2, transcribe_text, report_text, emotion, status, $3, NULLIF($4, ''), version, language, language_spans
+	FROM transcripts
+	WHERE segment_id = ANY($1) AND deleted_at = 0
+	`
+	_, err := tr.Exec(ctx, query, segmentIds, userId, action, note)
+	if err != nil {
+		return fmt.Errorf("failed to create transcript revision: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TranscriptRepo) GetHistory(ctx context.Context, id int) (*entity.TranscriptHistory, error) {
+	res := entity.TranscriptHistory{SegmentId: id, Revisions: []entity.TranscriptRevision{}}
+
+	query := `SELECT ai_text FROM transcripts WHERE segment_id = $1 AND deleted_at = 0`
+	err := r.pg.Pool.QueryRow(ctx, query, id).Scan(&res.AIText)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT
+		r.id,
+		r.user_id::text,
+		u.username,
+		r.transcribe_text,
+		r.report_text,
+		r.emotion,
+		r.status,
+		r.action,
+		r.note,
+		r.created_at,
+		r.language,
+		r.language_spans
+	FROM transcript_revisions r
+	LEFT JOIN users u ON r.user_id = u.id
+	WHERE r.segment_id = $1
+	ORDER BY r.id
+	`
+	rows, err := r.pg.Pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcript history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var createdAt time.Time
+		rev := entity.TranscriptRevision{}
+		err := rows.Scan(
+			&rev.Id,
+			&rev.UserId,
+			&rev.Username,
+			&rev.TranscriptText,
+			&rev.ReportText,
+			&rev.Emotion,
+			&rev.Status,
+			&rev.Action,
+			&rev.Note,
+			&createdAt,
+			&rev.Language,
+			&rev.LanguageSpans)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript revision: %w", err)
+		}
+		rev.CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+		res.Revisions = append(res.Revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over transcript revisions: %w", err)
+	}
+
+	return &res, nil
+}
+
+// revisionText returns the transcribe_text of a revision of the segment, where
+// revision 0 is the original ai_text and a nil revision is the latest one.
+func (r *TranscriptRepo) revisionText(ctx context.Context, segmentId int, revisionId *int) (int, string, error) {
+	var (
+		id   int
+		text *string
+	)
+
+	var err error
+	switch {
+	case revisionId != nil && *revisionId == 0:
+		query := `SELECT 0, ai_text FROM transcripts WHERE segment_id = $1 AND deleted_at = 0`
+		err = r.pg.Pool.QueryRow(ctx, query, segmentId).Scan(&id, &text)
+	case revisionId != nil:
+		query := `SELECT id, transcribe_text FROM transcript_revisions WHERE segment_id = $1 AND id = $2`
+		err = r.pg.Pool.QueryRow(ctx, query, segmentId, *revisionId).Scan(&id, &text)
+	default:
+		query := `SELECT id, transcribe_text FROM transcript_revisions WHERE segment_id = $1 ORDER BY id DESC LIMIT 1`
+		err = r.pg.Pool.QueryRow(ctx, query, segmentId).Scan(&id, &text)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if text == nil {
+		return id, "", nil
+	}
+	return id, *text, nil
+}
+
+func (r *TranscriptRepo) Diff(ctx context.Context, req *entity.TranscriptDiffReq) (*entity.TranscriptDiff, error) {
+	fromId, fromText, err := r.revisionText(ctx, req.SegmentId, &req.FromRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	toId, toText, err := r.revisionText(ctx, req.SegmentId, req.ToRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	res := entity.TranscriptDiff{
+		SegmentId:    req.SegmentId,
+		FromRevision: fromId,
+		ToRevision:   toId,
+		Ops:          []entity.DiffOp{},
+	}
+	for _, op := range diff.Words(fromText, toText) {
+		res.Ops = append(res.Ops, entity.DiffOp(op))
+	}
+
+	return &res, nil
+}
+
+// Revert restores the transcript to the given revision and records the revert as a
+// new revision. Revision 0 restores the original ai_text. A revision from before
+// language spans has its "(ru: ...)" passages taken out into spans, like an edit, so
+// the old convention does not come back; languages that are no longer configured
+// give entity.ErrRevertLanguages.
+func (r *TranscriptRepo) Revert(ctx context.Context, req *entity.RevertTranscript) error {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var (
+		text       *string
+		language   string
+		spans      []entity.LanguageSpan
+		reportText *string
+		emotion    *string
+		status     string
+	)
+	if req.RevisionId == 0 {
+		query := `SELECT ai_text, language, emotion FROM transcripts WHERE segment_id = $1 AND deleted_at = 0 FOR UPDATE`
+		err = tr.QueryRow(ctx, query, req.SegmentId).Scan(&text, &language, &emotion)
+		status = "done"
+	} else {
+		query := `
+		SELECT r.transcribe_text, COALESCE(r.language, t.language), COALESCE(r.language_spans, '[]'), r.report_text, r.emotion, r.status::text
+		FROM transcripts t
+		JOIN transcript_revisions r ON r.segment_id = t.segment_id AND r.id = $2
+		WHERE t.segment_id = $1 AND t.deleted_at = 0
+		FOR UPDATE OF t
+		`
+		err = tr.QueryRow(ctx, query, req.SegmentId, req.RevisionId).Scan(&text, &language, &spans, &reportText, &emotion, &status)
+	}
+	if err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+
+	if text != nil && *text != "" {
+		plain, lang, parsed := *text, language, toLangspans(spans)
+		if langspan.HasInline(plain) {
+			plain, lang, parsed = langspan.Parse(plain, lang)
+		}
+		if err := langspan.Validate(plain, lang, parsed, req.Languages); err != nil {
+			tr.Rollback(ctx)
+			return fmt.Errorf("%w: %v", entity.ErrRevertLanguages, err)
+		}
+		text, language, spans = &plain, lang, fromLangspans(parsed)
+	}
+
+	query := `
+	UPDATE transcripts
+	SET
+		transcribe_text = $2,
+		language = $3,
+		language_spans = $4,
+		report_text = $5,
+		emotion = $6,
+		status = $7::transcript_status,
+		user_id = $8,
+		updated_at = now(),
+		version = version + 1
+	WHERE segment_id = $1 AND deleted_at = 0
+	`
+	_, err = tr.Exec(ctx, query, req.SegmentId, text, language, spansJSON(spans), reportText, emotion, status, req.UserID)
+	if err != nil {
+		tr.Rollback(ctx)
+		return fmt.Errorf("failed to revert transcript: %w", err)
+	}
+
+	err = insertRevision(ctx, tr, []int{req.SegmentId}, req.UserID, "revert", fmt.Sprintf("revision %d", req.RevisionId))
+	if err != nil {
+		tr.Rollback(ctx)
+		return err
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// inlineLanguagePattern matches "(ru: ...)" passages typed into a transcript.
+const inlineLanguagePattern = `\(\s*[a-zA-Z]{2,3}\s*:[^()]*\)`
+
+// languageMigrateBatch is the number of transcripts migrated at a time.
+const languageMigrateBatch = 500
+
+// languageSpansMigration names the migration to language spans in data_migrations.
+const languageSpansMigration = "language_spans"
+
+// MigrateLanguageSpans moves the transcripts that still mark passages inline as
+// "(ru: ...)" to plain text with language spans, and records a revision for each.
+// Their updated_at is kept, since they were not submitted again. It runs once: when
+// no inline transcript is left, the migration is recorded as finished and later
+// calls return right away. It returns the number of transcripts migrated.
+func (r *TranscriptRepo) MigrateLanguageSpans(ctx context.Context) (int, error) {
+	var finished bool
+	err := r.pg.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM data_migrations WHERE name = $1)`,
+		languageSpansMigration).Scan(&finished)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get data migration: %w", err)
+	}
+	if finished {
+		return 0, nil
+	}
+
+	migrated := 0
+	for {
+		n, err := r.migrateLanguageSpans(ctx)
+		migrated += n
+		if err != nil {
+			return migrated, err
+		}
+
+		var left bool
+		err = r.pg.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM transcripts WHERE deleted_at = 0 AND transcribe_text ~ $1)`,
+			inlineLanguagePattern).Scan(&left)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to check inline language transcripts: %w", err)
+		}
+		if !left {
+			break
+		}
+		if n == 0 {
+			// What is left does not parse into spans; it stays for the next start.
+			return migrated, fmt.Errorf("failed to migrate every inline language transcript")
+		}
+		// Transcripts edited during the pass are picked up by the next one.
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, `
+	INSERT INTO data_migrations (name, migrated) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+		languageSpansMigration, migrated)
+	if err != nil {
+		return migrated, fmt.Errorf("failed to record data migration: %w", err)
+	}
+
+	return migrated, nil
+}
+
+// migrateLanguageSpans makes one pass over the inline language transcripts. A
+// transcript edited meanwhile is left out.
+func (r *TranscriptRepo) migrateLanguageSpans(ctx context.Context) (int, error) {
+	type inline struct {
+		id, segmentId, version int
+		text, language         string
+	}
+
+	migrated, after := 0, 0
+	for {
+		rows, err := r.pg.Pool.Query(ctx, `
+		SELECT id, segment_id, version, transcribe_text, language
+		FROM transcripts
+		WHERE deleted_at = 0 AND id > $1 AND transcribe_text ~ $2
+		ORDER BY id
+		LIMIT $3`, after, inlineLanguagePattern, languageMigrateBatch)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to get inline language transcripts: %w", err)
+		}
+		var found []inline
+		for rows.Next() {
+			var t inline
+			if err := rows.Scan(&t.id, &t.segmentId, &t.version, &t.text, &t.language); err != nil {
+				rows.Close()
+				return migrated, fmt.Errorf("failed to scan transcript: %w", err)
+			}
+			found = append(found, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return migrated, fmt.Errorf("failed to iterate over transcripts: %w", err)
+		}
+		if len(found) == 0 {
+			return migrated, nil
+		}
+		after = found[len(found)-1].id
+
+		batch := &pgx.Batch{}
+		for _, t := range found {
+			text, language, spans := langspan.Parse(t.text, t.language)
+			batch.Queue(`
+			UPDATE transcripts
+			SET transcribe_text = $2, language = $3, language_spans = $4, version = version + 1
+			WHERE id = $1 AND version = $5 AND deleted_at = 0`,
+				t.id, text, language, spansJSON(fromLangspans(spans)), t.version)
+		}
+
+		tr, err := r.pg.Pool.Begin(ctx)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		var segmentIds []int
+		br := tr.SendBatch(ctx, batch)
+		for _, t := range found {
+			tag, err := br.Exec()
+			if err != nil {
+				br.Close()
+				tr.Rollback(ctx)
+				return migrated, fmt.Errorf("failed to migrate transcript: %w", err)
+			}
+			if tag.RowsAffected() > 0 {
+				segmentIds = append(segmentIds, t.segmentId)
+			}
+		}
+		if err := br.Close(); err != nil {
+			tr.Rollback(ctx)
+			return migrated, fmt.Errorf("failed to migrate transcripts: %w", err)
+		}
+
+		if err := insertRevision(ctx, tr, segmentIds, nil, "migrate", "language spans"); err != nil {
+			tr.Rollback(ctx)
+			return migrated, err
+		}
+		// Measurements keep their review; only their language follows.
+		_, err = tr.Exec(ctx, `
+		UPDATE transcript_quality q SET language = t.language
+		FROM transcripts t
+		WHERE q.transcript_id = t.id AND t.segment_id = ANY($1) AND t.deleted_at = 0`, segmentIds)
+		if err != nil {
+			tr.Rollback(ctx)
+			return migrated, fmt.Errorf("failed to update quality language: %w", err)
+		}
+		if err := tr.Commit(ctx); err != nil {
+			return migrated, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		migrated += len(segmentIds)
+	}
+}
+
+// reopenTranscripts puts the segments' transcripts back to 'ready' so they go through
+// transcription again. The text is kept and the change is recorded as a revision;
+// the audio file status follows through trg_update_audio_status.
+func reopenTranscripts(ctx context.Context, tr pgx.Tx, segmentIds []int, userId *string, note string) error {
+	query := `
+	UPDATE transcripts
+	SET
+		status = 'ready',
+		report_status = CASE WHEN report_status IN ('open', 'rechunking') THEN 'reopened'::report_status ELSE report_status END,
+		report_resolved_by = CASE WHEN report_status IN ('open', 'rechunking') THEN $2::uuid ELSE report_resolved_by END,
+		report_resolved_at = CASE WHEN report_status IN ('open', 'rechunking') THEN now() ELSE report_resolved_at END,
+		updated_at = now(),
+		version = version + 1
+	WHERE segment_id = ANY($1) AND deleted_at = 0
+	`
+	_, err := tr.Exec(ctx, query, segmentIds, userId)
+	if err != nil {
+		return fmt.Errorf("failed to reopen transcripts: %w", err)
+	}
+
+	return insertRevision(ctx, tr, segmentIds, userId, "reopen", note)
+}
+
+// Heartbeat credits the time since the previous heartbeat of the user's open session
+// on the segment to the session, the transcript and the user's day. A session idle
+// for longer than the configured timeout is closed at its last heartbeat without
+// credit and a new one is started. Sessions on other segments are closed, since the
+// user moved on from them. It returns pgx.ErrNoRows for an unknown segment and
+// entity.ErrSegmentNotAssigned for a segment of a file assigned to someone else.
+func (r *TranscriptRepo) Heartbeat(ctx context.Context, req *entity.Heartbeat) (*entity.HeartbeatResult, error) {
+	tr, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var assigned bool
+	query := `
+	SELECT a.user_id IS NOT DISTINCT FROM $2::uuid
+	FROM audio_file_segments s
+	JOIN audio_files a ON a.id = s.audio_id
+	JOIN transcripts t ON t.segment_id = s.id
+	WHERE s.id = $1 AND s.deleted_at = 0 AND a.deleted_at = 0 AND t.deleted_at = 0
+	`
+	err = tr.QueryRow(ctx, query, req.SegmentId, req.UserId).Scan(&assigned)
+	if err != nil {
+		tr.Rollback(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pgx.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get segment assignment: %w", err)
+	}
+	if !assigned {
+		tr.Rollback(ctx)
+		return nil, entity.ErrSegmentNotAssigned
+	}
+
+	query = `
+	UPDATE editor_sessions
+	SET ended_at = last_beat_at
+	WHERE user_id = $1 AND ended_at IS NULL
+		AND (segment_id <> $2 OR last_beat_at < now() - make_interval(secs => $3))
+	`
+	_, err = tr.Exec(ctx, query, req.UserId, req.SegmentId, r.config.Work.IdleTimeout.Seconds())
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to close idle editor sessions: %w", err)
+	}
+
+	// A concurrent heartbeat may open the session first; the insert then does nothing
+	// and the session is resumed.
+	res := entity.HeartbeatResult{Resumed: true}
+	query = `
+	INSERT INTO editor_sessions (user_id, segment_id) VALUES ($1, $2)
+	ON CONFLICT (user_id, segment_id) WHERE ended_at IS NULL DO NOTHING
+	RETURNING id
+	`
+	err = tr.QueryRow(ctx, query, req.UserId, req.SegmentId).Scan(&res.SessionId)
+	if err == nil {
+		res.Resumed = false
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to open editor session: %w", err)
+	}
+
+	var elapsed int
+	query = `
+	SELECT id, active_seconds, EXTRACT(EPOCH FROM now() - last_beat_at)::int
+	FROM editor_sessions
+	WHERE user_id = $1 AND segment_id = $2 AND ended_at IS NULL
+	FOR UPDATE
+	`
+	err = tr.QueryRow(ctx, query, req.UserId, req.SegmentId).Scan(&res.SessionId, &res.SessionSeconds, &elapsed)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to get editor session: %w", err)
+	}
+
+	query = `
+	UPDATE editor_sessions
+	SET active_seconds = active_seconds + $2, last_beat_at = now(), ended_at = CASE WHEN $3 THEN now() END
+	WHERE id = $1
+	`
+	_, err = tr.Exec(ctx, query, res.SessionId, elapsed, req.End)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to update editor session: %w", err)
+	}
+	res.SessionSeconds += elapsed
+
+	query = `
+	UPDATE transcripts
+	SET active_seconds = active_seconds + $2, viewed_at = COALESCE(viewed_at, now())
+	WHERE segment_id = $1 AND deleted_at = 0
+	RETURNING active_seconds
+	`
+	err = tr.QueryRow(ctx, query, req.SegmentId, elapsed).Scan(&res.ActiveSeconds)
+	if err != nil {
+		tr.Rollback(ctx)
+		return nil, fmt.Errorf("failed to update transcript active seconds: %w", err)
+	}
+
+	if elapsed > 0 {
+		query = `
+		INSERT INTO user_activity_daily (user_id, day, active_seconds)
+		VALUES ($1, (now() AT TIME ZONE $2)::date, $3)
+		ON CONFLICT (user_id, day) DO UPDATE SET active_seconds = user_activity_daily.active_seconds + EXCLUDED.active_seconds
+		`
+		_, err = tr.Exec(ctx, query, req.UserId, r.config.Work.Timezone, elapsed)
+		if err != nil {
+			tr.Rollback(ctx)
+			return nil, fmt.Errorf("failed to update user activity: %w", err)
+		}
+	}
+
+	if err := tr.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &res, nil
+}
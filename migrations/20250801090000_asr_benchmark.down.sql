@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS asr_hypotheses;
+DROP TABLE IF EXISTS asr_models;
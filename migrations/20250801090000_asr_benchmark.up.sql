@@ -0,0 +1,26 @@
+CREATE TABLE asr_models (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT unique_asr_model_name_deleted_at UNIQUE (name, deleted_at)
+);
+
+CREATE TABLE asr_hypotheses (
+    id SERIAL PRIMARY KEY,
+    model_id INT NOT NULL REFERENCES asr_models(id),
+    segment_id INT NOT NULL REFERENCES audio_file_segments(id),
+    hypothesis TEXT NOT NULL,
+    word_errors INT NOT NULL,
+    ref_words INT NOT NULL,
+    char_errors INT NOT NULL,
+    ref_chars INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (model_id, segment_id)
+);
+
+CREATE INDEX idx_asr_hypotheses_segment_id ON asr_hypotheses (segment_id);
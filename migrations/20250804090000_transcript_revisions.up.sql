@@ -0,0 +1,21 @@
+CREATE TABLE transcript_revisions (
+    id SERIAL PRIMARY KEY,
+    transcript_id INT NOT NULL REFERENCES transcripts(id),
+    segment_id INT NOT NULL REFERENCES audio_file_segments(id),
+    user_id UUID,
+    transcribe_text TEXT,
+    report_text TEXT,
+    emotion VARCHAR(50),
+    status transcript_status NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcript_revisions_segment_id ON transcript_revisions (segment_id, id);
+
+-- Keep the state of already edited transcripts as their first revision.
+INSERT INTO transcript_revisions (transcript_id, segment_id, user_id, transcribe_text, report_text, emotion, status, action, created_at)
+SELECT id, segment_id, user_id, transcribe_text, report_text, emotion, status, 'import', updated_at
+FROM transcripts
+WHERE deleted_at = 0 AND status <> 'ready';
@@ -0,0 +1,3 @@
+ALTER TABLE transcript_revisions DROP COLUMN IF EXISTS version;
+
+ALTER TABLE transcripts DROP COLUMN IF EXISTS version;
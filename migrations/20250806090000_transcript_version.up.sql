@@ -0,0 +1,3 @@
+ALTER TABLE transcripts ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE transcript_revisions ADD COLUMN version INT;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_transcripts_report_status;
+
+ALTER TABLE transcripts
+    DROP COLUMN IF EXISTS report_reason,
+    DROP COLUMN IF EXISTS report_status,
+    DROP COLUMN IF EXISTS report_resolved_by,
+    DROP COLUMN IF EXISTS report_resolved_at;
+
+DROP TYPE IF EXISTS report_status;
+
+DROP TABLE IF EXISTS report_reasons;
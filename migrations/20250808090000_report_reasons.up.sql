@@ -0,0 +1,37 @@
+CREATE TABLE report_reasons (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name_uz VARCHAR(100) NOT NULL,
+    name_ru VARCHAR(100) NOT NULL,
+    name_cy VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT unique_report_reason_code_deleted_at UNIQUE (code, deleted_at)
+);
+
+INSERT INTO report_reasons (code, name_uz, name_ru, name_cy, sort_order) VALUES
+    ('noise',          'Shovqin',         'Шум',               'Шовқин',         1),
+    ('wrong_language', 'Boshqa til',      'Другой язык',       'Бошқа тил',      2),
+    ('music',          'Musiqa',          'Музыка',            'Мусиқа',         3),
+    ('silence',        'Sukunat',         'Тишина',            'Сукунат',        4),
+    ('cut_word',       'Kesilgan so''z',  'Обрезанное слово',  'Кесилган сўз',   5),
+    ('pii',            'Shaxsiy ma''lumot', 'Персональные данные', 'Шахсий маълумот', 6),
+    ('other',          'Boshqa',          'Другое',            'Бошқа',          99);
+
+CREATE TYPE report_status AS ENUM('open', 'accepted', 'reopened', 'rechunked');
+
+ALTER TABLE transcripts
+    ADD COLUMN report_reason VARCHAR(50),
+    ADD COLUMN report_status report_status,
+    ADD COLUMN report_resolved_by UUID,
+    ADD COLUMN report_resolved_at TIMESTAMP;
+
+-- Reports made before the catalog existed only have free text.
+UPDATE transcripts
+SET report_reason = 'other', report_status = 'open'
+WHERE status = 'invalid' AND deleted_at = 0;
+
+CREATE INDEX idx_transcripts_report_status ON transcripts (report_status, report_reason) WHERE deleted_at = 0;
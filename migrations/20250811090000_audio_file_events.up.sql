@@ -0,0 +1,13 @@
+CREATE TABLE audio_file_events (
+    id SERIAL PRIMARY KEY,
+    audio_id INT NOT NULL REFERENCES audio_files(id),
+    action VARCHAR(20) NOT NULL,
+    from_user_id UUID,
+    to_user_id UUID,
+    actor_id UUID,
+    reason TEXT,
+    segment_ids INT[],
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audio_file_events_audio_id ON audio_file_events (audio_id, id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_audio_files_queue;
+
+ALTER TABLE audio_files DROP COLUMN IF EXISTS priority;
+
+DROP TABLE IF EXISTS audio_file_skills;
+DROP TABLE IF EXISTS user_skills;
+DROP TABLE IF EXISTS skills;
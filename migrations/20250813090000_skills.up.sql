@@ -0,0 +1,42 @@
+CREATE TABLE skills (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('language', 'dialect', 'domain')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT unique_skill_code_deleted_at UNIQUE (code, deleted_at)
+);
+
+INSERT INTO skills (code, name, kind) VALUES
+    ('uz', 'Uzbek', 'language'),
+    ('ru', 'Russian', 'language');
+
+CREATE TABLE user_skills (
+    user_id UUID NOT NULL REFERENCES users(id),
+    skill_id INT NOT NULL REFERENCES skills(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, skill_id)
+);
+
+CREATE TABLE audio_file_skills (
+    audio_id INT NOT NULL REFERENCES audio_files(id),
+    skill_id INT NOT NULL REFERENCES skills(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (audio_id, skill_id)
+);
+
+ALTER TABLE audio_files ADD COLUMN priority INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_audio_files_queue ON audio_files (priority DESC, created_at) WHERE deleted_at = 0;
+
+-- Files that are not finished yet and already contain Russian speech need a Russian speaker.
+INSERT INTO audio_file_skills (audio_id, skill_id)
+SELECT DISTINCT s.audio_id, sk.id
+FROM transcripts t
+JOIN audio_file_segments s ON s.id = t.segment_id
+JOIN audio_files a ON a.id = s.audio_id
+JOIN skills sk ON sk.code = 'ru' AND sk.deleted_at = 0
+WHERE a.deleted_at = 0 AND a.status IN ('pending', 'unassigned')
+    AND (t.ai_text ILIKE '%(ru:%' OR t.transcribe_text ILIKE '%(ru:%');
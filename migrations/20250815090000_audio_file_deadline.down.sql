@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audio_files_queue;
+
+CREATE INDEX idx_audio_files_queue ON audio_files (priority DESC, created_at) WHERE deleted_at = 0;
+
+ALTER TABLE audio_files DROP COLUMN IF EXISTS deadline;
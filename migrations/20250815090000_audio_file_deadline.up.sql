@@ -0,0 +1,5 @@
+ALTER TABLE audio_files ADD COLUMN deadline TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_audio_files_queue;
+
+CREATE INDEX idx_audio_files_queue ON audio_files (priority DESC, deadline ASC NULLS LAST, created_at) WHERE deleted_at = 0;
@@ -0,0 +1,13 @@
+CREATE TABLE transcriber_limits (
+    id SERIAL PRIMARY KEY,
+    user_id UUID UNIQUE REFERENCES users(id),
+    role role UNIQUE,
+    max_segments_per_day INT,
+    max_minutes_per_week NUMERIC(10, 2),
+    work_start TIME,
+    work_end TIME,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT transcriber_limits_target CHECK ((user_id IS NULL) <> (role IS NULL)),
+    CONSTRAINT transcriber_limits_work_hours CHECK ((work_start IS NULL) = (work_end IS NULL))
+);
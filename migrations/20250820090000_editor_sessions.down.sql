@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_activity_daily;
+DROP TABLE IF EXISTS editor_sessions;
+
+ALTER TABLE transcripts DROP COLUMN IF EXISTS active_seconds;
+
+ALTER TABLE transcripts ALTER COLUMN viewed_at SET DEFAULT '2025-05-01 00:00:00';
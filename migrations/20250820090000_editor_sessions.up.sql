@@ -0,0 +1,25 @@
+ALTER TABLE transcripts ALTER COLUMN viewed_at DROP DEFAULT;
+
+UPDATE transcripts SET viewed_at = NULL WHERE viewed_at = '2025-05-01 00:00:00';
+
+ALTER TABLE transcripts ADD COLUMN active_seconds INT NOT NULL DEFAULT 0;
+
+CREATE TABLE editor_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    segment_id INT NOT NULL REFERENCES audio_file_segments(id),
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_beat_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    active_seconds INT NOT NULL DEFAULT 0,
+    ended_at TIMESTAMP
+);
+
+CREATE INDEX idx_editor_sessions_open ON editor_sessions (user_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_editor_sessions_segment_id ON editor_sessions (segment_id);
+
+CREATE TABLE user_activity_daily (
+    user_id UUID NOT NULL REFERENCES users(id),
+    day DATE NOT NULL,
+    active_seconds INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
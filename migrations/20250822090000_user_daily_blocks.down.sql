@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transcripts_done_updated_at;
+
+DROP TABLE IF EXISTS daily_block_rollups;
+DROP TABLE IF EXISTS user_daily_blocks;
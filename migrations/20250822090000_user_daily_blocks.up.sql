@@ -0,0 +1,15 @@
+CREATE TABLE user_daily_blocks (
+    user_id UUID NOT NULL,
+    day DATE NOT NULL,
+    blocks INT NOT NULL,
+    PRIMARY KEY (day, user_id)
+);
+
+-- Days whose blocks are computed, with the block length they were computed for.
+CREATE TABLE daily_block_rollups (
+    day DATE PRIMARY KEY,
+    block_seconds INT NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcripts_done_updated_at ON transcripts (updated_at) WHERE status = 'done' AND deleted_at = 0;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_audio_file_events_action;
+
+DROP TABLE IF EXISTS stats_snapshots;
+
+DROP TRIGGER IF EXISTS trg_transcripts_audio_file_text ON transcripts;
+DROP FUNCTION IF EXISTS update_audio_file_text();
+DROP TABLE IF EXISTS audio_file_texts;
+
+DROP TRIGGER IF EXISTS trg_transcripts_stat_counters ON transcripts;
+DROP TRIGGER IF EXISTS trg_audio_files_stat_counters ON audio_files;
+DROP FUNCTION IF EXISTS update_stat_counters();
+DROP FUNCTION IF EXISTS stat_counter_add(TEXT, INT);
+DROP TABLE IF EXISTS stat_counters;
@@ -0,0 +1,132 @@
+-- Row counts per table and status, kept current by triggers.
+CREATE TABLE stat_counters (
+    name VARCHAR(50) PRIMARY KEY,
+    value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION stat_counter_add(p_name TEXT, p_delta INT)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO stat_counters (name, value, updated_at)
+    VALUES (p_name, p_delta, NOW())
+    ON CONFLICT (name) DO UPDATE
+    SET value = stat_counters.value + EXCLUDED.value,
+        updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_stat_counters()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_active BOOLEAN := CASE WHEN TG_OP IN ('UPDATE', 'DELETE') THEN OLD.deleted_at = 0 ELSE FALSE END;
+    new_active BOOLEAN := CASE WHEN TG_OP IN ('INSERT', 'UPDATE') THEN NEW.deleted_at = 0 ELSE FALSE END;
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.status = NEW.status AND old_active = new_active THEN
+        RETURN NULL;
+    END IF;
+
+    IF old_active AND NOT new_active THEN
+        PERFORM stat_counter_add(TG_TABLE_NAME, -1);
+    ELSIF new_active AND NOT old_active THEN
+        PERFORM stat_counter_add(TG_TABLE_NAME, 1);
+    END IF;
+
+    IF old_active THEN
+        PERFORM stat_counter_add(TG_TABLE_NAME || ':' || OLD.status, -1);
+    END IF;
+    IF new_active THEN
+        PERFORM stat_counter_add(TG_TABLE_NAME || ':' || NEW.status, 1);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audio_files_stat_counters
+AFTER INSERT OR UPDATE OF status, deleted_at OR DELETE ON audio_files
+FOR EACH ROW
+EXECUTE FUNCTION update_stat_counters();
+
+CREATE TRIGGER trg_transcripts_stat_counters
+AFTER INSERT OR UPDATE OF status, deleted_at OR DELETE ON transcripts
+FOR EACH ROW
+EXECUTE FUNCTION update_stat_counters();
+
+INSERT INTO stat_counters (name, value)
+SELECT 'audio_files', COUNT(*) FROM audio_files WHERE deleted_at = 0
+UNION ALL
+SELECT 'audio_files:' || status, COUNT(*) FROM audio_files WHERE deleted_at = 0 GROUP BY status
+UNION ALL
+SELECT 'transcripts', COUNT(*) FROM transcripts WHERE deleted_at = 0
+UNION ALL
+SELECT 'transcripts:' || status, COUNT(*) FROM transcripts WHERE deleted_at = 0 GROUP BY status;
+
+-- The text of every audio file in segment order, as shown by the dataset viewer.
+CREATE TABLE audio_file_texts (
+    audio_id INT PRIMARY KEY REFERENCES audio_files(id),
+    all_transcripts TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION update_audio_file_text()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_segment_id INT;
+    v_audio_id INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_segment_id := OLD.segment_id;
+    ELSE
+        IF TG_OP = 'UPDATE'
+            AND OLD.transcribe_text IS NOT DISTINCT FROM NEW.transcribe_text
+            AND OLD.deleted_at = NEW.deleted_at THEN
+            RETURN NULL;
+        END IF;
+        v_segment_id := NEW.segment_id;
+    END IF;
+
+    SELECT audio_id INTO v_audio_id FROM audio_file_segments WHERE id = v_segment_id;
+    IF v_audio_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO audio_file_texts (audio_id, all_transcripts, updated_at)
+    VALUES (
+        v_audio_id,
+        (
+            SELECT STRING_AGG(t.transcribe_text, ' ' ORDER BY t.id)
+            FROM audio_file_segments s
+            JOIN transcripts t ON t.segment_id = s.id
+            WHERE s.audio_id = v_audio_id AND t.deleted_at = 0
+        ),
+        NOW()
+    )
+    ON CONFLICT (audio_id) DO UPDATE
+    SET all_transcripts = EXCLUDED.all_transcripts,
+        updated_at = EXCLUDED.updated_at;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_transcripts_audio_file_text
+AFTER INSERT OR UPDATE OF transcribe_text, deleted_at OR DELETE ON transcripts
+FOR EACH ROW
+EXECUTE FUNCTION update_audio_file_text();
+
+INSERT INTO audio_file_texts (audio_id, all_transcripts)
+SELECT s.audio_id, STRING_AGG(t.transcribe_text, ' ' ORDER BY t.id)
+FROM audio_file_segments s
+JOIN transcripts t ON t.segment_id = s.id
+WHERE t.deleted_at = 0
+GROUP BY s.audio_id;
+
+-- Results of expensive statistics, refreshed by a background job.
+CREATE TABLE stats_snapshots (
+    name VARCHAR(50) PRIMARY KEY,
+    payload JSONB NOT NULL,
+    refreshed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audio_file_events_action ON audio_file_events (action, created_at);
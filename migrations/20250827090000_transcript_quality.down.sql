@@ -0,0 +1 @@
+DROP TABLE IF EXISTS transcript_quality;
@@ -0,0 +1,21 @@
+-- Speaking-rate measurements of done transcripts. A transcript is flagged when its rate
+-- falls outside the fences of its language or its text is empty on a long segment.
+CREATE TABLE transcript_quality (
+    transcript_id INT PRIMARY KEY REFERENCES transcripts(id),
+    language VARCHAR(10) NOT NULL,
+    duration FLOAT NOT NULL,
+    chars INT NOT NULL,
+    words INT NOT NULL,
+    chars_per_sec FLOAT NOT NULL,
+    words_per_sec FLOAT NOT NULL,
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,
+    review VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (review IN ('open', 'confirmed', 'dismissed')),
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    -- updated_at of the transcript when it was measured; an edit reopens the review.
+    transcript_updated_at TIMESTAMP NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcript_quality_flagged ON transcript_quality (language, review) WHERE flagged;
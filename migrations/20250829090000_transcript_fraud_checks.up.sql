@@ -0,0 +1,14 @@
+-- Low-effort findings of every done transcript, checked on submission and in batch.
+CREATE TABLE transcript_fraud_checks (
+    transcript_id INT PRIMARY KEY REFERENCES transcripts(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    risk FLOAT NOT NULL DEFAULT 0,
+    duration FLOAT NOT NULL,
+    spent_seconds FLOAT,
+    -- updated_at of the transcript when it was checked, i.e. when it was submitted.
+    transcript_updated_at TIMESTAMP NOT NULL,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcript_fraud_checks_user_id ON transcript_fraud_checks (user_id, transcript_updated_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS export_bundle_parts;
+DROP TABLE IF EXISTS export_bundles;
@@ -0,0 +1,41 @@
+-- Dataset bundles built in the background: the audio of the selected segments and a
+-- manifest, packed into a tar.gz and uploaded to MinIO in parts.
+CREATE TABLE export_bundles (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    -- The entity.ManifestReq the segments are selected with.
+    filter JSONB NOT NULL,
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    -- Segments whose audio object is missing; they are left out of the bundle.
+    skipped INT NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL DEFAULT 0,
+    object_name TEXT,
+    upload_id TEXT,
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Bumped while a worker makes progress; a running bundle left alone for longer
+    -- than the lease is picked up again.
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_export_bundles_status ON export_bundles (status, id);
+
+-- Uploaded parts of a bundle. Every part ends on a whole segment, so the build resumes
+-- after last_audio_id, last_segment_id of the last part.
+CREATE TABLE export_bundle_parts (
+    bundle_id INT NOT NULL REFERENCES export_bundles(id) ON DELETE CASCADE,
+    part_number INT NOT NULL,
+    etag TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    segments INT NOT NULL,
+    skipped INT NOT NULL,
+    last_audio_id INT NOT NULL,
+    last_segment_id INT NOT NULL,
+    -- Manifest lines of the segments in the part, written out when the bundle is finished.
+    manifest TEXT NOT NULL,
+    PRIMARY KEY (bundle_id, part_number)
+);
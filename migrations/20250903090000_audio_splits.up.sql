@@ -0,0 +1,12 @@
+-- Dataset split of every audio file. Splits are kept per audio file, which is both the
+-- call and the speaker, so that no call or speaker ends up in two splits.
+CREATE TABLE audio_splits (
+    audio_id INT PRIMARY KEY REFERENCES audio_files(id),
+    split TEXT NOT NULL,
+    -- Duration bucket or emotion the audio file was stratified by, if any.
+    stratum TEXT NOT NULL DEFAULT '',
+    seed TEXT NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audio_splits_split ON audio_splits (split);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dataset_release_items;
+DROP TABLE IF EXISTS dataset_releases;
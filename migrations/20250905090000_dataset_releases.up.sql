@@ -0,0 +1,37 @@
+-- Frozen dataset releases. A release keeps its own copy of every segment it was cut
+-- with, so that it exports the same manifest however the transcripts change later.
+CREATE TABLE dataset_releases (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    -- The entity.ManifestReq the segments were selected with.
+    filter JSONB NOT NULL,
+    segments INT NOT NULL DEFAULT 0,
+    audio_files INT NOT NULL DEFAULT 0,
+    hours FLOAT NOT NULL DEFAULT 0,
+    -- Segment counts by language, split and emotion.
+    stats JSONB NOT NULL DEFAULT '{}',
+    -- SHA-256 of the JSONL manifest of the release.
+    manifest_sha256 TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE dataset_release_items (
+    release_id INT NOT NULL REFERENCES dataset_releases(id) ON DELETE CASCADE,
+    segment_id INT NOT NULL,
+    audio_id INT NOT NULL,
+    transcript_id INT NOT NULL,
+    audio_filepath TEXT NOT NULL,
+    duration FLOAT NOT NULL,
+    text TEXT NOT NULL,
+    -- SHA-256 of text.
+    text_hash TEXT NOT NULL,
+    emotion TEXT NOT NULL,
+    language TEXT NOT NULL,
+    split TEXT NOT NULL,
+    transcriber_id TEXT NOT NULL,
+    PRIMARY KEY (release_id, segment_id)
+);
+
+CREATE INDEX idx_dataset_release_items_order ON dataset_release_items (release_id, audio_id, segment_id);
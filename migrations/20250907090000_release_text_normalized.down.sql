@@ -0,0 +1 @@
+ALTER TABLE dataset_release_items DROP COLUMN IF EXISTS text_normalized;
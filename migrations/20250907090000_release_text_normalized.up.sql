@@ -0,0 +1,2 @@
+-- Normalized text of releases frozen with normalize set; empty otherwise.
+ALTER TABLE dataset_release_items ADD COLUMN text_normalized TEXT NOT NULL DEFAULT '';
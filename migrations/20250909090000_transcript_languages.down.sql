@@ -0,0 +1,12 @@
+ALTER TABLE dataset_release_items DROP COLUMN IF EXISTS language_spans;
+
+ALTER TABLE transcript_revisions
+    DROP COLUMN IF EXISTS language_spans,
+    DROP COLUMN IF EXISTS language;
+
+DROP INDEX IF EXISTS idx_transcripts_language_spans;
+DROP INDEX IF EXISTS idx_transcripts_language;
+
+ALTER TABLE transcripts
+    DROP COLUMN IF EXISTS language_spans,
+    DROP COLUMN IF EXISTS language;
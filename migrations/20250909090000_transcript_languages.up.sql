@@ -0,0 +1,20 @@
+-- Languages of a transcript: the primary language of the segment and the passages in
+-- other languages, as [{"start": 4, "end": 10, "language": "ru"}] over the characters
+-- of transcribe_text. Texts still using the inline "(ru: ...)" convention are moved
+-- over by the application in the background.
+ALTER TABLE transcripts
+    ADD COLUMN language VARCHAR(8) NOT NULL DEFAULT 'uz',
+    ADD COLUMN language_spans JSONB NOT NULL DEFAULT '[]';
+
+-- Until then, a text that is one Russian passage is a Russian segment.
+UPDATE transcripts SET language = 'ru'
+WHERE transcribe_text ~* '^\s*\(\s*ru\s*:[^()]*\)\s*$';
+
+CREATE INDEX idx_transcripts_language ON transcripts (language) WHERE deleted_at = 0;
+CREATE INDEX idx_transcripts_language_spans ON transcripts USING GIN (language_spans jsonb_path_ops) WHERE deleted_at = 0;
+
+ALTER TABLE transcript_revisions
+    ADD COLUMN language VARCHAR(8),
+    ADD COLUMN language_spans JSONB;
+
+ALTER TABLE dataset_release_items ADD COLUMN language_spans JSONB NOT NULL DEFAULT '[]';
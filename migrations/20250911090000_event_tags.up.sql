@@ -0,0 +1,24 @@
+CREATE TABLE event_tags (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    export_as VARCHAR(50) NOT NULL DEFAULT '',
+    name_uz VARCHAR(100) NOT NULL,
+    name_ru VARCHAR(100) NOT NULL,
+    name_cy VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT unique_event_tag_code_deleted_at UNIQUE (code, deleted_at)
+);
+
+INSERT INTO event_tags (code, tag, aliases, name_uz, name_ru, name_cy, sort_order) VALUES
+    ('music',          'Musiqa',      '{music,muzika,musiqa,мусиқа,музыка}',           'Musiqa',      'Музыка',       'Мусиқа',      1),
+    ('noise',          'Shovqin',     '{noise,shum,шовқин,шум}',                        'Shovqin',     'Шум',          'Шовқин',      2),
+    ('laughter',       'Kulgi',       '{laugh,laughter,kulish,кулги,смех}',             'Kulgi',       'Смех',         'Кулги',       3),
+    ('cough',          'Yoʻtal',      '{cough,yo''tal,yotal,йўтал,кашель}',             'Yoʻtal',      'Кашель',       'Йўтал',       4),
+    ('breath',         'Nafas',       '{breath,нафас,дыхание}',                         'Nafas',       'Дыхание',      'Нафас',       5),
+    ('unintelligible', 'Tushunarsiz', '{unk,unintelligible,тушунарсиз,неразборчиво}',   'Tushunarsiz', 'Неразборчиво', 'Тушунарсиз',  6);
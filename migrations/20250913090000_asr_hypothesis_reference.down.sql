@@ -0,0 +1 @@
+ALTER TABLE asr_hypotheses DROP COLUMN IF EXISTS reference_hash;
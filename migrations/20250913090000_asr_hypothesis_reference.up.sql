@@ -0,0 +1,9 @@
+-- The hash of the approved transcript a hypothesis was scored against. Scores of
+-- transcripts edited since are left off the leaderboard until they are uploaded again.
+ALTER TABLE asr_hypotheses ADD COLUMN reference_hash CHAR(32);
+
+-- Earlier scores can't be told apart; take them as scored against today's text.
+UPDATE asr_hypotheses h
+SET reference_hash = md5(t.transcribe_text)
+FROM transcripts t
+WHERE t.segment_id = h.segment_id AND t.deleted_at = 0 AND t.transcribe_text IS NOT NULL;
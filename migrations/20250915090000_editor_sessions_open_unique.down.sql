@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_editor_sessions_open_segment;
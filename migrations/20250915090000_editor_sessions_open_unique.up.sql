@@ -0,0 +1,9 @@
+-- Keep the latest of concurrently opened sessions on a segment.
+UPDATE editor_sessions e
+SET ended_at = e.last_beat_at
+WHERE e.ended_at IS NULL AND EXISTS (
+    SELECT 1 FROM editor_sessions o
+    WHERE o.user_id = e.user_id AND o.segment_id = e.segment_id AND o.ended_at IS NULL AND o.id > e.id
+);
+
+CREATE UNIQUE INDEX idx_editor_sessions_open_segment ON editor_sessions (user_id, segment_id) WHERE ended_at IS NULL;
@@ -0,0 +1,76 @@
+DROP TRIGGER IF EXISTS trg_transcripts_audio_file_text_delete ON transcripts;
+DROP TRIGGER IF EXISTS trg_transcripts_audio_file_text_update ON transcripts;
+DROP TRIGGER IF EXISTS trg_transcripts_audio_file_text_insert ON transcripts;
+DROP FUNCTION IF EXISTS mark_audio_file_texts_dirty();
+
+DROP INDEX IF EXISTS idx_audio_file_texts_dirty;
+ALTER TABLE audio_file_texts DROP COLUMN IF EXISTS dirty;
+
+CREATE OR REPLACE FUNCTION update_audio_file_text()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_segment_id INT;
+    v_audio_id INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_segment_id := OLD.segment_id;
+    ELSE
+        IF TG_OP = 'UPDATE'
+            AND OLD.transcribe_text IS NOT DISTINCT FROM NEW.transcribe_text
+            AND OLD.deleted_at = NEW.deleted_at THEN
+            RETURN NULL;
+        END IF;
+        v_segment_id := NEW.segment_id;
+    END IF;
+
+    SELECT audio_id INTO v_audio_id FROM audio_file_segments WHERE id = v_segment_id;
+    IF v_audio_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO audio_file_texts (audio_id, all_transcripts, updated_at)
+    VALUES (
+        v_audio_id,
+        (
+            SELECT STRING_AGG(t.transcribe_text, ' ' ORDER BY t.id)
+            FROM audio_file_segments s
+            JOIN transcripts t ON t.segment_id = s.id
+            WHERE s.audio_id = v_audio_id AND t.deleted_at = 0
+        ),
+        NOW()
+    )
+    ON CONFLICT (audio_id) DO UPDATE
+    SET all_transcripts = EXCLUDED.all_transcripts,
+        updated_at = EXCLUDED.updated_at;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_transcripts_audio_file_text
+AFTER INSERT OR UPDATE OF transcribe_text, deleted_at OR DELETE ON transcripts
+FOR EACH ROW
+EXECUTE FUNCTION update_audio_file_text();
+
+CREATE OR REPLACE FUNCTION stat_counter_add(p_name TEXT, p_delta INT)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO stat_counters (name, value, updated_at)
+    VALUES (p_name, p_delta, NOW())
+    ON CONFLICT (name) DO UPDATE
+    SET value = stat_counters.value + EXCLUDED.value,
+        updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE stat_counters_merged AS
+SELECT name, SUM(value)::BIGINT AS value, MAX(updated_at) AS updated_at
+FROM stat_counters
+GROUP BY name;
+DROP TABLE stat_counters;
+ALTER TABLE stat_counters_merged RENAME TO stat_counters;
+ALTER TABLE stat_counters ALTER COLUMN value SET NOT NULL;
+ALTER TABLE stat_counters ALTER COLUMN value SET DEFAULT 0;
+ALTER TABLE stat_counters ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE stat_counters ALTER COLUMN updated_at SET DEFAULT NOW();
+ALTER TABLE stat_counters ADD PRIMARY KEY (name);
@@ -0,0 +1,76 @@
+-- Spread each counter over shards so concurrent writers do not queue on one row.
+-- Readers sum the shards of a name.
+ALTER TABLE stat_counters ADD COLUMN shard SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE stat_counters DROP CONSTRAINT stat_counters_pkey;
+ALTER TABLE stat_counters ADD PRIMARY KEY (name, shard);
+
+CREATE OR REPLACE FUNCTION stat_counter_add(p_name TEXT, p_delta INT)
+RETURNS VOID AS $$
+BEGIN
+    INSERT INTO stat_counters (name, shard, value, updated_at)
+    VALUES (p_name, pg_backend_pid() % 16, p_delta, NOW())
+    ON CONFLICT (name, shard) DO UPDATE
+    SET value = stat_counters.value + EXCLUDED.value,
+        updated_at = NOW();
+END;
+$$ LANGUAGE plpgsql;
+
+-- Rebuilding a file's text on every transcript write is quadratic in its
+-- segment count. Writes now only mark the file dirty, once per statement, and
+-- the background job rebuilds dirty files.
+DROP TRIGGER IF EXISTS trg_transcripts_audio_file_text ON transcripts;
+DROP FUNCTION IF EXISTS update_audio_file_text();
+
+ALTER TABLE audio_file_texts ADD COLUMN dirty BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_audio_file_texts_dirty ON audio_file_texts (audio_id) WHERE dirty;
+
+CREATE OR REPLACE FUNCTION mark_audio_file_texts_dirty()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO audio_file_texts (audio_id, dirty)
+        SELECT DISTINCT s.audio_id, TRUE
+        FROM new_rows n
+        JOIN audio_file_segments s ON s.id = n.segment_id
+        ON CONFLICT (audio_id) DO UPDATE SET dirty = TRUE
+        WHERE NOT audio_file_texts.dirty;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO audio_file_texts (audio_id, dirty)
+        SELECT DISTINCT s.audio_id, TRUE
+        FROM new_rows n
+        JOIN old_rows o ON o.id = n.id
+        JOIN audio_file_segments s ON s.id = n.segment_id
+        WHERE o.transcribe_text IS DISTINCT FROM n.transcribe_text
+            OR o.deleted_at <> n.deleted_at
+        ON CONFLICT (audio_id) DO UPDATE SET dirty = TRUE
+        WHERE NOT audio_file_texts.dirty;
+    ELSE
+        INSERT INTO audio_file_texts (audio_id, dirty)
+        SELECT DISTINCT s.audio_id, TRUE
+        FROM old_rows o
+        JOIN audio_file_segments s ON s.id = o.segment_id
+        ON CONFLICT (audio_id) DO UPDATE SET dirty = TRUE
+        WHERE NOT audio_file_texts.dirty;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_transcripts_audio_file_text_insert
+AFTER INSERT ON transcripts
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT
+EXECUTE FUNCTION mark_audio_file_texts_dirty();
+
+CREATE TRIGGER trg_transcripts_audio_file_text_update
+AFTER UPDATE ON transcripts
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT
+EXECUTE FUNCTION mark_audio_file_texts_dirty();
+
+CREATE TRIGGER trg_transcripts_audio_file_text_delete
+AFTER DELETE ON transcripts
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT
+EXECUTE FUNCTION mark_audio_file_texts_dirty();
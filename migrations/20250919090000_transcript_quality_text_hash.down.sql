@@ -0,0 +1 @@
+ALTER TABLE transcript_quality DROP COLUMN IF EXISTS text_hash;
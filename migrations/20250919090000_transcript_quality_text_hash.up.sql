@@ -0,0 +1,8 @@
+-- md5 of the text that was measured. Only a change of text, not any touch of the
+-- transcript, reopens the review.
+ALTER TABLE transcript_quality ADD COLUMN text_hash CHAR(32);
+
+UPDATE transcript_quality q
+SET text_hash = md5(btrim(COALESCE(t.transcribe_text, '')))
+FROM transcripts t
+WHERE t.id = q.transcript_id AND t.updated_at = q.transcript_updated_at;
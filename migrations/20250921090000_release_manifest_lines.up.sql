@@ -0,0 +1,4 @@
+-- The manifest line of each item as it was hashed into manifest_sha256, exported
+-- verbatim so that the checksum holds whatever the manifest entry looks like later.
+-- Items of earlier releases have none and are encoded on export.
+ALTER TABLE dataset_release_items ADD COLUMN manifest_line TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- One-off data migrations run by the application, recorded once finished so that
+-- they are not run again.
+CREATE TABLE data_migrations (
+    name VARCHAR(100) PRIMARY KEY,
+    migrated INT NOT NULL DEFAULT 0,
+    finished_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Enum values cannot be dropped; hand unfinished claims back to the queue instead.
+UPDATE transcripts
+SET report_status = 'open', report_resolved_by = NULL, report_resolved_at = NULL
+WHERE report_status = 'rechunking';
@@ -0,0 +1,3 @@
+-- A report being re-chunked is claimed first, so the chunking runs outside any
+-- transaction and a concurrent resolve finds nothing open.
+ALTER TYPE report_status ADD VALUE IF NOT EXISTS 'rechunking';
@@ -0,0 +1,53 @@
+// Package cache keeps values in memory for a limited time.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// Cache is a map whose values expire after a fixed time. It is safe for concurrent use.
+type Cache[V any] struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]entry[V]
+}
+
+// New returns a cache keeping values for ttl. A cache with a ttl of zero keeps nothing.
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		ttl:   ttl,
+		items: make(map[string]entry[V]),
+	}
+}
+
+// Get returns the value of key unless it is missing or expired.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.items, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key.
+func (c *Cache[V]) Set(key string, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = entry[V]{value: value, expires: time.Now().Add(c.ttl)}
+}
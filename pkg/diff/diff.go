@@ -0,0 +1,71 @@
+// Package diff implements a word-level diff between two texts.
+package diff
+
+import "strings"
+
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// Op is a run of consecutive words that are equal in both texts, only in the
+// new text (insert) or only in the old text (delete).
+type Op struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Words returns the word-level edit script turning a into b.
+func Words(a, b string) []Op {
+	aw := strings.Fields(a)
+	bw := strings.Fields(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of aw[i:] and bw[j:]
+	lcs := make([][]int, len(aw)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bw)+1)
+	}
+	for i := len(aw) - 1; i >= 0; i-- {
+		for j := len(bw) - 1; j >= 0; j-- {
+			if aw[i] == bw[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []Op
+	push := func(op, word string) {
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text += " " + word
+			return
+		}
+		ops = append(ops, Op{Op: op, Text: word})
+	}
+
+	i, j := 0, 0
+	for i < len(aw) && j < len(bw) {
+		switch {
+		case aw[i] == bw[j]:
+			push(OpEqual, aw[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			push(OpDelete, aw[i])
+			i++
+		default:
+			push(OpInsert, bw[j])
+			j++
+		}
+	}
+	for ; i < len(aw); i++ {
+		push(OpDelete, aw[i])
+	}
+	for ; j < len(bw); j++ {
+		push(OpInsert, bw[j])
+	}
+
+	return ops
+}
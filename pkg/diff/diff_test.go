@@ -0,0 +1,48 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{"both empty", "", "", nil},
+		{"equal", "salom  dunyo", "salom dunyo", []Op{{OpEqual, "salom dunyo"}}},
+		{"insert into empty", "", "salom dunyo", []Op{{OpInsert, "salom dunyo"}}},
+		{"delete all", "salom dunyo", "", []Op{{OpDelete, "salom dunyo"}}},
+		{"insert", "men keldim", "men bugun keldim", []Op{
+			{OpEqual, "men"},
+			{OpInsert, "bugun"},
+			{OpEqual, "keldim"},
+		}},
+		{"delete", "men bugun erta keldim", "men keldim", []Op{
+			{OpEqual, "men"},
+			{OpDelete, "bugun erta"},
+			{OpEqual, "keldim"},
+		}},
+		{"replace", "bir olma bor", "bir anor bor", []Op{
+			{OpEqual, "bir"},
+			{OpDelete, "olma"},
+			{OpInsert, "anor"},
+			{OpEqual, "bor"},
+		}},
+		{"replace at the end", "salom dunyo", "salom olam", []Op{
+			{OpEqual, "salom"},
+			{OpDelete, "dunyo"},
+			{OpInsert, "olam"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Words(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package eventtag handles the non-speech event tags of transcripts, such as
+// "[Musiqa]", against a catalog of canonical spellings and their aliases. Lookups
+// ignore case and the spaces inside the brackets.
+package eventtag
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type Tag struct {
+	// Code names the tag in statistics and exports, such as music.
+	Code string
+	// Tag is the canonical spelling written between the brackets, such as Musiqa.
+	Tag string
+	// Aliases are other spellings that mean the tag, such as music or мусиқа.
+	Aliases []string
+	// ExportAs replaces the tag in a mapped export, "<code>" when empty.
+	ExportAs string
+}
+
+// Mode says what an export does with the tags of a text.
+type Mode string
+
+const (
+	// Keep writes known tags in their canonical spelling.
+	Keep Mode = "keep"
+	// Map replaces known tags with their ExportAs token.
+	Map Mode = "map"
+	// Drop removes every tag, unknown ones included.
+	Drop Mode = "drop"
+)
+
+// Catalog looks tags up by any of their spellings.
+type Catalog struct {
+	tags map[string]Tag
+}
+
+var tagPattern = regexp.MustCompile(`\[([^\[\]]*)\]`)
+
+// New builds a catalog of tags. A spelling used by two tags means the first one.
+func New(tags []Tag) *Catalog {
+	c := &Catalog{tags: map[string]Tag{}}
+	for _, tag := range tags {
+		for _, name := range append([]string{tag.Tag}, tag.Aliases...) {
+			key := key(name)
+			if _, ok := c.tags[key]; !ok && key != "" {
+				c.tags[key] = tag
+			}
+		}
+	}
+	return c
+}
+
+// Lookup returns the tag spelled name, without brackets.
+func (c *Catalog) Lookup(name string) (Tag, bool) {
+	tag, ok := c.tags[key(name)]
+	return tag, ok
+}
+
+// Names returns every spelling of the catalog, canonical ones and aliases.
+func (c *Catalog) Names() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, tag := range c.tags {
+		for _, name := range append([]string{tag.Tag}, tag.Aliases...) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Normalize writes the known tags of text in their canonical spelling.
+func (c *Catalog) Normalize(text string) string {
+	return c.Apply(text, Keep)
+}
+
+// Apply rewrites the tags of text by mode. Unknown tags are left as typed unless
+// dropped. A dropped tag takes a space next to it along, so no double spaces or
+// spaces before punctuation are left behind.
+func (c *Catalog) Apply(text string, mode Mode) string {
+	matches := tagPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		out = append(out, text[last:m[0]]...)
+		last = m[1]
+
+		tag, known := c.Lookup(text[m[2]:m[3]])
+		switch {
+		case mode == Drop:
+			lead := len(out) == 0 || out[len(out)-1] == ' '
+			switch {
+			case lead && last < len(text) && text[last] == ' ':
+				last++
+			case lead && len(out) > 0 && (last == len(text) || unicode.IsPunct(firstRune(text[last:]))):
+				out = out[:len(out)-1]
+			}
+		case !known:
+			out = append(out, text[m[0]:m[1]]...)
+		case mode == Map:
+			out = append(out, tag.exportAs()...)
+		default:
+			out = append(out, "["+tag.Tag+"]"...)
+		}
+	}
+	out = append(out, text[last:]...)
+	return string(out)
+}
+
+func (t Tag) exportAs() string {
+	if t.ExportAs != "" {
+		return t.ExportAs
+	}
+	return "<" + t.Code + ">"
+}
+
+func key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package eventtag
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var catalog = New([]Tag{
+	{Code: "music", Tag: "Musiqa", Aliases: []string{"music", "мусиқа"}},
+	{Code: "noise", Tag: "Shovqin", Aliases: []string{"noise", "shum"}, ExportAs: "<noise>"},
+	{Code: "laughter", Tag: "Kulgi"},
+})
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		mode Mode
+		want string
+	}{
+		{"canonical", "[Musiqa] salom", Keep, "[Musiqa] salom"},
+		{"case and spaces", "[ musiqa ] salom [SHOVQIN]", Keep, "[Musiqa] salom [Shovqin]"},
+		{"aliases", "[music] salom [мусиқа] [shum]", Keep, "[Musiqa] salom [Musiqa] [Shovqin]"},
+		{"unknown kept", "salom [yomgʻir]", Keep, "salom [yomgʻir]"},
+		{"language tags untouched", "salom (ru: привет)", Keep, "salom (ru: привет)"},
+		{"map", "[music] salom [noise] [kulgi]", Map, "<music> salom <noise> <laughter>"},
+		{"map keeps unknown", "salom [yomgʻir]", Map, "salom [yomgʻir]"},
+		{"drop at start", "[Musiqa] salom", Drop, "salom"},
+		{"drop at end", "salom [Musiqa]", Drop, "salom"},
+		{"drop in the middle", "salom [Kulgi] dunyo", Drop, "salom dunyo"},
+		{"drop before punctuation", "salom [Kulgi], dunyo", Drop, "salom, dunyo"},
+		{"drop repeated", "[Musiqa] [Shovqin] salom [Kulgi] [Kulgi]", Drop, "salom"},
+		{"drop unknown", "salom [yomgʻir] dunyo", Drop, "salom dunyo"},
+		{"drop glued", "salom[Kulgi]dunyo", Drop, "salomdunyo"},
+		{"drop only tag", "[Musiqa]", Drop, ""},
+		{"no tags", "salom dunyo", Drop, "salom dunyo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := catalog.Apply(tt.text, tt.mode); got != tt.want {
+				t.Errorf("Apply(%q, %s) = %q, want %q", tt.text, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if tag, ok := catalog.Lookup(" MUSIC "); !ok || tag.Code != "music" {
+		t.Errorf("Lookup(music) = %v, %v", tag, ok)
+	}
+	if _, ok := catalog.Lookup("yomgʻir"); ok {
+		t.Error("Lookup(yomgʻir) found a tag")
+	}
+
+	names := catalog.Names()
+	sort.Strings(names)
+	want := []string{"Kulgi", "Musiqa", "Shovqin", "music", "noise", "shum", "мусиқа"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() = %v, want %v", names, want)
+	}
+}
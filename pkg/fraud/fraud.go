@@ -0,0 +1,130 @@
+// Package fraud spots transcripts that were submitted with little or no effort.
+//
+// A submission is checked against the AI draft it started from, the texts of the
+// segments next to it, the time spent on it and the variety of its words. Each
+// finding has a weight, and the risk of a submission is the sum of its weights,
+// capped at one.
+package fraud
+
+import (
+	"strings"
+
+	"github.com/mirjalilova/voice_transcribe/pkg/wer"
+)
+
+// Findings of a check.
+const (
+	UnchangedAi        = "unchanged_ai"
+	DuplicateNeighbour = "duplicate_neighbour"
+	TooFast            = "too_fast"
+	RepetitiveFiller   = "repetitive_filler"
+)
+
+// Weights of the findings in the risk of a submission. An unchanged AI draft or a
+// quick submission can be honest on its own, so they weigh less than a copy.
+var Weights = map[string]float64{
+	UnchangedAi:        0.6,
+	DuplicateNeighbour: 1,
+	TooFast:            0.4,
+	RepetitiveFiller:   0.8,
+}
+
+// Config sets the thresholds of a check.
+type Config struct {
+	// MinChars is the length under which texts are not compared with the AI draft
+	// or the neighbours; short answers are often the same by nature.
+	MinChars int
+	// MaxAiEditRate is the character error rate against the AI draft at or under
+	// which the draft counts as unchanged.
+	MaxAiEditRate float64
+	// MaxNeighbourEditRate is the character error rate against a neighbour at or
+	// under which the text counts as a copy of it.
+	MaxNeighbourEditRate float64
+	// MinSpentRatio is the share of the audio duration a transcriber has to spend
+	// on a segment at least.
+	MinSpentRatio float64
+	// A text of at least FillerMinWords words is filler when one word makes up
+	// FillerShare of it or more.
+	FillerMinWords int
+	FillerShare    float64
+}
+
+var DefaultConfig = Config{
+	MinChars:             10,
+	MaxAiEditRate:        0.02,
+	MaxNeighbourEditRate: 0.1,
+	MinSpentRatio:        1,
+	FillerMinWords:       4,
+	FillerShare:          0.6,
+}
+
+// Submission is a transcript as it was submitted.
+type Submission struct {
+	Text       string
+	AiText     string
+	Neighbours []string
+	// Duration of the audio in seconds.
+	Duration float64
+	// Spent is the time in seconds spent on the segment, nil when unknown.
+	Spent *float64
+}
+
+// Check returns the findings of a submission in a fixed order.
+func (c Config) Check(s Submission) []string {
+	var res []string
+
+	words := wer.Tokenize(s.Text)
+	long := len([]rune(strings.Join(words, " "))) >= c.MinChars
+
+	if long && s.AiText != "" && wer.Compute(s.AiText, s.Text).CER() <= c.MaxAiEditRate {
+		res = append(res, UnchangedAi)
+	}
+
+	if long {
+		for _, neighbour := range s.Neighbours {
+			if neighbour != "" && wer.Compute(neighbour, s.Text).CER() <= c.MaxNeighbourEditRate {
+				res = append(res, DuplicateNeighbour)
+				break
+			}
+		}
+	}
+
+	if s.Spent != nil && s.Duration > 0 && *s.Spent < s.Duration*c.MinSpentRatio {
+		res = append(res, TooFast)
+	}
+
+	if c.filler(words) {
+		res = append(res, RepetitiveFiller)
+	}
+
+	return res
+}
+
+func (c Config) filler(words []string) bool {
+	if len(words) < c.FillerMinWords || len(words) == 0 {
+		return false
+	}
+
+	counts := make(map[string]int)
+	top := 0
+	for _, w := range words {
+		counts[w]++
+		if counts[w] > top {
+			top = counts[w]
+		}
+	}
+
+	return float64(top)/float64(len(words)) >= c.FillerShare
+}
+
+// Risk returns the risk of a submission with the findings, from 0 to 1.
+func Risk(findings []string) float64 {
+	risk := 0.0
+	for _, f := range findings {
+		risk += Weights[f]
+	}
+	if risk > 1 {
+		return 1
+	}
+	return risk
+}
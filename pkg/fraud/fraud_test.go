@@ -0,0 +1,80 @@
+package fraud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	cfg := Config{
+		MinChars:             10,
+		MaxAiEditRate:        0.1,
+		MaxNeighbourEditRate: 0.2,
+		MinSpentRatio:        1,
+		FillerMinWords:       4,
+		FillerShare:          0.5,
+	}
+	spent := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name string
+		sub  Submission
+		want []string
+	}{
+		{"clean", Submission{Text: "salom dunyo", AiText: "salam dunya", Duration: 5, Spent: spent(20)}, nil},
+		{"unchanged ai", Submission{Text: "Salom, dunyo!", AiText: "salom dunyo"}, []string{UnchangedAi}},
+		{"at min chars", Submission{Text: "salom olam", AiText: "salom olam"}, []string{UnchangedAi}},
+		{"under min chars", Submission{Text: "salom bob", AiText: "salom bob", Neighbours: []string{"salom bob"}}, nil},
+		{"ai edit rate at the limit", Submission{Text: "abcdefghiz", AiText: "abcdefghij"}, []string{UnchangedAi}},
+		{"ai edit rate over the limit", Submission{Text: "abcdefghyz", AiText: "abcdefghij"}, nil},
+		{"copied neighbour", Submission{Text: "abcdefghyz", Neighbours: []string{"", "boshqa matn bu", "abcdefghij"}}, []string{DuplicateNeighbour}},
+		{"neighbour edit rate over the limit", Submission{Text: "abcdefgxyz", Neighbours: []string{"abcdefghij"}}, nil},
+		{"empty neighbours", Submission{Text: "salom dunyo", Neighbours: []string{"", ""}}, nil},
+		{"too fast", Submission{Text: "salom", Duration: 5, Spent: spent(4.9)}, []string{TooFast}},
+		{"spent the duration", Submission{Text: "salom", Duration: 5, Spent: spent(5)}, nil},
+		{"unknown time spent", Submission{Text: "salom", Duration: 5}, nil},
+		{"unknown duration", Submission{Text: "salom", Spent: spent(0)}, nil},
+		{"filler", Submission{Text: "ha ha ha yo'q"}, []string{RepetitiveFiller}},
+		{"filler share at the limit", Submission{Text: "ha ha yo'q bor"}, []string{RepetitiveFiller}},
+		{"filler under min words", Submission{Text: "ha ha ha"}, nil},
+		{"varied words", Submission{Text: "ha yo'q bor kim"}, nil},
+		{"all findings in order", Submission{
+			Text:       "ha ha ha ha ha ha",
+			AiText:     "ha ha ha ha ha ha",
+			Neighbours: []string{"ha ha ha ha ha ha"},
+			Duration:   5,
+			Spent:      spent(1),
+		}, []string{UnchangedAi, DuplicateNeighbour, TooFast, RepetitiveFiller}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.Check(tt.sub); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRisk(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []string
+		want     float64
+	}{
+		{"none", nil, 0},
+		{"one", []string{TooFast}, 0.4},
+		{"sum", []string{UnchangedAi, TooFast}, 1},
+		{"capped", []string{UnchangedAi, RepetitiveFiller}, 1},
+		{"all capped", []string{UnchangedAi, DuplicateNeighbour, TooFast, RepetitiveFiller}, 1},
+		{"unknown finding", []string{"other"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Risk(tt.findings); got != tt.want {
+				t.Errorf("Risk(%v) = %v, want %v", tt.findings, got, tt.want)
+			}
+		})
+	}
+}
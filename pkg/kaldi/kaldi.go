@@ -0,0 +1,241 @@
+// Package kaldi writes a Kaldi / ESPnet data directory as a tar.gz stream.
+//
+// Every segment is its own recording, so segments span whole recordings. Speakers
+// are audio files, and utterance ids start with their speaker id and are zero padded
+// so that the byte order Kaldi expects is the order of audio and segment ids.
+// The lists are kept in temporary files until Finish, while bundled audio goes into
+// the archive as utterances are added; memory use does not grow with the export.
+package kaldi
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dir is the directory of the data files inside the archive.
+const Dir = "data"
+
+var lists = []string{"wav.scp", "text", "segments", "utt2spk", "spk2utt", "utt2dur"}
+
+// Opener opens the audio behind a URL and returns its size.
+type Opener func(ctx context.Context, url string) (io.ReadCloser, int64, error)
+
+// Utterance is one transcribed segment.
+type Utterance struct {
+	AudioId   int
+	SegmentId int
+	AudioURL  string
+	Text      string
+	Duration  float64
+}
+
+// SpeakerId returns the speaker id of an audio file.
+func SpeakerId(audioId int) string {
+	return fmt.Sprintf("audio_%08d", audioId)
+}
+
+// UtteranceId returns the utterance id of a segment.
+func UtteranceId(audioId, segmentId int) string {
+	return fmt.Sprintf("%s-%08d", SpeakerId(audioId), segmentId)
+}
+
+type Exporter struct {
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	open Opener
+	dir  string
+
+	files   map[string]*os.File
+	writers map[string]*bufio.Writer
+
+	speaker     string
+	speakerUtts []string
+	count       int
+}
+
+// NewExporter starts an archive on w. With a nil open, wav.scp references the audio
+// URLs; otherwise the audio is bundled into the archive under data/wav.
+func NewExporter(w io.Writer, open Opener) (*Exporter, error) {
+	dir, err := os.MkdirTemp("", "kaldi-export-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	e := &Exporter{
+		open:    open,
+		dir:     dir,
+		files:   make(map[string]*os.File),
+		writers: make(map[string]*bufio.Writer),
+	}
+	for _, name := range lists {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		e.files[name] = f
+		e.writers[name] = bufio.NewWriter(f)
+	}
+
+	e.gz = gzip.NewWriter(w)
+	e.tw = tar.NewWriter(e.gz)
+	return e, nil
+}
+
+// Count returns the number of utterances added.
+func (e *Exporter) Count() int {
+	return e.count
+}
+
+// Add writes the utterance to the lists and, when bundling, its audio to the archive.
+// Utterances must come in audio and segment order.
+func (e *Exporter) Add(ctx context.Context, u Utterance) error {
+	spk := SpeakerId(u.AudioId)
+	utt := UtteranceId(u.AudioId, u.SegmentId)
+
+	ext := path.Ext(u.AudioURL)
+	if ext == "" {
+		ext = ".wav"
+	}
+
+	source := u.AudioURL
+	if e.open != nil {
+		source = path.Join(Dir, "wav", utt+ext)
+		if err := e.bundle(ctx, u.AudioURL, source); err != nil {
+			return err
+		}
+	}
+
+	var wav string
+	switch {
+	case ext != ".wav":
+		wav = fmt.Sprintf("ffmpeg -nostdin -loglevel error -i %s -ac 1 -f wav - |", quote(source))
+	case e.open != nil:
+		wav = source
+	default:
+		wav = fmt.Sprintf("curl -sf %s |", quote(source))
+	}
+
+	if spk != e.speaker {
+		if err := e.flushSpeaker(); err != nil {
+			return err
+		}
+		e.speaker = spk
+	}
+	e.speakerUtts = append(e.speakerUtts, utt)
+
+	lines := map[string]string{
+		"wav.scp":  utt + " " + wav,
+		"text":     strings.TrimSpace(utt + " " + strings.Join(strings.Fields(u.Text), " ")),
+		"segments": fmt.Sprintf("%s %s 0.000 %.3f", utt, utt, u.Duration),
+		"utt2spk":  utt + " " + spk,
+		"utt2dur":  fmt.Sprintf("%s %.3f", utt, u.Duration),
+	}
+	for name, line := range lines {
+		if _, err := e.writers[name].WriteString(line + "\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	e.count++
+	return nil
+}
+
+func (e *Exporter) bundle(ctx context.Context, url, name string) error {
+	r, size, err := e.open(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", url, err)
+	}
+	defer r.Close()
+
+	err = e.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(e.tw, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (e *Exporter) flushSpeaker() error {
+	if e.speaker == "" {
+		return nil
+	}
+	_, err := e.writers["spk2utt"].WriteString(e.speaker + " " + strings.Join(e.speakerUtts, " ") + "\n")
+	if err != nil {
+		return fmt.Errorf("failed to write spk2utt: %w", err)
+	}
+	e.speakerUtts = e.speakerUtts[:0]
+	return nil
+}
+
+// Finish appends the lists to the archive and closes it. An export that failed
+// halfway should not be finished, so that the archive shows up as broken.
+func (e *Exporter) Finish() error {
+	if err := e.flushSpeaker(); err != nil {
+		return err
+	}
+
+	for _, name := range lists {
+		if err := e.writers[name].Flush(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		f := e.files[name]
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+
+		err = e.tw.WriteHeader(&tar.Header{
+			Name:    path.Join(Dir, name),
+			Mode:    0o644,
+			Size:    info.Size(),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if _, err := io.Copy(e.tw, f); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	if err := e.tw.Close(); err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+	if err := e.gz.Close(); err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+	return nil
+}
+
+// Close removes the temporary files. It does not finish the archive.
+func (e *Exporter) Close() error {
+	for _, f := range e.files {
+		f.Close()
+	}
+	return os.RemoveAll(e.dir)
+}
+
+// quote quotes s for the shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
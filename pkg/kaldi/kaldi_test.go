@@ -0,0 +1,147 @@
+package kaldi
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readArchive returns the names of the archive entries in order and their contents.
+func readArchive(t *testing.T, data []byte) ([]string, map[string]string) {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	var names []string
+	contents := make(map[string]string)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		contents[h.Name] = string(body)
+	}
+	return names, contents
+}
+
+func export(t *testing.T, open Opener, utts []Utterance) ([]string, map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	e, err := NewExporter(&buf, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for _, u := range utts {
+		if err := e.Add(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.Count() != len(utts) {
+		t.Errorf("Count() = %d, want %d", e.Count(), len(utts))
+	}
+	if err := e.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return readArchive(t, buf.Bytes())
+}
+
+func TestExporterBundled(t *testing.T) {
+	audio := map[string]string{
+		"http://minio/a.wav": "RIFF-a",
+		"http://minio/b.wav": "RIFF-b",
+		"http://minio/c.wav": "RIFF-c",
+	}
+	open := func(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+		return io.NopCloser(strings.NewReader(audio[url])), int64(len(audio[url])), nil
+	}
+
+	names, contents := export(t, open, []Utterance{
+		{AudioId: 2, SegmentId: 5, AudioURL: "http://minio/a.wav", Text: " salom   dunyo ", Duration: 1.5},
+		{AudioId: 2, SegmentId: 12, AudioURL: "http://minio/b.wav", Text: "", Duration: 2},
+		{AudioId: 10, SegmentId: 3, AudioURL: "http://minio/c.wav", Text: "ha", Duration: 0.25},
+	})
+
+	wantNames := []string{
+		"data/wav/audio_00000002-00000005.wav",
+		"data/wav/audio_00000002-00000012.wav",
+		"data/wav/audio_00000010-00000003.wav",
+		"data/wav.scp",
+		"data/text",
+		"data/segments",
+		"data/utt2spk",
+		"data/spk2utt",
+		"data/utt2dur",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("entries = %q, want %q", names, wantNames)
+	}
+
+	want := map[string]string{
+		"data/wav/audio_00000002-00000005.wav": "RIFF-a",
+		"data/wav/audio_00000002-00000012.wav": "RIFF-b",
+		"data/wav/audio_00000010-00000003.wav": "RIFF-c",
+		"data/wav.scp": "audio_00000002-00000005 data/wav/audio_00000002-00000005.wav\n" +
+			"audio_00000002-00000012 data/wav/audio_00000002-00000012.wav\n" +
+			"audio_00000010-00000003 data/wav/audio_00000010-00000003.wav\n",
+		"data/text": "audio_00000002-00000005 salom dunyo\n" +
+			"audio_00000002-00000012\n" +
+			"audio_00000010-00000003 ha\n",
+		"data/segments": "audio_00000002-00000005 audio_00000002-00000005 0.000 1.500\n" +
+			"audio_00000002-00000012 audio_00000002-00000012 0.000 2.000\n" +
+			"audio_00000010-00000003 audio_00000010-00000003 0.000 0.250\n",
+		"data/utt2spk": "audio_00000002-00000005 audio_00000002\n" +
+			"audio_00000002-00000012 audio_00000002\n" +
+			"audio_00000010-00000003 audio_00000010\n",
+		"data/spk2utt": "audio_00000002 audio_00000002-00000005 audio_00000002-00000012\n" +
+			"audio_00000010 audio_00000010-00000003\n",
+		"data/utt2dur": "audio_00000002-00000005 1.500\n" +
+			"audio_00000002-00000012 2.000\n" +
+			"audio_00000010-00000003 0.250\n",
+	}
+	for name, content := range want {
+		if contents[name] != content {
+			t.Errorf("%s = %q, want %q", name, contents[name], content)
+		}
+	}
+}
+
+func TestExporterURLs(t *testing.T) {
+	names, contents := export(t, nil, []Utterance{
+		{AudioId: 1, SegmentId: 1, AudioURL: "http://minio/a.wav", Text: "bir", Duration: 1},
+		{AudioId: 1, SegmentId: 2, AudioURL: "http://minio/it's.spx", Text: "ikki", Duration: 1},
+	})
+
+	wantNames := []string{"data/wav.scp", "data/text", "data/segments", "data/utt2spk", "data/spk2utt", "data/utt2dur"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("entries = %q, want %q", names, wantNames)
+	}
+
+	wantScp := "audio_00000001-00000001 curl -sf 'http://minio/a.wav' |\n" +
+		`audio_00000001-00000002 ffmpeg -nostdin -loglevel error -i 'http://minio/it'\''s.spx' -ac 1 -f wav - |` + "\n"
+	if contents["data/wav.scp"] != wantScp {
+		t.Errorf("wav.scp = %q, want %q", contents["data/wav.scp"], wantScp)
+	}
+	if want := "audio_00000001 audio_00000001-00000001 audio_00000001-00000002\n"; contents["data/spk2utt"] != want {
+		t.Errorf("spk2utt = %q, want %q", contents["data/spk2utt"], want)
+	}
+}
@@ -0,0 +1,215 @@
+// Package langspan handles the languages of a transcript: the primary language of the
+// segment and spans of text in other languages, such as Russian passages in Uzbek
+// speech. Span offsets count characters (Unicode code points) of the text, the end
+// exclusive.
+//
+// Transcribers used to type these passages inline as "(ru: ...)"; Parse turns that
+// convention into spans and Inline writes it back.
+package langspan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Span struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Language string `json:"language"`
+}
+
+var inlinePattern = regexp.MustCompile(`\(\s*([a-zA-Z]{2,3})\s*:([^()]*)\)`)
+
+// HasInline reports whether text has "(ru: ...)" style passages.
+func HasInline(text string) bool {
+	return inlinePattern.MatchString(text)
+}
+
+// Parse takes the "(ru: ...)" passages out of text and returns the plain text, its
+// primary language and the spans of the passages. Passages in primary add no span.
+// When passages of one language hold every letter, that language becomes the
+// primary one and there are no spans.
+func Parse(text, primary string) (string, string, []Span) {
+	var b strings.Builder
+	var spans []Span
+	size := 0
+	write := func(s string) {
+		b.WriteString(s)
+		size += len([]rune(s))
+	}
+
+	last := 0
+	for _, m := range inlinePattern.FindAllStringSubmatchIndex(text, -1) {
+		write(text[last:m[0]])
+		last = m[1]
+
+		words := strings.TrimSpace(text[m[4]:m[5]])
+		if words == "" {
+			continue
+		}
+		// Keep the passage apart from words it was glued to.
+		if prev := lastRune(b.String()); prev != 0 && !unicode.IsSpace(prev) {
+			write(" ")
+		}
+		start := size
+		write(words)
+		if lang := strings.ToLower(text[m[2]:m[3]]); lang != primary {
+			spans = append(spans, Span{Start: start, End: size, Language: lang})
+		}
+		if next := firstRune(text[m[1]:]); next != 0 && !unicode.IsSpace(next) && !unicode.IsPunct(next) {
+			write(" ")
+		}
+	}
+	write(text[last:])
+
+	plain := b.String()
+	if lang, ok := whole(plain, spans); ok {
+		return plain, lang, nil
+	}
+	return plain, primary, spans
+}
+
+// whole returns the language of spans when they are all in one language and hold
+// every letter of text.
+func whole(text string, spans []Span) (string, bool) {
+	if len(spans) == 0 {
+		return "", false
+	}
+	for _, s := range spans[1:] {
+		if s.Language != spans[0].Language {
+			return "", false
+		}
+	}
+
+	runes := []rune(text)
+	next := 0
+	for _, s := range spans {
+		if hasLetter(runes[next:s.Start]) {
+			return "", false
+		}
+		next = s.End
+	}
+	if hasLetter(runes[next:]) {
+		return "", false
+	}
+	return spans[0].Language, true
+}
+
+// Validate checks that primary and the span languages are among codes and that the
+// spans lie inside text in order, without overlapping, each in a language other than
+// primary.
+func Validate(text, primary string, spans []Span, codes []string) error {
+	known := func(code string) bool {
+		for _, c := range codes {
+			if c == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !known(primary) {
+		return fmt.Errorf("unknown language %q, expected one of %s", primary, strings.Join(codes, ", "))
+	}
+
+	size := len([]rune(text))
+	end := 0
+	for i, s := range spans {
+		switch {
+		case !known(s.Language):
+			return fmt.Errorf("span %d: unknown language %q, expected one of %s", i, s.Language, strings.Join(codes, ", "))
+		case s.Language == primary:
+			return fmt.Errorf("span %d: language %q is the primary language", i, s.Language)
+		case s.Start < 0 || s.End > size || s.Start >= s.End:
+			return fmt.Errorf("span %d: [%d, %d) is not inside the text of %d characters", i, s.Start, s.End, size)
+		case s.Start < end:
+			return fmt.Errorf("span %d: overlaps or comes before the previous span", i)
+		}
+		end = s.End
+	}
+	return nil
+}
+
+// Inline writes the spans into text as "(ru: ...)" passages.
+func Inline(text string, spans []Span) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	next := 0
+	for _, s := range sorted(spans) {
+		if s.Start < next || s.End > len(runes) || s.Start >= s.End {
+			continue
+		}
+		b.WriteString(string(runes[next:s.Start]))
+		b.WriteString("(" + s.Language + ": " + string(runes[s.Start:s.End]) + ")")
+		next = s.End
+	}
+	b.WriteString(string(runes[next:]))
+	return b.String()
+}
+
+// Map calls fn with every run of text in one language, outside spans in primary, and
+// returns the text put together from what fn returned with the spans moved to match.
+// Spans fn empties are dropped.
+func Map(text, primary string, spans []Span, fn func(part, lang string) string) (string, []Span) {
+	var b strings.Builder
+	var out []Span
+	runes := []rune(text)
+	size := 0
+	write := func(s string) {
+		b.WriteString(s)
+		size += len([]rune(s))
+	}
+
+	next := 0
+	for _, s := range sorted(spans) {
+		if s.Start < next || s.End > len(runes) || s.Start >= s.End {
+			continue
+		}
+		write(fn(string(runes[next:s.Start]), primary))
+		start := size
+		write(fn(string(runes[s.Start:s.End]), s.Language))
+		if size > start {
+			out = append(out, Span{Start: start, End: size, Language: s.Language})
+		}
+		next = s.End
+	}
+	write(fn(string(runes[next:]), primary))
+	return b.String(), out
+}
+
+func sorted(spans []Span) []Span {
+	res := append([]Span(nil), spans...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res
+}
+
+func hasLetter(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
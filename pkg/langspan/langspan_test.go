@@ -0,0 +1,102 @@
+package langspan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantText    string
+		wantPrimary string
+		wantSpans   []Span
+	}{
+		{"no passages", "Salom dunyo", "Salom dunyo", "uz", nil},
+		{"one passage", "Men (ru: Привет, как дела) dedim", "Men Привет, как дела dedim", "uz",
+			[]Span{{Start: 4, End: 20, Language: "ru"}}},
+		{"two languages", "(ru: да) ha (en: okay)", "да ha okay", "uz",
+			[]Span{{Start: 0, End: 2, Language: "ru"}, {Start: 6, End: 10, Language: "en"}}},
+		{"glued passage", "ha(ru:да)dedi", "ha да dedi", "uz",
+			[]Span{{Start: 3, End: 5, Language: "ru"}}},
+		{"punctuation after passage", "U (ru: да), dedi", "U да, dedi", "uz",
+			[]Span{{Start: 2, End: 4, Language: "ru"}}},
+		{"whole text", "(ru: Привет, как дела?)", "Привет, как дела?", "ru", nil},
+		{"whole text in passages", "(ru: Привет). (RU: Пока)", "Привет. Пока", "ru", nil},
+		{"passage in primary", "(uz: salom) dunyo", "salom dunyo", "uz", nil},
+		{"empty passage", "ha (ru: ) dedi", "ha  dedi", "uz", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, primary, spans := Parse(tt.text, "uz")
+			if text != tt.wantText || primary != tt.wantPrimary || !reflect.DeepEqual(spans, tt.wantSpans) {
+				t.Errorf("Parse(%q) = %q, %q, %v, want %q, %q, %v", tt.text, text, primary, spans, tt.wantText, tt.wantPrimary, tt.wantSpans)
+			}
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	for _, text := range []string{
+		"Men (ru: Привет, как дела) dedim",
+		"(ru: да) ha (en: okay)",
+		"Salom dunyo",
+	} {
+		plain, _, spans := Parse(text, "uz")
+		if got := Inline(plain, spans); got != text {
+			t.Errorf("Inline(Parse(%q)) = %q", text, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	codes := []string{"uz", "ru", "en"}
+	text := "Men Привет dedim"
+
+	tests := []struct {
+		name    string
+		primary string
+		spans   []Span
+		wantErr string
+	}{
+		{"valid", "uz", []Span{{4, 10, "ru"}}, ""},
+		{"no spans", "ru", nil, ""},
+		{"unknown primary", "kk", nil, "unknown language"},
+		{"unknown span language", "uz", []Span{{4, 10, "de"}}, "unknown language"},
+		{"primary span", "uz", []Span{{0, 3, "uz"}}, "primary language"},
+		{"outside text", "uz", []Span{{10, 30, "en"}}, "not inside"},
+		{"empty span", "uz", []Span{{4, 4, "ru"}}, "not inside"},
+		{"overlap", "uz", []Span{{4, 10, "ru"}, {8, 12, "en"}}, "overlaps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(text, tt.primary, tt.spans, codes)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	text, spans := Map("ab Привет cd", "uz", []Span{{3, 9, "ru"}}, func(part, lang string) string {
+		if lang == "uz" {
+			return strings.Repeat(part, 2)
+		}
+		return part
+	})
+
+	if want := "ab ab Привет cd cd"; text != want {
+		t.Errorf("Map() text = %q, want %q", text, want)
+	}
+	if want := []Span{{6, 12, "ru"}}; !reflect.DeepEqual(spans, want) {
+		t.Errorf("Map() spans = %v, want %v", spans, want)
+	}
+}
@@ -0,0 +1,270 @@
+// Package lint checks transcripts against the transcription guidelines: event tags
+// from the catalog only, balanced brackets, numbers written as words, one alphabet per
+// word and tidy spacing. Each rule reports its findings at a severity set in Config;
+// errors block a save, warnings only inform. Offsets count characters (Unicode code
+// points) of the text, the end exclusive.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Severities of a rule. A rule that is off does not run.
+const (
+	Off     = "off"
+	Warning = "warning"
+	Error   = "error"
+)
+
+// Rules.
+const (
+	RuleTag         = "tag"
+	RuleBracket     = "bracket"
+	RuleDigits      = "digits"
+	RuleMixedScript = "mixed_script"
+	RuleSpacing     = "spacing"
+)
+
+// DefaultRules are the severities of the rules not set in Config.Rules.
+var DefaultRules = map[string]string{
+	RuleTag:         Error,
+	RuleBracket:     Error,
+	RuleDigits:      Error,
+	RuleMixedScript: Error,
+	RuleSpacing:     Warning,
+}
+
+type Issue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Message  string `json:"message"`
+}
+
+type Config struct {
+	// Rules sets the severity of rules by name, DefaultRules for the rest.
+	Rules map[string]string
+	// Tags are the event tags allowed in brackets, as in "[Musiqa]", in any case. With
+	// none, any tag is allowed.
+	Tags []string
+}
+
+// ParseRules reads rule severities written as "digits:error,spacing:warning".
+func ParseRules(value string) (map[string]string, error) {
+	rules := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, severity, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("rule %q has no severity", item)
+		}
+		rules[strings.TrimSpace(name)] = strings.TrimSpace(severity)
+	}
+	return rules, nil
+}
+
+// Linter runs the rules of a Config.
+type Linter struct {
+	severity map[string]string
+	tags     map[string]bool
+}
+
+// New checks the rule names and severities of cfg.
+func New(cfg Config) (*Linter, error) {
+	l := &Linter{severity: map[string]string{}, tags: map[string]bool{}}
+	for name, severity := range DefaultRules {
+		l.severity[name] = severity
+	}
+	for name, severity := range cfg.Rules {
+		if _, ok := DefaultRules[name]; !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		switch severity {
+		case Off, Warning, Error:
+		default:
+			return nil, fmt.Errorf("rule %q: unknown severity %q, expected off, warning or error", name, severity)
+		}
+		l.severity[name] = severity
+	}
+	for _, tag := range cfg.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			l.tags[strings.ToLower(tag)] = true
+		}
+	}
+	return l, nil
+}
+
+// Lint returns the issues of text in order of their offsets.
+func (l *Linter) Lint(text string) []Issue {
+	runes := []rune(text)
+	var issues []Issue
+	add := func(rule string, start, end int, format string, args ...interface{}) {
+		issues = append(issues, Issue{
+			Rule:     rule,
+			Severity: l.severity[rule],
+			Start:    start,
+			End:      end,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if l.on(RuleTag) || l.on(RuleBracket) {
+		l.brackets(runes, add)
+	}
+	if l.on(RuleDigits) {
+		for _, w := range runs(runes, unicode.IsDigit) {
+			add(RuleDigits, w[0], w[1], "Write the number %s in words", string(runes[w[0]:w[1]]))
+		}
+	}
+	if l.on(RuleMixedScript) {
+		for _, w := range runs(runes, isWordRune) {
+			if mixedScript(runes[w[0]:w[1]]) {
+				add(RuleMixedScript, w[0], w[1], "%q mixes Latin and Cyrillic letters", string(runes[w[0]:w[1]]))
+			}
+		}
+	}
+	if l.on(RuleSpacing) {
+		spacing(runes, add)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Start < issues[j].Start })
+	return issues
+}
+
+// Blocking reports whether any of issues is an error.
+func Blocking(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Linter) on(rule string) bool {
+	return l.severity[rule] != Off
+}
+
+type addFunc func(rule string, start, end int, format string, args ...interface{})
+
+// brackets checks that square and round brackets pair up without nesting square
+// ones, and that square brackets hold a known tag.
+func (l *Linter) brackets(runes []rune, add addFunc) {
+	var open []int
+	tagStart := -1
+	for i, r := range runes {
+		switch r {
+		case '[':
+			if tagStart >= 0 {
+				if l.on(RuleBracket) {
+					add(RuleBracket, tagStart, tagStart+1, "Tag is not closed with ]")
+				}
+			}
+			tagStart = i
+		case ']':
+			if tagStart < 0 {
+				if l.on(RuleBracket) {
+					add(RuleBracket, i, i+1, "] has no opening [")
+				}
+				continue
+			}
+			if l.on(RuleTag) {
+				l.checkTag(runes, tagStart, i+1, add)
+			}
+			tagStart = -1
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				if l.on(RuleBracket) {
+					add(RuleBracket, i, i+1, ") has no opening (")
+				}
+				continue
+			}
+			open = open[:len(open)-1]
+		}
+	}
+
+	if !l.on(RuleBracket) {
+		return
+	}
+	if tagStart >= 0 {
+		add(RuleBracket, tagStart, tagStart+1, "Tag is not closed with ]")
+	}
+	for _, i := range open {
+		add(RuleBracket, i, i+1, "( is not closed with )")
+	}
+}
+
+// checkTag checks the tag runes[start:end], brackets included.
+func (l *Linter) checkTag(runes []rune, start, end int, add addFunc) {
+	name := strings.TrimSpace(string(runes[start+1 : end-1]))
+	switch {
+	case name == "":
+		add(RuleTag, start, end, "Tag is empty")
+	case len(l.tags) > 0 && !l.tags[strings.ToLower(name)]:
+		add(RuleTag, start, end, "Unknown tag [%s]", name)
+	}
+}
+
+// spacing finds whitespace at the ends of text, repeated spaces and spaces before
+// punctuation.
+func spacing(runes []rune, add addFunc) {
+	for _, w := range runs(runes, unicode.IsSpace) {
+		start, end := w[0], w[1]
+		switch {
+		case start == 0:
+			add(RuleSpacing, start, end, "Text starts with whitespace")
+		case end == len(runes):
+			add(RuleSpacing, start, end, "Text ends with whitespace")
+		case end-start > 1 || runes[start] != ' ':
+			add(RuleSpacing, start, end, "Use a single space between words")
+		case strings.ContainsRune(",.!?;:", runes[end]):
+			add(RuleSpacing, start, end, "No space before %c", runes[end])
+		}
+	}
+}
+
+// runs returns the [start, end) offsets of the runs of runes matching fn.
+func runs(runes []rune, fn func(rune) bool) [][2]int {
+	var res [][2]int
+	start := -1
+	for i, r := range runes {
+		switch {
+		case fn(r) && start < 0:
+			start = i
+		case !fn(r) && start >= 0:
+			res = append(res, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		res = append(res, [2]int{start, len(runes)})
+	}
+	return res
+}
+
+// isWordRune reports whether r belongs to a word. Apostrophes do, as in oʻzbek.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || strings.ContainsRune("'`ʻʼ‘’", r)
+}
+
+func mixedScript(word []rune) bool {
+	var latin, cyrillic bool
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin = true
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		}
+	}
+	return latin && cyrillic
+}
@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	l, err := New(Config{Tags: []string{"Musiqa", "Shovqin", "Kulgi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []Issue
+	}{
+		{"clean", "[Musiqa] Salom, qalaysiz?", nil},
+		{"unknown tag", "salom [Yomgʻir]", []Issue{
+			{RuleTag, Error, 6, 15, "Unknown tag [Yomgʻir]"},
+		}},
+		{"tag in other case", "[musiqa] ha", nil},
+		{"empty tag", "ha [ ] yoʻq", []Issue{
+			{RuleTag, Error, 3, 6, "Tag is empty"},
+		}},
+		{"unclosed tag", "ha [Musiqa yoʻq", []Issue{
+			{RuleBracket, Error, 3, 4, "Tag is not closed with ]"},
+		}},
+		{"unbalanced round brackets", "ha) (yoʻq", []Issue{
+			{RuleBracket, Error, 2, 3, ") has no opening ("},
+			{RuleBracket, Error, 4, 5, "( is not closed with )"},
+		}},
+		{"digits", "5 ta olma", []Issue{
+			{RuleDigits, Error, 0, 1, "Write the number 5 in words"},
+		}},
+		{"mixed script", "salom дунё mеn", []Issue{
+			{RuleMixedScript, Error, 11, 14, `"mеn" mixes Latin and Cyrillic letters`},
+		}},
+		{"apostrophes belong to words", "oʻzbek o'g'il", nil},
+		{"spacing", " salom  dunyo , ha ", []Issue{
+			{RuleSpacing, Warning, 0, 1, "Text starts with whitespace"},
+			{RuleSpacing, Warning, 6, 8, "Use a single space between words"},
+			{RuleSpacing, Warning, 13, 14, "No space before ,"},
+			{RuleSpacing, Warning, 18, 19, "Text ends with whitespace"},
+		}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.Lint(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	rules, err := ParseRules("digits:warning, spacing:off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(Config{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := l.Lint("[anything]  5")
+	want := []Issue{{RuleDigits, Warning, 12, 13, "Write the number 5 in words"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %v, want %v", got, want)
+	}
+	if Blocking(got) {
+		t.Error("Blocking() = true for warnings only")
+	}
+
+	for _, value := range []string{"digits", "unknown:error", "digits:fatal"} {
+		rules, err := ParseRules(value)
+		if err == nil {
+			_, err = New(Config{Rules: rules})
+		}
+		if err == nil {
+			t.Errorf("rules %q were accepted", value)
+		}
+	}
+}
@@ -3,8 +3,12 @@ package minio
 import (
 	"context"
 	"fmt"
+	"io"
 	"mime"
+	"net/url"
+	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -92,3 +96,87 @@ func (m *MinIO) Upload(cnf config.Config, fileName, filePath string) (string, er
 
 	return minioURL, nil
 }
+
+// ObjectName returns the object name of a URL produced by Upload.
+func ObjectName(objectURL string) string {
+	return path.Base(objectURL)
+}
+
+func (m *MinIO) Download(cnf config.Config, objectURL, filePath string) error {
+	err := m.Client.FGetObject(context.Background(), cnf.MINIO_BUCKET_NAME, ObjectName(objectURL), filePath, minio.GetObjectOptions{})
+	if err != nil {
+		slog.Error("Error while downloading %s from bucket %s: %v\n", objectURL, cnf.MINIO_BUCKET_NAME, err)
+		return err
+	}
+
+	return nil
+}
+
+// Open opens the object of a URL produced by Upload and returns its size.
+func (m *MinIO) Open(ctx context.Context, objectURL string) (io.ReadCloser, int64, error) {
+	obj, err := m.Client.GetObject(ctx, m.Cnf.MINIO_BUCKET_NAME, ObjectName(objectURL), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, err
+	}
+
+	return obj, info.Size, nil
+}
+
+// MinPartSize is the smallest size of a multipart upload part other than the last.
+const MinPartSize = 5 << 20
+
+// IsNotFound reports whether err says that an object does not exist.
+func IsNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+// StartUpload starts a multipart upload of an object and returns its upload id.
+func (m *MinIO) StartUpload(ctx context.Context, objectName, contentType string) (string, error) {
+	core := minio.Core{Client: m.Client}
+	return core.NewMultipartUpload(ctx, m.Cnf.MINIO_BUCKET_NAME, objectName, minio.PutObjectOptions{ContentType: contentType})
+}
+
+// UploadPart uploads part number of a multipart upload and returns its ETag.
+func (m *MinIO) UploadPart(ctx context.Context, objectName, uploadId string, number int, r io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: m.Client}
+	part, err := core.PutObjectPart(ctx, m.Cnf.MINIO_BUCKET_NAME, objectName, uploadId, number, r, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+// CompleteUpload joins the parts of a multipart upload, given their ETags in order,
+// into the object.
+func (m *MinIO) CompleteUpload(ctx context.Context, objectName, uploadId string, etags []string) error {
+	parts := make([]minio.CompletePart, len(etags))
+	for i, etag := range etags {
+		parts[i] = minio.CompletePart{PartNumber: i + 1, ETag: etag}
+	}
+
+	core := minio.Core{Client: m.Client}
+	_, err := core.CompleteMultipartUpload(ctx, m.Cnf.MINIO_BUCKET_NAME, objectName, uploadId, parts, minio.PutObjectOptions{})
+	return err
+}
+
+// AbortUpload drops a multipart upload and its parts.
+func (m *MinIO) AbortUpload(ctx context.Context, objectName, uploadId string) error {
+	core := minio.Core{Client: m.Client}
+	return core.AbortMultipartUpload(ctx, m.Cnf.MINIO_BUCKET_NAME, objectName, uploadId)
+}
+
+// PresignedURL returns a link that downloads an object until it expires. The link is
+// on MINIO_ENDPOINT rather than the public host of Upload.
+func (m *MinIO) PresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	u, err := m.Client.PresignedGetObject(ctx, m.Cnf.MINIO_BUCKET_NAME, objectName, expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,282 @@
+// Package normalizer turns human transcripts into the plain text ASR training
+// expects: language tags such as "(ru: ...)" resolved, event tags such as "[noise]"
+// dropped, numbers spelled out in Uzbek or Russian, Uzbek apostrophes (oʻ, o', o`)
+// unified, punctuation stripped and everything lowercased. Each step can be turned
+// off in Config.
+package normalizer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TagMode says what happens to a tag.
+type TagMode string
+
+const (
+	// TagKeep keeps the words of a tag and drops its markers.
+	TagKeep TagMode = "keep"
+	// TagDrop drops a tag with its words.
+	TagDrop TagMode = "drop"
+	// TagRaw keeps the markers of a tag around its normalized words.
+	TagRaw TagMode = "raw"
+)
+
+type Config struct {
+	Lowercase        bool
+	StripPunctuation bool
+	// LanguageTags handles "(ru: ...)" spans. Their words are normalized in the
+	// language of the tag.
+	LanguageTags TagMode
+	// EventTags handles "[noise]" style tags.
+	EventTags TagMode
+	// Apostrophe replaces every apostrophe variant between letters; empty leaves them.
+	Apostrophe string
+	// Numbers spells out digits in Uzbek and Russian text.
+	Numbers bool
+	// Language is the language of the text outside language tags, uz by default.
+	Language string
+}
+
+// DefaultConfig gives plain lowercase words: the words of language tags are kept,
+// event tags are dropped and apostrophes become '.
+var DefaultConfig = Config{
+	Lowercase:        true,
+	StripPunctuation: true,
+	LanguageTags:     TagKeep,
+	EventTags:        TagDrop,
+	Apostrophe:       "'",
+	Numbers:          true,
+	Language:         "uz",
+}
+
+// Validate checks the tag modes.
+func (c Config) Validate() error {
+	for name, mode := range map[string]TagMode{"language": c.LanguageTags, "event": c.EventTags} {
+		switch mode {
+		case TagKeep, TagDrop, TagRaw:
+		default:
+			return fmt.Errorf("unknown %s tag mode %q, expected keep, drop or raw", name, mode)
+		}
+	}
+	return nil
+}
+
+var (
+	tagPattern    = regexp.MustCompile(`\(\s*([a-zA-Z]{2,3})\s*:([^()]*)\)|\[([^\[\]]*)\]`)
+	numberPattern = regexp.MustCompile(`[0-9]+`)
+)
+
+// apostrophes are the characters written for the Uzbek ʻ and ʼ.
+const apostrophes = "'`ʻʼ‘’´"
+
+// span is a run of text in one language.
+type span struct {
+	text string
+	lang string
+	// open and close are the markers of a raw tag.
+	open, close string
+}
+
+// Normalize normalizes text.
+func (c Config) Normalize(text string) string {
+	var parts []string
+	for _, s := range c.spans(text) {
+		if part := c.normalizeSpan(s); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// spans cuts text at its tags.
+func (c Config) spans(text string) []span {
+	lang := c.Language
+	if lang == "" {
+		lang = "uz"
+	}
+
+	var spans []span
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, span{text: text[last:m[0]], lang: lang})
+		last = m[1]
+
+		if m[2] >= 0 {
+			code := strings.ToLower(text[m[2]:m[3]])
+			inner := text[m[4]:m[5]]
+			switch c.LanguageTags {
+			case TagKeep:
+				spans = append(spans, span{text: inner, lang: code})
+			case TagRaw:
+				spans = append(spans, span{text: inner, lang: code, open: "(" + code + ": ", close: ")"})
+			}
+			continue
+		}
+
+		inner := text[m[6]:m[7]]
+		switch c.EventTags {
+		case TagKeep:
+			spans = append(spans, span{text: inner, lang: lang})
+		case TagRaw:
+			spans = append(spans, span{text: inner, lang: lang, open: "[", close: "]"})
+		}
+	}
+	spans = append(spans, span{text: text[last:], lang: lang})
+
+	return spans
+}
+
+func (c Config) normalizeSpan(s span) string {
+	text := s.text
+	if c.Numbers {
+		text = spellNumbers(text, s.lang)
+	}
+	if c.Lowercase {
+		text = strings.ToLower(text)
+	}
+	if c.Apostrophe != "" {
+		text = unifyApostrophes(text, c.Apostrophe)
+	}
+	if c.StripPunctuation {
+		text = stripPunctuation(text, c.Apostrophe)
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	return s.open + text + s.close
+}
+
+// spellNumbers spells out the digit runs of Uzbek and Russian text. In Uzbek a
+// number followed by a hyphen and a word is an ordinal, as in "2024-yil".
+func spellNumbers(text, lang string) string {
+	if lang != "uz" && lang != "ru" {
+		return text
+	}
+	cyrillic := lang == "uz" && isCyrillic(text)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range numberPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(text[last:m[0]])
+		digits := text[m[0]:m[1]]
+		last = m[1]
+
+		ordinal := false
+		if lang == "uz" && last+1 < len(text) && text[last] == '-' {
+			suffix := leadingLetters(text[last+1:])
+			if suffix != "" {
+				ordinal = true
+				last++
+				// The ordinal suffix written out after the hyphen is part of the words.
+				switch strings.ToLower(suffix) {
+				case "chi", "inchi", "nchi", "чи", "инчи", "нчи":
+					last += len(suffix)
+					suffix = ""
+				}
+			}
+			b.WriteString(spellNumber(digits, lang, cyrillic, ordinal))
+			if suffix != "" {
+				b.WriteString(" ")
+			}
+			continue
+		}
+
+		b.WriteString(spellNumber(digits, lang, cyrillic, ordinal))
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// spellNumber spells out a run of digits. Runs with a leading zero or too long to
+// be a quantity are read digit by digit.
+func spellNumber(digits, lang string, cyrillic, ordinal bool) string {
+	spell := func(n int64) string {
+		if lang == "ru" {
+			return Russian(n)
+		}
+		if ordinal {
+			return UzbekOrdinal(n, cyrillic)
+		}
+		return Uzbek(n, cyrillic)
+	}
+
+	if len(digits) > maxNumberDigits || (len(digits) > 1 && digits[0] == '0') {
+		words := make([]string, len(digits))
+		for i := range digits {
+			words[i] = spell(int64(digits[i] - '0'))
+		}
+		return strings.Join(words, " ")
+	}
+
+	n, _ := strconv.ParseInt(digits, 10, 64)
+	return spell(n)
+}
+
+// leadingLetters returns the letters text starts with.
+func leadingLetters(text string) string {
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !strings.ContainsRune(apostrophes, r)
+	})
+	if end < 0 {
+		return text
+	}
+	return text[:end]
+}
+
+// isCyrillic reports whether text has more Cyrillic than Latin letters.
+func isCyrillic(text string) bool {
+	balance := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			balance++
+		case unicode.Is(unicode.Latin, r):
+			balance--
+		}
+	}
+	return balance > 0
+}
+
+// unifyApostrophes replaces the apostrophe variants that follow a letter.
+func unifyApostrophes(text, apostrophe string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsLetter(runes[i-1]) && strings.ContainsRune(apostrophes, r) {
+			b.WriteString(apostrophe)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stripPunctuation replaces everything but letters, digits and apostrophes between
+// letters with spaces.
+func stripPunctuation(text, apostrophe string) string {
+	runes := []rune(text)
+	isApostrophe := func(i int) bool {
+		r := string(runes[i])
+		return strings.Contains(apostrophes, r) || (apostrophe != "" && r == apostrophe)
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		case isApostrophe(i) && i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,188 @@
+package normalizer
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"lowercase and punctuation", "Salom, Dunyo! Qalaysiz?", "salom dunyo qalaysiz"},
+		{"apostrophe variants", "Oʻzbekiston, o‘g‘il, o`rik, g’isht, ma'no", "o'zbekiston o'g'il o'rik g'isht ma'no"},
+		{"quotes are not apostrophes", "U 'ha' dedi", "u ha dedi"},
+		{"language tag words are kept", "Men (ru: Привет, как дела?) dedim", "men привет как дела dedim"},
+		{"event tags are dropped", "[noise] ha [laugh] albatta", "ha albatta"},
+		{"hyphens split words", "ona-bola", "ona bola"},
+		{"whitespace", "  bir \n\t ikki  ", "bir ikki"},
+		{"uzbek numbers", "5 ta olma va 21 nok", "besh ta olma va yigirma bir nok"},
+		{"uzbek number with suffix", "5ta", "beshta"},
+		{"uzbek ordinal", "2024-yil 5-sinf", "ikki ming yigirma to'rtinchi yil beshinchi sinf"},
+		{"uzbek ordinal suffix", "3-chi qavat", "uchinchi qavat"},
+		{"number range", "5-6 kun", "besh olti kun"},
+		{"russian numbers in tag", "(ru: 21 рубль)", "двадцать один рубль"},
+		{"uzbek cyrillic numbers", "Менда 4 та китоб бор", "менда тўрт та китоб бор"},
+		{"leading zero", "007", "nol nol yetti"},
+		{"long digit run", "998901234567", "to'qqiz yuz to'qson sakkiz milliard to'qqiz yuz bir million ikki yuz o'ttiz to'rt ming besh yuz oltmish yetti"},
+		{"phone number", "+998901234567890", "to'qqiz to'qqiz sakkiz to'qqiz nol bir ikki uch to'rt besh olti yetti sakkiz to'qqiz nol"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultConfig.Normalize(tt.text); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTagModes(t *testing.T) {
+	text := "Ha [noise] (ru: Да, конечно) albatta"
+
+	tests := []struct {
+		name     string
+		language TagMode
+		event    TagMode
+		want     string
+	}{
+		{"keep and drop", TagKeep, TagDrop, "ha да конечно albatta"},
+		{"drop both", TagDrop, TagDrop, "ha albatta"},
+		{"keep both", TagKeep, TagKeep, "ha noise да конечно albatta"},
+		{"raw both", TagRaw, TagRaw, "ha [noise] (ru: да конечно) albatta"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig
+			cfg.LanguageTags = tt.language
+			cfg.EventTags = tt.event
+			if got := cfg.Normalize(text); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSteps(t *testing.T) {
+	text := "Oʻsha 2 kitob, xolos."
+
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want string
+	}{
+		{"all steps", func(*Config) {}, "o'sha ikki kitob xolos"},
+		{"no lowercase", func(c *Config) { c.Lowercase = false }, "O'sha ikki kitob xolos"},
+		{"no punctuation stripping", func(c *Config) { c.StripPunctuation = false }, "o'sha ikki kitob, xolos."},
+		{"no numbers", func(c *Config) { c.Numbers = false }, "o'sha 2 kitob xolos"},
+		{"apostrophes left", func(c *Config) { c.Apostrophe = "" }, "oʻsha ikki kitob xolos"},
+		{"modifier letter apostrophe", func(c *Config) { c.Apostrophe = "ʻ" }, "oʻsha ikki kitob xolos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig
+			tt.edit(&cfg)
+			if got := cfg.Normalize(text); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := DefaultConfig.Validate(); err != nil {
+		t.Errorf("DefaultConfig.Validate() = %v", err)
+	}
+
+	cfg := DefaultConfig
+	cfg.EventTags = "skip"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown tag mode")
+	}
+}
+
+func TestUzbek(t *testing.T) {
+	tests := []struct {
+		n        int64
+		cyrillic bool
+		want     string
+	}{
+		{0, false, "nol"},
+		{7, false, "yetti"},
+		{10, false, "o'n"},
+		{11, false, "o'n bir"},
+		{40, false, "qirq"},
+		{99, false, "to'qson to'qqiz"},
+		{100, false, "yuz"},
+		{105, false, "yuz besh"},
+		{250, false, "ikki yuz ellik"},
+		{1000, false, "ming"},
+		{1100, false, "ming yuz"},
+		{2025, false, "ikki ming yigirma besh"},
+		{15000, false, "o'n besh ming"},
+		{1000000, false, "bir million"},
+		{3000000001, false, "uch milliard bir"},
+		{1000000000000, false, "ming milliard"},
+		{24, true, "йигирма тўрт"},
+		{1990, true, "минг тўққиз юз тўқсон"},
+	}
+
+	for _, tt := range tests {
+		if got := Uzbek(tt.n, tt.cyrillic); got != tt.want {
+			t.Errorf("Uzbek(%d, %v) = %q, want %q", tt.n, tt.cyrillic, got, tt.want)
+		}
+	}
+}
+
+func TestUzbekOrdinal(t *testing.T) {
+	tests := []struct {
+		n        int64
+		cyrillic bool
+		want     string
+	}{
+		{1, false, "birinchi"},
+		{2, false, "ikkinchi"},
+		{6, false, "oltinchi"},
+		{40, false, "qirqinchi"},
+		{2024, false, "ikki ming yigirma to'rtinchi"},
+		{2, true, "иккинчи"},
+		{5, true, "бешинчи"},
+	}
+
+	for _, tt := range tests {
+		if got := UzbekOrdinal(tt.n, tt.cyrillic); got != tt.want {
+			t.Errorf("UzbekOrdinal(%d, %v) = %q, want %q", tt.n, tt.cyrillic, got, tt.want)
+		}
+	}
+}
+
+func TestRussian(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "ноль"},
+		{1, "один"},
+		{12, "двенадцать"},
+		{21, "двадцать один"},
+		{100, "сто"},
+		{342, "триста сорок два"},
+		{1000, "одна тысяча"},
+		{2000, "две тысячи"},
+		{5000, "пять тысяч"},
+		{11000, "одиннадцать тысяч"},
+		{21000, "двадцать одна тысяча"},
+		{1000000, "один миллион"},
+		{2000000, "два миллиона"},
+		{5000000, "пять миллионов"},
+		{2000000001, "два миллиарда один"},
+	}
+
+	for _, tt := range tests {
+		if got := Russian(tt.n); got != tt.want {
+			t.Errorf("Russian(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package normalizer
+
+import "strings"
+
+// maxNumberDigits is the longest digit run read out as a number; longer runs, such as
+// phone or card numbers, are read digit by digit.
+const maxNumberDigits = 12
+
+type uzbekWords struct {
+	cyrillic bool
+	ones     [10]string
+	tens     [10]string
+	hundred  string
+	scales   [4]string
+	ordinalV string
+	ordinalC string
+	vowels   string
+}
+
+var uzbekLatin = uzbekWords{
+	ones:     [10]string{"nol", "bir", "ikki", "uch", "to'rt", "besh", "olti", "yetti", "sakkiz", "to'qqiz"},
+	tens:     [10]string{"", "o'n", "yigirma", "o'ttiz", "qirq", "ellik", "oltmish", "yetmish", "sakson", "to'qson"},
+	hundred:  "yuz",
+	scales:   [4]string{"", "ming", "million", "milliard"},
+	ordinalV: "nchi",
+	ordinalC: "inchi",
+	vowels:   "aeiou",
+}
+
+var uzbekCyrillic = uzbekWords{
+	cyrillic: true,
+	ones:     [10]string{"нол", "бир", "икки", "уч", "тўрт", "беш", "олти", "етти", "саккиз", "тўққиз"},
+	tens:     [10]string{"", "ўн", "йигирма", "ўттиз", "қирқ", "эллик", "олтмиш", "етмиш", "саксон", "тўқсон"},
+	hundred:  "юз",
+	scales:   [4]string{"", "минг", "миллион", "миллиард"},
+	ordinalV: "нчи",
+	ordinalC: "инчи",
+	vowels:   "аеиоуэюяў",
+}
+
+// Uzbek spells n out in Uzbek, in Cyrillic or Latin script. One hundred and one
+// thousand are read without "bir", as they are spoken.
+func Uzbek(n int64, cyrillic bool) string {
+	w := uzbekLatin
+	if cyrillic {
+		w = uzbekCyrillic
+	}
+	if n == 0 {
+		return w.ones[0]
+	}
+
+	var words []string
+	for scale := len(w.scales) - 1; scale >= 0; scale-- {
+		unit := pow1000(scale)
+		group := n / unit % 1000
+		if scale == len(w.scales)-1 {
+			group = n / unit
+		}
+		if group == 0 {
+			continue
+		}
+		if !(scale == 1 && group == 1) {
+			words = append(words, w.group(group)...)
+		}
+		if scale > 0 {
+			words = append(words, w.scales[scale])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// group spells out n below a thousand.
+func (w uzbekWords) group(n int64) []string {
+	if n >= 1000 {
+		// Only the largest scale can exceed a thousand.
+		return strings.Fields(Uzbek(n, w.cyrillic))
+	}
+
+	var words []string
+	if h := n / 100; h > 0 {
+		if h > 1 {
+			words = append(words, w.group(h)...)
+		}
+		words = append(words, w.hundred)
+	}
+	if t := n % 100 / 10; t > 0 {
+		words = append(words, w.tens[t])
+	}
+	if o := n % 10; o > 0 {
+		words = append(words, w.ones[o])
+	}
+	return words
+}
+
+// UzbekOrdinal spells n out as an Uzbek ordinal, as in "2024-yil".
+func UzbekOrdinal(n int64, cyrillic bool) string {
+	w := uzbekLatin
+	if cyrillic {
+		w = uzbekCyrillic
+	}
+	words := Uzbek(n, cyrillic)
+	last := []rune(words)[len([]rune(words))-1]
+	if strings.ContainsRune(w.vowels, last) {
+		return words + w.ordinalV
+	}
+	return words + w.ordinalC
+}
+
+var (
+	russianOnes     = [20]string{"ноль", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять", "десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	russianTens     = [10]string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	russianHundreds = [10]string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+	// russianScales holds the forms for one, two to four and five or more.
+	russianScales = [4][3]string{
+		{},
+		{"тысяча", "тысячи", "тысяч"},
+		{"миллион", "миллиона", "миллионов"},
+		{"миллиард", "миллиарда", "миллиардов"},
+	}
+)
+
+// Russian spells n out in Russian, in the nominative case.
+func Russian(n int64) string {
+	if n == 0 {
+		return russianOnes[0]
+	}
+
+	var words []string
+	for scale := len(russianScales) - 1; scale >= 0; scale-- {
+		unit := pow1000(scale)
+		group := n / unit % 1000
+		if scale == len(russianScales)-1 {
+			group = n / unit
+		}
+		if group == 0 {
+			continue
+		}
+		words = append(words, russianGroup(group, scale == 1)...)
+		if scale > 0 {
+			words = append(words, russianScales[scale][russianPlural(group)])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// russianGroup spells out n below a thousand; thousands are feminine.
+func russianGroup(n int64, feminine bool) []string {
+	if n >= 1000 {
+		// Only the largest scale can exceed a thousand.
+		return strings.Fields(Russian(n))
+	}
+
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, russianHundreds[h])
+	}
+	rest := n % 100
+	if rest >= 20 {
+		words = append(words, russianTens[rest/10])
+		rest %= 10
+	}
+	if rest > 0 {
+		word := russianOnes[rest]
+		if feminine && rest == 1 {
+			word = "одна"
+		} else if feminine && rest == 2 {
+			word = "две"
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// russianPlural picks the form of a noun counted by n: 0 for one, 1 for two to four,
+// 2 for five or more.
+func russianPlural(n int64) int {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return 2
+	case n%10 == 1:
+		return 0
+	case n%10 >= 2 && n%10 <= 4:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func pow1000(scale int) int64 {
+	unit := int64(1)
+	for i := 0; i < scale; i++ {
+		unit *= 1000
+	}
+	return unit
+}
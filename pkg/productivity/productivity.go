@@ -0,0 +1,95 @@
+// Package productivity measures how much of a shift an operator was active, in blocks.
+//
+// An operator's day is cut into consecutive blocks starting at their first event
+// of the day. A block is active when at least one event falls into it, and the
+// day's score is the number of active blocks over the number of blocks in a shift.
+package productivity
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Config sets the block and shift lengths. Events are expected in the local time
+// of the timezone days are counted in.
+type Config struct {
+	Block time.Duration
+	Shift time.Duration
+}
+
+// DefaultConfig matches the former database function: 30 minute blocks, 18 blocks a shift.
+var DefaultConfig = Config{
+	Block: 30 * time.Minute,
+	Shift: 9 * time.Hour,
+}
+
+// BlocksPerShift returns the number of blocks in a shift, at least one.
+func (c Config) BlocksPerShift() float64 {
+	if c.Block <= 0 {
+		return 1
+	}
+	return math.Max(float64(c.Shift)/float64(c.Block), 1)
+}
+
+// Score returns the share of a shift covered by active blocks, rounded to two decimals.
+func (c Config) Score(blocks int) float64 {
+	return math.Round(float64(blocks)/c.BlocksPerShift()*100) / 100
+}
+
+// Day identifies the calendar day of an operator.
+type Day struct {
+	UserId string
+	Date   time.Time
+}
+
+// Event is a unit of work done by an operator at a point in time.
+type Event struct {
+	UserId string
+	At     time.Time
+}
+
+// Blocks counts the active blocks of every operator and day in events.
+func (c Config) Blocks(events []Event) map[Day]int {
+	byDay := make(map[Day][]time.Time)
+	for _, e := range events {
+		day := Day{UserId: e.UserId, Date: date(e.At)}
+		byDay[day] = append(byDay[day], e.At)
+	}
+
+	res := make(map[Day]int, len(byDay))
+	for day, times := range byDay {
+		res[day] = c.DayBlocks(times)
+	}
+	return res
+}
+
+// DayBlocks counts the active blocks among the events of one operator on one day.
+func (c Config) DayBlocks(times []time.Time) int {
+	if len(times) == 0 {
+		return 0
+	}
+	if c.Block <= 0 {
+		return 1
+	}
+
+	sorted := append([]time.Time(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	start := sorted[0]
+	blocks := 0
+	last := int64(-1)
+	for _, t := range sorted {
+		idx := int64(t.Sub(start) / c.Block)
+		if idx != last {
+			blocks++
+			last = idx
+		}
+	}
+	return blocks
+}
+
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,83 @@
+package productivity
+
+import (
+	"testing"
+	"time"
+)
+
+func at(clock string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", "2025-08-01 "+clock)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDayBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []time.Time
+		want  int
+	}{
+		{"no events", nil, 0},
+		{"one event", []time.Time{at("09:10")}, 1},
+		{"same block", []time.Time{at("09:10"), at("09:25"), at("09:39")}, 1},
+		{"block edge starts the next block", []time.Time{at("09:10"), at("09:40")}, 2},
+		{"gap of idle blocks", []time.Time{at("09:10"), at("11:15"), at("11:20")}, 2},
+		{"unsorted", []time.Time{at("11:15"), at("09:10"), at("09:45")}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultConfig.DayBlocks(tt.times); got != tt.want {
+				t.Errorf("DayBlocks() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlocksGroupsByUserAndDay(t *testing.T) {
+	next := at("09:10").Add(24 * time.Hour)
+	events := []Event{
+		{UserId: "a", At: at("09:10")},
+		{UserId: "a", At: at("10:00")},
+		{UserId: "b", At: at("09:15")},
+		{UserId: "a", At: next},
+	}
+
+	got := DefaultConfig.Blocks(events)
+
+	want := map[Day]int{
+		{UserId: "a", Date: date(at("00:00"))}: 2,
+		{UserId: "b", Date: date(at("00:00"))}: 1,
+		{UserId: "a", Date: date(next)}:        1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Blocks() returned %d days, want %d", len(got), len(want))
+	}
+	for day, blocks := range want {
+		if got[day] != blocks {
+			t.Errorf("Blocks()[%s %s] = %d, want %d", day.UserId, day.Date.Format("2006-01-02"), got[day], blocks)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		cfg    Config
+		blocks int
+		want   float64
+	}{
+		{DefaultConfig, 18, 1},
+		{DefaultConfig, 9, 0.5},
+		{DefaultConfig, 1, 0.06},
+		{Config{Block: 15 * time.Minute, Shift: 8 * time.Hour}, 16, 0.5},
+		{Config{Block: time.Hour, Shift: 30 * time.Minute}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cfg.Score(tt.blocks); got != tt.want {
+			t.Errorf("%v.Score(%d) = %v, want %v", tt.cfg, tt.blocks, got, tt.want)
+		}
+	}
+}
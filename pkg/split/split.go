@@ -0,0 +1,159 @@
+// Package split assigns items, such as audio files, to named splits like train, dev
+// and test.
+//
+// Without strata, the split of an item depends only on a hash of a seed and its key,
+// placed against the cumulative ratios. The same seed and key always give the same
+// split, whatever other items are assigned with it or in which batch, so every large
+// enough group of items is split close to the ratios.
+//
+// With strata, each stratum is balanced on its own: its items are taken in the order
+// of their hash and each goes to the split furthest below its share of the stratum,
+// counting the items placed before. Small strata then follow the ratios as closely as
+// their size allows, which hashing alone does not give.
+package split
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ratio is the share of a split. Weights need not add up to one.
+type Ratio struct {
+	Name   string
+	Weight float64
+}
+
+// ParseRatios parses "train:0.8,dev:0.1,test:0.1".
+func ParseRatios(s string) ([]Ratio, error) {
+	var ratios []Ratio
+	for _, field := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(field), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid split ratio %q, expected name:weight", field)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of split %q: %w", name, err)
+		}
+		ratios = append(ratios, Ratio{Name: strings.TrimSpace(name), Weight: w})
+	}
+	if err := Validate(ratios); err != nil {
+		return nil, err
+	}
+	return ratios, nil
+}
+
+// Validate checks that there is at least one split, that names are set and unique,
+// and that weights are positive.
+func Validate(ratios []Ratio) error {
+	if len(ratios) == 0 {
+		return fmt.Errorf("no splits given")
+	}
+	seen := make(map[string]bool, len(ratios))
+	for _, r := range ratios {
+		if r.Name == "" {
+			return fmt.Errorf("split name is empty")
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("split %q is given twice", r.Name)
+		}
+		if !(r.Weight > 0) || math.IsInf(r.Weight, 0) {
+			return fmt.Errorf("weight of split %q must be positive", r.Name)
+		}
+		seen[r.Name] = true
+	}
+	return nil
+}
+
+// Hash places a key in [0, 1) for a seed.
+func Hash(seed, key string) float64 {
+	sum := sha256.Sum256([]byte(seed + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// Of returns the split of key: the first split whose cumulative share exceeds the
+// hash of the key. Ratios must be valid.
+func Of(seed string, ratios []Ratio, key int) string {
+	var total float64
+	for _, r := range ratios {
+		total += r.Weight
+	}
+
+	pos := Hash(seed, strconv.Itoa(key))
+	var cum float64
+	for _, r := range ratios {
+		cum += r.Weight / total
+		if pos < cum {
+			return r.Name
+		}
+	}
+	// Rounding can leave the last bound just below one.
+	return ratios[len(ratios)-1].Name
+}
+
+// Assign returns the split of every key. Ratios must be valid.
+func Assign(seed string, ratios []Ratio, keys []int) map[int]string {
+	res := make(map[int]string, len(keys))
+	for _, key := range keys {
+		res[key] = Of(seed, ratios, key)
+	}
+	return res
+}
+
+// Stratified returns the split of every key of strata, which maps keys to their
+// stratum. Counts holds, per stratum, how many items are already in each split; they
+// keep their split and count towards the balance. The keys of a stratum are taken in
+// the order of their hash under the seed and the stratum, and each goes to the split
+// furthest below its share of the stratum, ties going to the earlier split. Ratios
+// must be valid.
+func Stratified(seed string, ratios []Ratio, strata map[int]string, counts map[string]map[string]int) map[int]string {
+	var total float64
+	for _, r := range ratios {
+		total += r.Weight
+	}
+
+	byStratum := make(map[string][]int)
+	for key, stratum := range strata {
+		byStratum[stratum] = append(byStratum[stratum], key)
+	}
+
+	res := make(map[int]string, len(strata))
+	for stratum, keys := range byStratum {
+		pos := make(map[int]float64, len(keys))
+		for _, key := range keys {
+			pos[key] = Hash(seed, stratum+"\x00"+strconv.Itoa(key))
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if pos[keys[i]] != pos[keys[j]] {
+				return pos[keys[i]] < pos[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+
+		have := make([]int, len(ratios))
+		n := 0
+		for i, r := range ratios {
+			have[i] = counts[stratum][r.Name]
+			n += have[i]
+		}
+
+		for _, key := range keys {
+			n++
+			best, deficit := 0, math.Inf(-1)
+			for i, r := range ratios {
+				if d := r.Weight/total*float64(n) - float64(have[i]); d > deficit {
+					best, deficit = i, d
+				}
+			}
+			have[best]++
+			res[key] = ratios[best].Name
+		}
+	}
+
+	return res
+}
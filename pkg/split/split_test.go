@@ -0,0 +1,169 @@
+package split
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+var ratios = []Ratio{{"train", 0.8}, {"dev", 0.1}, {"test", 0.1}}
+
+func TestParseRatios(t *testing.T) {
+	got, err := ParseRatios(" train:0.8, dev:0.1 ,test:0.1")
+	if err != nil {
+		t.Fatalf("ParseRatios: %v", err)
+	}
+	if len(got) != 3 || got[0] != ratios[0] || got[1] != ratios[1] || got[2] != ratios[2] {
+		t.Errorf("ParseRatios = %v, want %v", got, ratios)
+	}
+
+	for _, s := range []string{"", "train", "train:x", "train:0", "train:-1", "train:0.5,train:0.5", ":1"} {
+		if _, err := ParseRatios(s); err == nil {
+			t.Errorf("ParseRatios(%q) gave no error", s)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("seed", "1") != Hash("seed", "1") {
+		t.Error("Hash is not deterministic")
+	}
+	if Hash("seed", "1") == Hash("other", "1") {
+		t.Error("Hash ignores the seed")
+	}
+	for i := 0; i < 1000; i++ {
+		if h := Hash("seed", strconv.Itoa(i)); h < 0 || h >= 1 {
+			t.Fatalf("Hash = %v, want [0, 1)", h)
+		}
+	}
+}
+
+func TestAssignIndependentOfBatch(t *testing.T) {
+	keys := make([]int, 1000)
+	for i := range keys {
+		keys[i] = i + 1
+	}
+	all := Assign("seed", ratios, keys)
+
+	// Assigning the same keys one at a time or in other batches gives the same splits.
+	for _, key := range keys {
+		if got := Assign("seed", ratios, []int{key})[key]; got != all[key] {
+			t.Fatalf("split of %d alone = %s, in a batch = %s", key, got, all[key])
+		}
+	}
+	half := Assign("seed", ratios, keys[500:])
+	for _, key := range keys[500:] {
+		if half[key] != all[key] {
+			t.Fatalf("split of %d in half the batch = %s, in the whole = %s", key, half[key], all[key])
+		}
+	}
+}
+
+func TestAssignRatios(t *testing.T) {
+	const n = 20000
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i + 1
+	}
+
+	counts := make(map[string]int)
+	for _, name := range Assign("seed", ratios, keys) {
+		counts[name]++
+	}
+	for _, r := range ratios {
+		if share := float64(counts[r.Name]) / n; math.Abs(share-r.Weight) > 0.02 {
+			t.Errorf("share of %s = %.3f, want about %.1f", r.Name, share, r.Weight)
+		}
+	}
+}
+
+func TestOfUnnormalizedWeights(t *testing.T) {
+	// Weights are shares of their sum.
+	scaled := []Ratio{{"train", 8}, {"dev", 1}, {"test", 1}}
+	for key := 1; key <= 1000; key++ {
+		if a, b := Of("seed", ratios, key), Of("seed", scaled, key); a != b {
+			t.Fatalf("split of %d = %s with fractions, %s with weights", key, a, b)
+		}
+	}
+}
+
+func TestOfSingleSplit(t *testing.T) {
+	for key := 1; key <= 100; key++ {
+		if got := Of("seed", []Ratio{{"all", 1}}, key); got != "all" {
+			t.Fatalf("Of(%d) = %s, want all", key, got)
+		}
+	}
+}
+
+func TestStratifiedRatios(t *testing.T) {
+	sizes := map[string]int{"0-1m": 5000, "1-3m": 400, "3-10m": 37, "10m+": 3}
+	strata := make(map[int]string)
+	key := 0
+	for stratum, n := range sizes {
+		for i := 0; i < n; i++ {
+			key++
+			strata[key] = stratum
+		}
+	}
+
+	counts := make(map[string]map[string]int)
+	for key, name := range Stratified("seed", ratios, strata, nil) {
+		if counts[strata[key]] == nil {
+			counts[strata[key]] = make(map[string]int)
+		}
+		counts[strata[key]][name]++
+	}
+
+	// Every stratum is within one item of its share of each split.
+	for stratum, n := range sizes {
+		for _, r := range ratios {
+			want := r.Weight * float64(n)
+			if got := float64(counts[stratum][r.Name]); math.Abs(got-want) > 1 {
+				t.Errorf("%s in stratum %s = %v, want about %.1f", r.Name, stratum, got, want)
+			}
+		}
+	}
+}
+
+func TestStratifiedCountsPlacedItems(t *testing.T) {
+	// Ten items of the stratum are already in train, so new ones fill dev and test first.
+	strata := map[int]string{1: "a", 2: "a", 3: "b"}
+	counts := map[string]map[string]int{"a": {"train": 10}}
+
+	got := Stratified("seed", ratios, strata, counts)
+	if got[1] == "train" || got[2] == "train" || got[1] == got[2] {
+		t.Errorf("splits of the new items = %s, %s, want dev and test", got[1], got[2])
+	}
+	if got[3] != "train" {
+		t.Errorf("split of the first item of stratum b = %s, want train", got[3])
+	}
+}
+
+func TestStratifiedDeterministic(t *testing.T) {
+	strata := make(map[int]string)
+	for key := 1; key <= 500; key++ {
+		strata[key] = strconv.Itoa(key % 7)
+	}
+
+	first := Stratified("seed", ratios, strata, nil)
+	for i := 0; i < 5; i++ {
+		again := Stratified("seed", ratios, strata, nil)
+		for key, name := range first {
+			if again[key] != name {
+				t.Fatalf("split of %d = %s, then %s", key, name, again[key])
+			}
+		}
+	}
+
+	other := Stratified("other", ratios, strata, nil)
+	same := true
+	for key, name := range first {
+		if other[key] != name {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("Stratified ignores the seed")
+	}
+}
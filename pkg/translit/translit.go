@@ -0,0 +1,270 @@
+// Package translit converts Uzbek text between the Cyrillic and the Latin alphabet
+// by the official rules of 1995: е is ye at the start of a word and after a vowel, ъ
+// or ь, ц is ts after a vowel and s elsewhere, ъ is the tutuq belgisi ʼ unless it
+// only separates a Russian е, ё, ю or я, and ь is dropped except before о, where it
+// is y. Latin oʻ and gʻ are written with ʻ (U+02BB) and the tutuq belgisi with ʼ
+// (U+02BC); any apostrophe variant is read back.
+//
+// Event tags such as "[noise]" and language tags other than "(uz: ...)", such as
+// "(ru: ...)", are left as they are.
+package translit
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Script is the alphabet a text is written in.
+type Script string
+
+const (
+	None     Script = ""
+	Latin    Script = "latin"
+	Cyrillic Script = "cyrillic"
+	// Mixed text has letters of both alphabets.
+	Mixed Script = "mixed"
+)
+
+const (
+	// Okina is the sign of oʻ and gʻ.
+	Okina = "ʻ"
+	// Tutuq is the tutuq belgisi, the glottal stop written for ъ.
+	Tutuq = "ʼ"
+)
+
+// apostrophes are the characters written for ʻ and ʼ.
+const apostrophes = "'`ʻʼ‘’´"
+
+var tagPattern = regexp.MustCompile(`\(\s*([a-zA-Z]{2,3})\s*:[^()]*\)|\[[^\[\]]*\]`)
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'ё': "yo", 'ж': "j", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ч': "ch", 'ш': "sh",
+	'щ': "sh", 'ы': "i", 'э': "e", 'ю': "yu", 'я': "ya", 'ў': "o" + Okina, 'қ': "q",
+	'ғ': "g" + Okina, 'ҳ': "h",
+}
+
+var latinToCyrillic = map[rune]string{
+	'a': "а", 'b': "б", 'c': "ц", 'd': "д", 'f': "ф", 'g': "г", 'h': "ҳ", 'i': "и",
+	'j': "ж", 'k': "к", 'l': "л", 'm': "м", 'n': "н", 'o': "о", 'p': "п", 'q': "қ",
+	'r': "р", 's': "с", 't': "т", 'u': "у", 'v': "в", 'w': "в", 'x': "х", 'y': "й",
+	'z': "з",
+}
+
+// Detect returns the alphabet of the letters of text outside tags.
+func Detect(text string) Script {
+	var latin, cyrillic bool
+	apply(text, func(part string) string {
+		for _, r := range part {
+			switch {
+			case unicode.Is(unicode.Latin, r):
+				latin = true
+			case unicode.Is(unicode.Cyrillic, r):
+				cyrillic = true
+			}
+		}
+		return part
+	})
+
+	switch {
+	case latin && cyrillic:
+		return Mixed
+	case latin:
+		return Latin
+	case cyrillic:
+		return Cyrillic
+	}
+	return None
+}
+
+// Convert writes text in script, which is Latin or Cyrillic. Letters already in
+// script are kept, so mixed text comes out in one alphabet.
+func Convert(text string, script Script) string {
+	switch script {
+	case Latin:
+		return ToLatin(text)
+	case Cyrillic:
+		return ToCyrillic(text)
+	}
+	return text
+}
+
+// ToLatin writes the Cyrillic letters of text in Latin.
+func ToLatin(text string) string {
+	return apply(text, toLatin)
+}
+
+// ToCyrillic writes the Latin letters of text in Cyrillic.
+func ToCyrillic(text string) string {
+	return apply(text, toCyrillic)
+}
+
+// apply calls fn with the parts of text outside tags that are not in Uzbek.
+func apply(text string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		if m[2] >= 0 && strings.EqualFold(text[m[2]:m[3]], "uz") {
+			continue
+		}
+		b.WriteString(fn(text[last:m[0]]))
+		b.WriteString(text[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(fn(text[last:]))
+	return b.String()
+}
+
+func toLatin(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+		var prev, next rune
+		if i > 0 {
+			prev = unicode.ToLower(runes[i-1])
+		}
+		if i+1 < len(runes) {
+			next = unicode.ToLower(runes[i+1])
+		}
+
+		var out string
+		switch lower {
+		case 'е':
+			out = "e"
+			if !unicode.IsLetter(prev) || isCyrillicVowel(prev) || prev == 'ъ' || prev == 'ь' {
+				out = "ye"
+			}
+		case 'ц':
+			out = "s"
+			if isCyrillicVowel(prev) {
+				out = "ts"
+			}
+		case 'ъ':
+			out = Tutuq
+			if strings.ContainsRune("еёюя", next) {
+				out = ""
+			}
+		case 'ь':
+			out = ""
+			if next == 'о' {
+				out = "y"
+			}
+		default:
+			var ok bool
+			if out, ok = cyrillicToLatin[lower]; !ok {
+				b.WriteRune(r)
+				continue
+			}
+		}
+
+		if r != lower {
+			out = upper(out, runes, i)
+		}
+		b.WriteString(out)
+	}
+	return b.String()
+}
+
+func toCyrillic(text string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = make([]rune, len(runes))
+		for i, r := range runes {
+			lower[i] = unicode.ToLower(r)
+		}
+	}
+	at := func(i int) rune {
+		if i < len(lower) {
+			return lower[i]
+		}
+		return 0
+	}
+
+	var b strings.Builder
+	// vowel and letter describe the last letter written.
+	vowel, letter := false, false
+	for i := 0; i < len(runes); i++ {
+		l := lower[i]
+
+		var out string
+		n := 1
+		isVowel := false
+		switch {
+		case (l == 'o' || l == 'g') && isApostrophe(at(i+1)):
+			out, n = "ғ", 2
+			if l == 'o' {
+				out, isVowel = "ў", true
+			}
+		case l == 's' && at(i+1) == 'h':
+			out, n = "ш", 2
+		case l == 'c' && at(i+1) == 'h':
+			out, n = "ч", 2
+		case l == 'y' && at(i+1) == 'o' && !isApostrophe(at(i+2)):
+			out, n, isVowel = "ё", 2, true
+		case l == 'y' && at(i+1) == 'u':
+			out, n, isVowel = "ю", 2, true
+		case l == 'y' && at(i+1) == 'a':
+			out, n, isVowel = "я", 2, true
+		case l == 'y' && at(i+1) == 'e':
+			out, n, isVowel = "е", 2, true
+			if letter && !vowel {
+				out = "ъе"
+			}
+		case l == 'e':
+			out, isVowel = "е", true
+			if !letter || vowel {
+				out = "э"
+			}
+		case isApostrophe(l) && letter && unicode.IsLetter(at(i+1)):
+			out = "ъ"
+		default:
+			var ok bool
+			if out, ok = latinToCyrillic[l]; !ok {
+				b.WriteRune(runes[i])
+				vowel, letter = false, unicode.IsLetter(runes[i])
+				continue
+			}
+			isVowel = strings.ContainsRune("aiou", l)
+		}
+
+		if runes[i] != l {
+			out = strings.ToUpper(out)
+		}
+		b.WriteString(out)
+		vowel, letter = isVowel, true
+		i += n - 1
+	}
+	return b.String()
+}
+
+// upper capitalizes out, the Latin of the uppercase letter at runes[i]: all of it
+// when the word around is written in capitals, its first letter otherwise.
+func upper(out string, runes []rune, i int) string {
+	if out == "" {
+		return out
+	}
+	caps := false
+	if i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+		caps = unicode.IsUpper(runes[i+1])
+	} else if i > 0 && unicode.IsLetter(runes[i-1]) {
+		caps = unicode.IsUpper(runes[i-1])
+	}
+	if caps {
+		return strings.ToUpper(out)
+	}
+	first := []rune(out)
+	first[0] = unicode.ToUpper(first[0])
+	return string(first)
+}
+
+func isCyrillicVowel(r rune) bool {
+	return strings.ContainsRune("аеёиоуўэюяы", r)
+}
+
+func isApostrophe(r rune) bool {
+	return r != 0 && strings.ContainsRune(apostrophes, r)
+}
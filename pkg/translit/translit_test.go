@@ -0,0 +1,101 @@
+package translit
+
+import "testing"
+
+func TestToLatin(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Салом, дунё!", "Salom, dunyo!"},
+		{"uzbek letters", "Ўзбекистон ғалла қишлоқ ҳаво", "Oʻzbekiston gʻalla qishloq havo"},
+		{"ye at word start", "Ер ва ел", "Yer va yel"},
+		{"ye after vowel", "поезд оила", "poyezd oila"},
+		{"e after consonant", "келди мен", "keldi men"},
+		{"e letter", "Энди эрта", "Endi erta"},
+		{"ts after vowel", "милиция", "militsiya"},
+		{"s after consonant and at start", "цирк станция", "sirk stansiya"},
+		{"tutuq", "маъно таъсир", "maʼno taʼsir"},
+		{"hard sign before ye", "объект съезд", "obyekt syezd"},
+		{"soft sign", "премьер бульон бульён", "premyer bulyon bulyon"},
+		{"digraph capitals", "Шаҳар Чорсу Ёшлик", "Shahar Chorsu Yoshlik"},
+		{"all capitals", "ШАҲАР ЧОРСУ", "SHAHAR CHORSU"},
+		{"latin is kept", "Salom дунё", "Salom dunyo"},
+		{"tags are kept", "Мен (ru: Привет) [noise] дедим", "Men (ru: Привет) [noise] dedim"},
+		{"uzbek tags are converted", "(uz: Раҳмат)", "(uz: Rahmat)"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToLatin(tt.text); got != tt.want {
+				t.Errorf("ToLatin(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Salom, dunyo!", "Салом, дунё!"},
+		{"apostrophe variants", "Oʻzbekiston o'g'il o`rik g’isht", "Ўзбекистон ўғил ўрик ғишт"},
+		{"digraphs", "Shahar choy", "Шаҳар чой"},
+		{"all capitals", "SHAHAR", "ШАҲАР"},
+		{"e at word start", "ekran erta", "экран эрта"},
+		{"e after vowel", "poeziya", "поэзия"},
+		{"e after consonant", "keldi", "келди"},
+		{"ye", "yer poyezd", "ер поезд"},
+		{"ye after consonant", "obyekt", "объект"},
+		{"y before o with apostrophe", "yoʻl yoz", "йўл ёз"},
+		{"ya and yu", "yaxshi yulduz", "яхши юлдуз"},
+		{"tutuq", "maʼno san'at", "маъно санъат"},
+		{"quotes are kept", "U 'ha' dedi", "У 'ҳа' деди"},
+		{"cyrillic is kept", "Салом dunyo", "Салом дунё"},
+		{"tags are kept", "Men (en: hello) [noise]", "Мен (en: hello) [noise]"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToCyrillic(tt.text); got != tt.want {
+				t.Errorf("ToCyrillic(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"Ўзбекистон Республикаси пойтахти Тошкент шаҳри",
+		"Бугун ҳаво жуда яхши, эртага ёмғир ёғади.",
+		"Маъмурият қарорига кўра, ғалла йиғим-терими бошланди",
+	} {
+		if got := ToCyrillic(ToLatin(text)); got != text {
+			t.Errorf("ToCyrillic(ToLatin(%q)) = %q", text, got)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want Script
+	}{
+		{"Salom dunyo", Latin},
+		{"Салом дунё", Cyrillic},
+		{"Salom дунё", Mixed},
+		{"Salom (ru: Привет) [шум]", Latin},
+		{"123 [noise]", None},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package wer implements word and character error rate scoring for ASR hypotheses.
+package wer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Score holds the raw edit counts of a hypothesis against its reference.
+type Score struct {
+	WordErrors int
+	RefWords   int
+	CharErrors int
+	RefChars   int
+}
+
+// WER returns the word error rate, or 0 for an empty reference.
+func (s Score) WER() float64 {
+	if s.RefWords == 0 {
+		return 0
+	}
+	return float64(s.WordErrors) / float64(s.RefWords)
+}
+
+// CER returns the character error rate, or 0 for an empty reference.
+func (s Score) CER() float64 {
+	if s.RefChars == 0 {
+		return 0
+	}
+	return float64(s.CharErrors) / float64(s.RefChars)
+}
+
+// Compute scores hyp against ref after lowercasing and stripping punctuation.
+func Compute(ref, hyp string) Score {
+	refWords := Tokenize(ref)
+	hypWords := Tokenize(hyp)
+
+	refChars := []rune(strings.Join(refWords, " "))
+	hypChars := []rune(strings.Join(hypWords, " "))
+
+	return Score{
+		WordErrors: distance(refWords, hypWords),
+		RefWords:   len(refWords),
+		CharErrors: distance(refChars, hypChars),
+		RefChars:   len(refChars),
+	}
+}
+
+// Tokenize lowercases text and splits it into words, dropping punctuation.
+func Tokenize(text string) []string {
+	text = strings.ToLower(text)
+	return strings.FieldsFunc(text, func(r rune) bool {
+		// apostrophes are part of Uzbek letters (o', g'), keep them inside words
+		if r == '\'' || r == 'ʻ' || r == 'ʼ' || r == '`' {
+			return false
+		}
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+}
+
+// distance is the Levenshtein distance between two token sequences.
+func distance[T comparable](a, b []T) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,74 @@
+package wer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		hyp  string
+		want Score
+	}{
+		{"both empty", "", "", Score{}},
+		{"empty reference", "", "salom dunyo", Score{WordErrors: 2, CharErrors: 11}},
+		{"empty hypothesis", "salom dunyo", "", Score{WordErrors: 2, RefWords: 2, CharErrors: 11, RefChars: 11}},
+		{"case and punctuation ignored", "Salom, dunyo!", "salom dunyo", Score{RefWords: 2, RefChars: 11}},
+		{"insertion", "salom dunyo", "salom katta dunyo", Score{WordErrors: 1, RefWords: 2, CharErrors: 6, RefChars: 11}},
+		{"deletion", "men bugun keldim", "men keldim", Score{WordErrors: 1, RefWords: 3, CharErrors: 6, RefChars: 16}},
+		{"substitution", "bir olma", "bir anor", Score{WordErrors: 1, RefWords: 2, CharErrors: 4, RefChars: 8}},
+		{"apostrophe letters counted as one rune", "oʻzbek tili", "o'zbek tili", Score{WordErrors: 1, RefWords: 2, CharErrors: 1, RefChars: 11}},
+		{"cyrillic counted in runes", "дунё", "дуне", Score{WordErrors: 1, RefWords: 1, CharErrors: 1, RefChars: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compute(tt.ref, tt.hyp); got != tt.want {
+				t.Errorf("Compute(%q, %q) = %+v, want %+v", tt.ref, tt.hyp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRates(t *testing.T) {
+	tests := []struct {
+		name     string
+		score    Score
+		wer, cer float64
+	}{
+		{"empty reference", Score{WordErrors: 2, CharErrors: 11}, 0, 0},
+		{"partial", Score{WordErrors: 1, RefWords: 4, CharErrors: 3, RefChars: 12}, 0.25, 0.25},
+		{"more errors than reference", Score{WordErrors: 3, RefWords: 2, CharErrors: 6, RefChars: 4}, 1.5, 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.score.WER(); got != tt.wer {
+				t.Errorf("WER() = %v, want %v", got, tt.wer)
+			}
+			if got := tt.score.CER(); got != tt.cer {
+				t.Errorf("CER() = %v, want %v", got, tt.cer)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Salom,  Dunyo!", []string{"salom", "dunyo"}},
+		{"O'g'il va qiz — oʻzbek", []string{"o'g'il", "va", "qiz", "oʻzbek"}},
+		{"Привет, мир", []string{"привет", "мир"}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}